@@ -0,0 +1,51 @@
+package alerts
+
+import "time"
+
+const (
+	RuleBeaconPower    = "beacon_power"
+	RuleNoiseFloor     = "noise_floor"
+	RulePassbandNoise  = "passband_noise"
+	RuleUnknownCarrier = "unknown_carrier"
+	RuleLockLost       = "lock_lost"
+)
+
+const (
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+// Alert is a rule violation. It is firing until the condition clears.
+type Alert struct {
+	ID        int        `json:"id"`
+	Pipeline  string     `json:"pipeline"`
+	Rule      string     `json:"rule"`
+	State     string     `json:"state"`
+	Message   string     `json:"message"`
+	Value     float64    `json:"value"`
+	Threshold float64    `json:"threshold"`
+	Start     time.Time  `json:"start"`
+	End       *time.Time `json:"end,omitempty"`
+}
+
+// Input holds the measurements evaluated by the rules
+type Input struct {
+	Time             time.Time
+	Locked           bool
+	BeaconFrequency  float64 // Absolute frequency of the beacon in Hertz
+	BeaconBandwidth  float64
+	BeaconPower      float64 // dBFS
+	NoiseFloor       float64 // dBFS/Hz around the beacon
+	PassbandMeasured bool
+	PassbandNoise    float64 // dBFS per bin of the signal detector
+	Carriers         []Carrier
+}
+
+// Carrier is a signal seen by the signal detector
+type Carrier struct {
+	Frequency float64
+	Bandwidth float64
+	Power     float64
+}
+
+type OnAlert func(alert Alert)
@@ -0,0 +1,320 @@
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/quan-to/slog"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minReferenceSamples is the number of measurements needed before a moving reference is used
+const minReferenceSamples = 10
+
+// condition tracks a rule between evaluations
+type condition struct {
+	since time.Time // When the condition started to be true
+	alert *Alert    // Firing alert
+}
+
+// reference is an exponential moving average of a level that is frozen while its rule is violated
+type reference struct {
+	value   float64
+	samples int
+}
+
+func (r *reference) update(v float64, alpha float64) {
+	if r.samples == 0 {
+		r.value = v
+	} else {
+		r.value += (v - r.value) * alpha
+	}
+	r.samples++
+}
+
+func (r *reference) valid() bool {
+	return r.samples >= minReferenceSamples
+}
+
+// Manager evaluates the alert rules and keeps the list of alerts.
+// Level rules compare the measurement with a moving reference, so they follow slow changes like rain fade and only
+// fire on sudden drops or rises. A condition must hold for Delay seconds before its alert fires.
+type Manager struct {
+	name     string
+	cfg      config.AlertsConfig
+	log      *slog.Instance
+	webhooks []*Webhook
+	onAlert  OnAlert
+
+	lock           sync.Mutex
+	conditions     map[string]*condition
+	beaconPower    reference
+	noiseFloor     reference
+	passbandNoise  reference
+	lastEvaluation time.Time
+	lockedAt       time.Time // Last time the beacon was locked, or the start time
+	nextID         int
+	history        []Alert
+}
+
+func MakeManager(name string, cfg config.AlertsConfig) *Manager {
+	m := &Manager{
+		name:       name,
+		cfg:        cfg,
+		log:        slog.Scope("Alerts " + name),
+		conditions: map[string]*condition{},
+		history:    make([]Alert, 0),
+		lockedAt:   time.Now(),
+	}
+
+	for _, w := range cfg.Webhooks {
+		m.webhooks = append(m.webhooks, MakeWebhook(name, w))
+	}
+
+	return m
+}
+
+// SetOnAlert sets the callback called when an alert fires or resolves
+func (m *Manager) SetOnAlert(cb OnAlert) {
+	m.onAlert = cb
+}
+
+func (m *Manager) Start() {
+	for _, w := range m.webhooks {
+		w.Start()
+	}
+}
+
+func (m *Manager) Stop() {
+	for _, w := range m.webhooks {
+		w.Stop()
+	}
+}
+
+// alpha returns the moving average coefficient for the time elapsed since the last evaluation
+func (m *Manager) alpha(elapsed time.Duration) float64 {
+	if m.cfg.ReferenceTime <= 0 {
+		return 1
+	}
+	return 1 - math.Exp(-elapsed.Seconds()/m.cfg.ReferenceTime)
+}
+
+// Evaluate runs all the rules against the measurements
+func (m *Manager) Evaluate(in Input) {
+	m.lock.Lock()
+
+	elapsed := time.Duration(0)
+	if !m.lastEvaluation.IsZero() {
+		elapsed = in.Time.Sub(m.lastEvaluation)
+	}
+	m.lastEvaluation = in.Time
+	alpha := m.alpha(elapsed)
+
+	alerts := make([]Alert, 0)
+
+	if m.cfg.BeaconPowerDrop > 0 {
+		threshold := m.beaconPower.value - m.cfg.BeaconPowerDrop
+		violated := m.beaconPower.valid() && in.BeaconPower < threshold
+		message := fmt.Sprintf("Beacon power dropped to %.1f dBFS, %.1f dB below its reference", in.BeaconPower, m.beaconPower.value-in.BeaconPower)
+		alerts = append(alerts, m.check(RuleBeaconPower, violated, in.Time, in.BeaconPower, threshold, message)...)
+		if !violated && m.conditions[RuleBeaconPower] == nil && in.Locked {
+			m.beaconPower.update(in.BeaconPower, alpha)
+		}
+	}
+
+	if m.cfg.NoiseFloorRise > 0 {
+		threshold := m.noiseFloor.value + m.cfg.NoiseFloorRise
+		violated := m.noiseFloor.valid() && in.NoiseFloor > threshold
+		message := fmt.Sprintf("Noise floor around the beacon rose to %.1f dBFS/Hz, %.1f dB above its reference", in.NoiseFloor, in.NoiseFloor-m.noiseFloor.value)
+		alerts = append(alerts, m.check(RuleNoiseFloor, violated, in.Time, in.NoiseFloor, threshold, message)...)
+		if !violated && m.conditions[RuleNoiseFloor] == nil {
+			m.noiseFloor.update(in.NoiseFloor, alpha)
+		}
+	}
+
+	if m.cfg.NoiseFloorRise > 0 && in.PassbandMeasured {
+		threshold := m.passbandNoise.value + m.cfg.NoiseFloorRise
+		violated := m.passbandNoise.valid() && in.PassbandNoise > threshold
+		message := fmt.Sprintf("Passband noise floor rose to %.1f dBFS, %.1f dB above its reference", in.PassbandNoise, in.PassbandNoise-m.passbandNoise.value)
+		alerts = append(alerts, m.check(RulePassbandNoise, violated, in.Time, in.PassbandNoise, threshold, message)...)
+		if !violated && m.conditions[RulePassbandNoise] == nil {
+			m.passbandNoise.update(in.PassbandNoise, alpha)
+		}
+	}
+
+	if m.cfg.CarrierRange > 0 {
+		var unknown *Carrier
+		for i, c := range in.Carriers {
+			if math.Abs(c.Frequency-in.BeaconFrequency) <= m.cfg.CarrierRange && !m.known(c, in) {
+				if unknown == nil || c.Power > unknown.Power {
+					unknown = &in.Carriers[i]
+				}
+			}
+		}
+
+		if unknown != nil {
+			message := fmt.Sprintf("Unknown carrier at %.3f kHz (%.0f Hz, %.1f dBFS), %.0f Hz from the beacon", unknown.Frequency/1e3, unknown.Bandwidth, unknown.Power, unknown.Frequency-in.BeaconFrequency)
+			alerts = append(alerts, m.check(RuleUnknownCarrier, true, in.Time, unknown.Frequency, m.cfg.CarrierRange, message)...)
+		} else {
+			alerts = append(alerts, m.check(RuleUnknownCarrier, false, in.Time, 0, m.cfg.CarrierRange, "")...)
+		}
+	}
+
+	if m.cfg.LockLostTime > 0 {
+		if in.Locked {
+			m.lockedAt = in.Time
+		}
+
+		unlocked := in.Time.Sub(m.lockedAt).Seconds()
+		violated := unlocked > m.cfg.LockLostTime
+		message := fmt.Sprintf("Beacon lock lost for %.0f seconds", unlocked)
+		// The lock time already debounces the rule
+		alerts = append(alerts, m.checkAfter(RuleLockLost, violated, 0, in.Time, unlocked, m.cfg.LockLostTime, message)...)
+	}
+
+	m.lock.Unlock()
+
+	for _, a := range alerts {
+		m.notify(a)
+	}
+}
+
+// known returns true when the carrier is the beacon or one of the configured carriers
+func (m *Manager) known(c Carrier, in Input) bool {
+	tolerance := m.cfg.CarrierTolerance + c.Bandwidth/2
+
+	if math.Abs(c.Frequency-in.BeaconFrequency) <= tolerance+in.BeaconBandwidth/2 {
+		return true
+	}
+
+	for _, f := range m.cfg.KnownCarriers {
+		if math.Abs(c.Frequency-f) <= tolerance {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Manager) check(rule string, violated bool, now time.Time, value, threshold float64, message string) []Alert {
+	return m.checkAfter(rule, violated, time.Duration(m.cfg.Delay*float64(time.Second)), now, value, threshold, message)
+}
+
+// checkAfter updates the condition of a rule and returns the alerts that changed state
+func (m *Manager) checkAfter(rule string, violated bool, delay time.Duration, now time.Time, value, threshold float64, message string) []Alert {
+	c := m.conditions[rule]
+
+	if !violated {
+		if c == nil {
+			return nil
+		}
+		delete(m.conditions, rule)
+		if c.alert == nil {
+			return nil
+		}
+
+		c.alert.State = StateResolved
+		c.alert.End = &now
+		m.record(*c.alert)
+		return []Alert{*c.alert}
+	}
+
+	if c == nil {
+		c = &condition{since: now}
+		m.conditions[rule] = c
+	}
+
+	if c.alert != nil {
+		// Keep the latest measurement without notifying again
+		c.alert.Value = value
+		c.alert.Message = message
+		m.record(*c.alert)
+		return nil
+	}
+
+	if now.Sub(c.since) < delay {
+		return nil
+	}
+
+	m.nextID++
+	c.alert = &Alert{
+		ID:        m.nextID,
+		Pipeline:  m.name,
+		Rule:      rule,
+		State:     StateFiring,
+		Message:   message,
+		Value:     value,
+		Threshold: threshold,
+		Start:     now,
+	}
+	m.record(*c.alert)
+
+	return []Alert{*c.alert}
+}
+
+// record adds the alert to the history or updates it when it is already there
+func (m *Manager) record(a Alert) {
+	for i := range m.history {
+		if m.history[i].ID == a.ID {
+			m.history[i] = a
+			return
+		}
+	}
+
+	m.history = append(m.history, a)
+	if len(m.history) > m.cfg.History {
+		m.history = m.history[len(m.history)-m.cfg.History:]
+	}
+}
+
+func (m *Manager) notify(a Alert) {
+	if a.State == StateFiring {
+		m.log.Warn("Alert %d %s: %s", a.ID, a.Rule, a.Message)
+	} else {
+		m.log.Info("Alert %d %s resolved after %s", a.ID, a.Rule, a.End.Sub(a.Start).Round(time.Second))
+	}
+
+	for _, w := range m.webhooks {
+		w.Send(a)
+	}
+
+	if m.onAlert != nil {
+		m.onAlert(a)
+	}
+}
+
+// GetAlerts returns the latest alerts, newest first. It is safe to be called from any goroutine.
+func (m *Manager) GetAlerts() []Alert {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	alerts := make([]Alert, len(m.history))
+	for i, a := range m.history {
+		alerts[len(alerts)-1-i] = a
+	}
+
+	return alerts
+}
+
+// GetWebhookErrors returns the number of webhook deliveries that failed
+func (m *Manager) GetWebhookErrors() int {
+	errors := 0
+	for _, w := range m.webhooks {
+		errors += w.Errors()
+	}
+	return errors
+}
+
+// Handler serves the latest alerts
+func (m *Manager) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, _ := json.Marshal(m.GetAlerts())
+
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(200)
+		_, _ = w.Write(data)
+	}
+}
@@ -0,0 +1,208 @@
+package alerts
+
+import (
+	"github.com/racerxdl/qo100-dedrift/config"
+	"testing"
+	"time"
+)
+
+// testManager evaluates the rules at a fixed pace and records the notified alerts
+type testManager struct {
+	*Manager
+	now      time.Time
+	notified []Alert
+}
+
+func makeTestManager(cfg config.AlertsConfig) *testManager {
+	m := &testManager{
+		Manager: MakeManager("test", cfg),
+		now:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	m.lockedAt = m.now
+	m.SetOnAlert(func(a Alert) {
+		m.notified = append(m.notified, a)
+	})
+	return m
+}
+
+// evaluate runs the rules seconds after the previous evaluation
+func (m *testManager) evaluate(seconds float64, in Input) {
+	m.now = m.now.Add(time.Duration(seconds * float64(time.Second)))
+	in.Time = m.now
+	m.Evaluate(in)
+}
+
+func beaconInput(power float64) Input {
+	return Input{Locked: true, BeaconFrequency: 10489750000, BeaconBandwidth: 1000, BeaconPower: power, NoiseFloor: -100}
+}
+
+func beaconPowerConfig() config.AlertsConfig {
+	cfg := config.DefaultConfig.Server.Alerts
+	cfg.NoiseFloorRise = 0
+	cfg.CarrierRange = 0
+	cfg.LockLostTime = 0
+	return cfg
+}
+
+func TestManagerThreshold(t *testing.T) {
+	m := makeTestManager(beaconPowerConfig())
+
+	for i := 0; i < minReferenceSamples; i++ {
+		if m.beaconPower.valid() {
+			t.Errorf("expected the reference not to be used after %d samples", i)
+		}
+		m.evaluate(1, beaconInput(-40))
+	}
+
+	// A drop under BeaconPowerDrop does not start the condition
+	m.evaluate(1, beaconInput(-40-m.cfg.BeaconPowerDrop+1))
+	if len(m.conditions) != 0 {
+		t.Errorf("expected no condition for a drop under the threshold")
+	}
+
+	m.evaluate(1, beaconInput(-40-m.cfg.BeaconPowerDrop-1))
+	if m.conditions[RuleBeaconPower] == nil {
+		t.Fatalf("expected a condition for a drop over the threshold")
+	}
+	if len(m.notified) != 0 {
+		t.Errorf("expected no alert before the delay, got %d", len(m.notified))
+	}
+}
+
+func TestManagerForDuration(t *testing.T) {
+	m := makeTestManager(beaconPowerConfig())
+	for i := 0; i < minReferenceSamples; i++ {
+		m.evaluate(1, beaconInput(-40))
+	}
+
+	// A violation shorter than the delay never fires and restarts the delay
+	m.evaluate(1, beaconInput(-60))
+	m.evaluate(m.cfg.Delay/2, beaconInput(-60))
+	m.evaluate(1, beaconInput(-40))
+	m.evaluate(1, beaconInput(-60))
+	m.evaluate(m.cfg.Delay-1, beaconInput(-60))
+	if len(m.notified) != 0 {
+		t.Fatalf("expected no alert for violations shorter than the delay, got %d", len(m.notified))
+	}
+
+	m.evaluate(1, beaconInput(-60))
+	if len(m.notified) != 1 {
+		t.Fatalf("expected 1 alert after the delay, got %d", len(m.notified))
+	}
+
+	a := m.notified[0]
+	if a.Rule != RuleBeaconPower || a.State != StateFiring || a.Value != -60 || a.Pipeline != "test" {
+		t.Errorf("unexpected alert %+v", a)
+	}
+	if !a.Start.Equal(m.now) {
+		t.Errorf("expected the alert to start when it fired, got %s", a.Start)
+	}
+}
+
+func TestManagerRepeatSuppressionAndResolve(t *testing.T) {
+	m := makeTestManager(beaconPowerConfig())
+	for i := 0; i < minReferenceSamples; i++ {
+		m.evaluate(1, beaconInput(-40))
+	}
+
+	m.evaluate(1, beaconInput(-60))
+	m.evaluate(m.cfg.Delay, beaconInput(-60))
+	if len(m.notified) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(m.notified))
+	}
+
+	// A firing alert is updated without notifying again, and the reference does not follow the drop
+	for i := 0; i < 100; i++ {
+		m.evaluate(10, beaconInput(-55))
+	}
+	if len(m.notified) != 1 {
+		t.Errorf("expected a single notification while firing, got %d", len(m.notified))
+	}
+	if m.beaconPower.value != -40 {
+		t.Errorf("expected the reference frozen at -40 dBFS, got %f", m.beaconPower.value)
+	}
+
+	alerts := m.GetAlerts()
+	if len(alerts) != 1 || alerts[0].Value != -55 || alerts[0].State != StateFiring {
+		t.Fatalf("expected the firing alert updated with the last value, got %+v", alerts)
+	}
+
+	// Recovering resolves the alert once, with its end time
+	m.evaluate(1, beaconInput(-41))
+	m.evaluate(1, beaconInput(-40))
+	if len(m.notified) != 2 {
+		t.Fatalf("expected the resolution to be notified once, got %d notifications", len(m.notified))
+	}
+
+	resolved := m.notified[1]
+	if resolved.ID != m.notified[0].ID || resolved.State != StateResolved || resolved.End == nil {
+		t.Errorf("expected the alert %d resolved with an end time, got %+v", m.notified[0].ID, resolved)
+	}
+
+	// A new violation is a new alert
+	m.evaluate(1, beaconInput(-60))
+	m.evaluate(m.cfg.Delay, beaconInput(-60))
+	if len(m.notified) != 3 || m.notified[2].ID == m.notified[0].ID {
+		t.Fatalf("expected a new alert, got %+v", m.notified)
+	}
+
+	alerts = m.GetAlerts()
+	if len(alerts) != 2 || alerts[0].ID != m.notified[2].ID || alerts[1].State != StateResolved {
+		t.Errorf("expected the history newest first, got %+v", alerts)
+	}
+}
+
+func TestManagerLockLost(t *testing.T) {
+	cfg := beaconPowerConfig()
+	cfg.BeaconPowerDrop = 0
+	cfg.LockLostTime = 60
+	m := makeTestManager(cfg)
+
+	m.evaluate(1, beaconInput(-40))
+
+	unlocked := beaconInput(-40)
+	unlocked.Locked = false
+
+	// The lock time is the delay of the rule
+	m.evaluate(30, unlocked)
+	m.evaluate(30, unlocked)
+	if len(m.notified) != 0 {
+		t.Fatalf("expected no alert at the lock lost time, got %d", len(m.notified))
+	}
+
+	m.evaluate(1, unlocked)
+	if len(m.notified) != 1 || m.notified[0].Rule != RuleLockLost {
+		t.Fatalf("expected a lock lost alert, got %+v", m.notified)
+	}
+
+	m.evaluate(1, beaconInput(-40))
+	if len(m.notified) != 2 || m.notified[1].State != StateResolved {
+		t.Errorf("expected the alert resolved on lock, got %+v", m.notified)
+	}
+}
+
+func TestManagerUnknownCarrier(t *testing.T) {
+	cfg := beaconPowerConfig()
+	cfg.BeaconPowerDrop = 0
+	cfg.Delay = 0
+	cfg.CarrierRange = 5000
+	cfg.KnownCarriers = []float64{10489752000}
+	m := makeTestManager(cfg)
+
+	in := beaconInput(-40)
+	in.Carriers = []Carrier{
+		{Frequency: in.BeaconFrequency, Bandwidth: 500, Power: -40},         // The beacon
+		{Frequency: 10489752050, Bandwidth: 100, Power: -50},                // Known
+		{Frequency: in.BeaconFrequency + 10000, Bandwidth: 100, Power: -30}, // Out of range
+	}
+	m.evaluate(1, in)
+	if len(m.notified) != 0 {
+		t.Fatalf("expected no alert for known carriers, got %+v", m.notified)
+	}
+
+	in.Carriers = append(in.Carriers, Carrier{Frequency: in.BeaconFrequency - 3000, Bandwidth: 2700, Power: -45})
+	m.evaluate(1, in)
+	if len(m.notified) != 1 || m.notified[0].Rule != RuleUnknownCarrier || m.notified[0].Value != in.BeaconFrequency-3000 {
+		t.Fatalf("expected an unknown carrier alert, got %+v", m.notified)
+	}
+}
@@ -0,0 +1,144 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/quan-to/slog"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	webhookQueueSize  = 32
+	webhookRetryDelay = 5 * time.Second
+)
+
+// Webhook posts the alerts as JSON to an URL. Deliveries run in their own goroutine so a slow endpoint never delays
+// the rule evaluation. Alerts are dropped when the queue is full.
+type Webhook struct {
+	cfg        config.WebhookConfig
+	log        *slog.Instance
+	client     *http.Client
+	rules      map[string]bool
+	retryDelay time.Duration
+	queue      chan Alert
+	done       chan bool
+
+	lock   sync.Mutex
+	errors int
+}
+
+func MakeWebhook(name string, cfg config.WebhookConfig) *Webhook {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = config.DefaultWebhookTimeout
+	}
+
+	w := &Webhook{
+		cfg:        cfg,
+		log:        slog.Scope("Webhook " + name),
+		client:     &http.Client{Timeout: time.Duration(timeout * float64(time.Second))},
+		retryDelay: webhookRetryDelay,
+		queue:      make(chan Alert, webhookQueueSize),
+		done:       make(chan bool),
+	}
+
+	if len(cfg.Rules) > 0 {
+		w.rules = map[string]bool{}
+		for _, r := range cfg.Rules {
+			w.rules[r] = true
+		}
+	}
+
+	return w
+}
+
+func (w *Webhook) Start() {
+	go w.loop()
+}
+
+func (w *Webhook) Stop() {
+	close(w.done)
+}
+
+// Send queues the alert if the webhook is subscribed to its rule. It never blocks.
+func (w *Webhook) Send(a Alert) {
+	if w.rules != nil && !w.rules[a.Rule] {
+		return
+	}
+
+	select {
+	case w.queue <- a:
+	default:
+		w.log.Error("Queue full, dropping alert %d", a.ID)
+		w.countError()
+	}
+}
+
+func (w *Webhook) loop() {
+	for {
+		select {
+		case a := <-w.queue:
+			w.deliver(a)
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *Webhook) deliver(a Alert) {
+	for attempt := 0; attempt <= w.cfg.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(w.retryDelay):
+			case <-w.done:
+				return
+			}
+		}
+
+		err := w.post(a)
+		if err == nil {
+			w.log.Debug("Alert %d delivered to %s", a.ID, w.cfg.URL)
+			return
+		}
+
+		w.log.Error("Error delivering alert %d to %s: %s", a.ID, w.cfg.URL, err)
+		w.countError()
+	}
+}
+
+func (w *Webhook) post(a Alert) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	res, err := w.client.Post(w.cfg.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("got status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+func (w *Webhook) countError() {
+	w.lock.Lock()
+	w.errors++
+	w.lock.Unlock()
+}
+
+// Errors returns the number of failed deliveries
+func (w *Webhook) Errors() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.errors
+}
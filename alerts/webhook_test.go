@@ -0,0 +1,125 @@
+package alerts
+
+import (
+	"encoding/json"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testEndpoint answers the posted alerts with the next status of the list, then 200
+type testEndpoint struct {
+	sync.Mutex
+	statuses []int
+	alerts   []Alert
+}
+
+func (e *testEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var a Alert
+	err := json.NewDecoder(r.Body).Decode(&a)
+	if err != nil || r.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	e.alerts = append(e.alerts, a)
+
+	status := http.StatusOK
+	if len(e.statuses) > 0 {
+		status = e.statuses[0]
+		e.statuses = e.statuses[1:]
+	}
+
+	w.WriteHeader(status)
+}
+
+func (e *testEndpoint) received() []Alert {
+	e.Lock()
+	defer e.Unlock()
+	return append([]Alert{}, e.alerts...)
+}
+
+func makeTestWebhook(url string, retries int, rules ...string) *Webhook {
+	w := MakeWebhook("test", config.WebhookConfig{URL: url, Timeout: 1, Retries: retries, Rules: rules})
+	w.retryDelay = time.Millisecond
+	return w
+}
+
+func TestWebhookDelivers(t *testing.T) {
+	endpoint := &testEndpoint{}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	w := makeTestWebhook(server.URL, 0)
+	w.deliver(Alert{ID: 1, Pipeline: "main", Rule: RuleLockLost, State: StateFiring})
+
+	alerts := endpoint.received()
+	if len(alerts) != 1 || alerts[0].ID != 1 || alerts[0].Rule != RuleLockLost || alerts[0].State != StateFiring {
+		t.Errorf("expected alert 1 to be delivered, got %+v", alerts)
+	}
+	if w.Errors() != 0 {
+		t.Errorf("expected no errors, got %d", w.Errors())
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	endpoint := &testEndpoint{statuses: []int{http.StatusInternalServerError, http.StatusServiceUnavailable}}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	w := makeTestWebhook(server.URL, 2)
+	w.deliver(Alert{ID: 2, Rule: RuleBeaconPower})
+
+	if n := len(endpoint.received()); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+	if w.Errors() != 2 {
+		t.Errorf("expected 2 errors, got %d", w.Errors())
+	}
+}
+
+func TestWebhookGivesUp(t *testing.T) {
+	endpoint := &testEndpoint{statuses: []int{http.StatusNotFound, http.StatusNotFound, http.StatusNotFound}}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	w := makeTestWebhook(server.URL, 1)
+	w.deliver(Alert{ID: 3, Rule: RuleBeaconPower})
+
+	if n := len(endpoint.received()); n != 2 {
+		t.Errorf("expected 2 attempts, got %d", n)
+	}
+	if w.Errors() != 2 {
+		t.Errorf("expected 2 errors, got %d", w.Errors())
+	}
+}
+
+func TestWebhookRules(t *testing.T) {
+	endpoint := &testEndpoint{}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	w := makeTestWebhook(server.URL, 0, RuleLockLost)
+	w.Start()
+
+	w.Send(Alert{ID: 4, Rule: RuleBeaconPower})
+	w.Send(Alert{ID: 5, Rule: RuleLockLost})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(endpoint.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	w.Stop()
+
+	alerts := endpoint.received()
+	if len(alerts) != 1 || alerts[0].ID != 5 {
+		t.Errorf("expected only alert 5 to be delivered, got %+v", alerts)
+	}
+}
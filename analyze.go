@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"github.com/racerxdl/qo100-dedrift/driftlog"
+	"github.com/racerxdl/qo100-dedrift/stability"
+	"os"
+	"sort"
+	"strings"
+)
+
+const AnalyzeCommand = "analyze"
+
+// runAnalyze prints the stability report of drift log files
+func runAnalyze(args []string) {
+	fs := flag.NewFlagSet(AnalyzeCommand, flag.ExitOnError)
+	tauList := fs.String("tau", joinTaus(config.DefaultDriftLogTaus), "comma separated tau values in seconds")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [options] driftlog.csv [driftlog.csv...]\n", os.Args[0], AnalyzeCommand)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	taus, err := stability.ParseTaus(*tauList)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	records := make([]driftlog.Record, 0)
+	for _, filename := range fs.Args() {
+		fileRecords, err := driftlog.ReadFile(filename)
+		if err != nil {
+			log.Fatal("Error reading %s: %s", filename, err)
+		}
+		records = append(records, fileRecords...)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+
+	report, err := stability.Analyze(records, taus)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *asJSON {
+		data, _ := json.MarshalIndent(report, "", "   ")
+		fmt.Println(string(data))
+		return
+	}
+
+	fmt.Printf("Samples:      %d (%s to %s)\n", report.Samples, report.Start.Format("2006-01-02 15:04:05"), report.End.Format("2006-01-02 15:04:05"))
+	fmt.Printf("Tau0:         %.3f s\n", report.Tau0)
+	fmt.Printf("Drift Rate:   %.6f Hz/s (%.6f ppm/h)\n", report.DriftRate, report.DriftRatePPM)
+	fmt.Printf("Peak to Peak: %.3f Hz (%.6f ppm)\n", report.PeakToPeak, report.PeakToPeakPPM)
+	fmt.Println()
+	fmt.Printf("%12s %14s %14s %8s\n", "Tau (s)", "ADEV", "OADEV", "N")
+	for _, p := range report.Points {
+		fmt.Printf("%12.3f %14.4e %14.4e %8d\n", p.Tau, p.ADEV, p.OADEV, p.N)
+	}
+}
+
+func joinTaus(taus []float64) string {
+	s := make([]string, len(taus))
+	for i, v := range taus {
+		s[i] = fmt.Sprintf("%g", v)
+	}
+	return strings.Join(s, ",")
+}
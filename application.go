@@ -1,12 +1,10 @@
 package main
 
 import (
-	"encoding/binary"
 	"flag"
 	"github.com/quan-to/slog"
 	"github.com/racerxdl/qo100-dedrift/config"
-	"github.com/racerxdl/qo100-dedrift/metrics"
-	"github.com/racerxdl/qo100-dedrift/rtltcp"
+	"github.com/racerxdl/qo100-dedrift/dedrift"
 	"github.com/racerxdl/qo100-dedrift/web"
 	"os"
 	"os/signal"
@@ -23,13 +21,19 @@ func init() {
 }
 
 var log = slog.Scope("Application")
-var server *rtltcp.Server
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
 var createDefault = flag.Bool("defaultConfig", false, "write a default config file")
 var pc config.ProgramConfig
+var correctionBus = dedrift.MakeCorrectionBus()
 
 func main() {
 	var err error
+
+	if len(os.Args) > 1 && os.Args[1] == AnalyzeCommand {
+		runAnalyze(os.Args[2:])
+		return
+	}
+
 	flag.Parse()
 	if *cpuprofile != "" {
 		f, err := os.Create(*cpuprofile)
@@ -57,62 +61,45 @@ func main() {
 		log.Fatal("Error loading configuration file at %s: %s", ConfigFileName, err)
 	}
 
-	InitDSP()
-
-	client := rtltcp.MakeClient()
-	err = client.Connect(pc.Source.Address)
-	if err != nil {
-		log.Fatal(err)
+	pipelineConfigs := pc.GetPipelines()
+	names := map[string]bool{}
+	for _, v := range pipelineConfigs {
+		if v.Name == "" || names[v.Name] {
+			log.Fatal("Pipeline names should be unique and not empty. Got %q", v.Name)
+		}
+		if err := web.ValidateNamespaceName(v.Name); err != nil {
+			log.Fatal("Invalid pipeline name %q: %s", v.Name, err)
+		}
+		names[v.Name] = true
 	}
 
-	defer client.Stop()
-
-	metrics.MaxWebConnections.Add(float64(pc.Server.MaxWebConnections))
-	metrics.MaxConnections.Add(float64(pc.Server.MaxRTLConnections))
-	metrics.ServerCenterFrequency.Set(float64(pc.Source.CenterFrequency))
-	metrics.ServerSampleRate.Set(float64(pc.Source.SampleRate))
-
-	_ = client.SetSampleRate(pc.Source.SampleRate)
-	_ = client.SetCenterFrequency(pc.Source.CenterFrequency)
-	client.SetOnSamples(func(data []complex64) {
-		sampleFifo.Add(data)
-	})
-	_ = client.SetGain(uint32(pc.Source.Gain * 10))
-
-	server = rtltcp.MakeRTLTCPServer(pc.Server.RTLTCPAddress)
-	server.SetDongleInfo(client.GetDongleInfo())
-	server.SetOnCommand(func(sessionId string, cmd rtltcp.Command) bool {
-		if cmd.Type == rtltcp.SetSampleRate {
-			sampleRate := binary.BigEndian.Uint32(cmd.Param[:])
-			if sampleRate != pc.Source.SampleRate {
-				log.Error("Client asked for %d as sampleRate, but we cannot change it! Current: %d", sampleRate, pc.Source.SampleRate)
-				log.Error("Closing connection with %s", sessionId)
-				return false
-			}
-			return true
+	for _, v := range pipelineConfigs {
+		if v.Uplink.Enable && v.Uplink.FollowRigctl && !v.Rigctl.Enable {
+			log.Fatal("Pipeline %s uplink follows the rigctl frequency but rigctl is disabled", v.Name)
+		}
+		if v.Correction.Subscribe != "" && (!names[v.Correction.Subscribe] || v.Correction.Subscribe == v.Name) {
+			log.Fatal("Pipeline %s subscribes to the correction of an invalid pipeline %q", v.Name, v.Correction.Subscribe)
 		}
+	}
 
-		if pc.Server.AllowControl {
-			_ = client.SendCommand(cmd)
-			if cmd.Type == rtltcp.SetFrequency {
-				frequency := binary.BigEndian.Uint32(cmd.Param[:])
-				OnChangeFrequency(frequency)
-			}
-		} else {
-			log.Warn("Ignoring command %s because AllowControl is false", rtltcp.CommandTypeToName[cmd.Type])
+	stateFiles := map[string]bool{}
+	for _, v := range pipelineConfigs {
+		if !v.Processing.State.Enable {
+			continue
+		}
+		if stateFiles[v.Processing.State.File] {
+			log.Fatal("Pipeline %s uses the state file %s of another pipeline", v.Name, v.Processing.State.File)
 		}
+		stateFiles[v.Processing.State.File] = true
+	}
 
-		return true
-	})
+	ws := web.MakeWebServer(pc.Server.HTTPAddress)
 
-	err = server.Start()
-	if err != nil {
-		log.Fatal("Error starting RTLTCP: %s", err)
+	pipelines := make([]*Pipeline, len(pipelineConfigs))
+	for i, v := range pipelineConfigs {
+		pipelines[i] = MakePipeline(v, ws)
 	}
 
-	defer server.Stop()
-
-	ws := web.MakeWebServer(pc.Server.HTTPAddress, pc.Server.MaxWebConnections, pc.Server.WebSettings)
 	err = ws.Start()
 
 	if err != nil {
@@ -121,13 +108,13 @@ func main() {
 
 	defer ws.Stop()
 
-	SetOnFFT(func(segFFT, fullFFT []float32) {
-		ws.BroadcastFFT(web.MessageTypeMainFFT, fullFFT)
-		ws.BroadcastFFT(web.MessageTypeSegFFT, segFFT)
-	})
-
-	dspRunning = true
-	go DSP()
+	for _, p := range pipelines {
+		err = p.Start()
+		if err != nil {
+			log.Fatal("Error starting pipeline %s: %s", p.name, err)
+		}
+		defer p.Stop()
+	}
 
 	var sig = make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt)
@@ -143,5 +130,4 @@ func main() {
 	//		running = false
 	//	//}
 	//}
-	dspRunning = false
 }
@@ -0,0 +1,72 @@
+package channel
+
+import (
+	"github.com/racerxdl/segdsp/dsp"
+)
+
+const (
+	// Output rate of the first stage, relative to the channel bandwidth
+	firstStageRatio = 8
+)
+
+// Channelizer extracts a narrow channel from a wide band stream.
+// It mixes the channel to DC and decimates it in two stages to keep the filters short.
+type Channelizer struct {
+	sampleRate float64
+	nco        *NCO
+	mixed      []complex64
+	first      *Decimator
+	second     *Decimator
+}
+
+// MakeChannelizer creates a channelizer for a channel of bandwidth Hertz at offset Hertz from the center of the input.
+// The output sample rate is at least twice the bandwidth. Use GetSampleRate to get the exact value.
+func MakeChannelizer(sampleRate, offset, bandwidth float64) *Channelizer {
+	firstDecimation := int(sampleRate / (bandwidth * firstStageRatio))
+	if firstDecimation < 1 {
+		firstDecimation = 1
+	}
+	firstRate := sampleRate / float64(firstDecimation)
+
+	secondDecimation := int(firstRate / (bandwidth * 2))
+	if secondDecimation < 1 {
+		secondDecimation = 1
+	}
+
+	// The first stage only has to protect the band that survives the second stage
+	firstCutoff := firstRate / float64(secondDecimation) / 2
+	firstTransition := firstRate/2 - firstCutoff
+
+	return &Channelizer{
+		sampleRate: sampleRate,
+		nco:        MakeNCO(sampleRate, -offset),
+		first:      MakeDecimator(firstDecimation, dsp.MakeLowPass(1, sampleRate, firstCutoff, firstTransition)),
+		second:     MakeDecimator(secondDecimation, dsp.MakeLowPass(1, firstRate, bandwidth/2, bandwidth/2)),
+	}
+}
+
+// SetOffset changes the channel frequency relative to the center of the input
+func (c *Channelizer) SetOffset(offset float64) {
+	c.nco.SetFrequency(-offset)
+}
+
+func (c *Channelizer) GetSampleRate() float64 {
+	return c.sampleRate / float64(c.first.GetDecimation()*c.second.GetDecimation())
+}
+
+// Work returns the channel samples from samples. The returned slice is reused by the next call.
+func (c *Channelizer) Work(samples []complex64) []complex64 {
+	if cap(c.mixed) < len(samples) {
+		c.mixed = make([]complex64, len(samples))
+	}
+	c.mixed = c.mixed[:len(samples)]
+
+	c.nco.Mix(c.mixed, samples)
+
+	return c.second.Work(c.first.Work(c.mixed))
+}
+
+func (c *Channelizer) Reset() {
+	c.first.Reset()
+	c.second.Reset()
+}
@@ -0,0 +1,117 @@
+package channel
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func tone(sampleRate, frequency float64, amplitude float32, length int) []complex64 {
+	samples := make([]complex64, length)
+	for i := range samples {
+		s, c := math.Sincos(2 * math.Pi * frequency * float64(i) / sampleRate)
+		samples[i] = complex(amplitude*float32(c), amplitude*float32(s))
+	}
+	return samples
+}
+
+func add(a, b []complex64) []complex64 {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+// work feeds samples in blocks of uneven sizes, so the output does not depend on the block boundaries
+func work(c *Channelizer, samples []complex64) []complex64 {
+	var output []complex64
+	for i, n := 0, 0; i < len(samples); i += n {
+		n = 1000 + (i/1000)%7*113
+		if i+n > len(samples) {
+			n = len(samples) - i
+		}
+		output = append(output, c.Work(samples[i:i+n])...)
+	}
+	return output
+}
+
+// frequency returns the average frequency of a tone from its phase increments
+func frequency(samples []complex64, sampleRate float64) float64 {
+	sum := complex128(0)
+	for i := 1; i < len(samples); i++ {
+		sum += complex128(samples[i]) * cmplx.Conj(complex128(samples[i-1]))
+	}
+	return cmplx.Phase(sum) * sampleRate / (2 * math.Pi)
+}
+
+func power(samples []complex64) float64 {
+	p := 0.0
+	for _, v := range samples {
+		p += float64(real(v)*real(v) + imag(v)*imag(v))
+	}
+	return p / float64(len(samples))
+}
+
+func TestChannelizer(t *testing.T) {
+	const (
+		sampleRate = 1.2e6
+		offset     = 150e3
+		bandwidth  = 400
+	)
+
+	c := MakeChannelizer(sampleRate, offset, bandwidth)
+	outRate := c.GetSampleRate()
+	if outRate < 2*bandwidth || outRate > 4*bandwidth {
+		t.Fatalf("expected an output rate between %d and %d, got %f", 2*bandwidth, 4*bandwidth, outRate)
+	}
+
+	// A tone 50 Hz above the channel center, and a stronger one outside of it
+	length := int(sampleRate)
+	input := add(tone(sampleRate, offset+50, 0.1, length), tone(sampleRate, -offset, 1, length))
+	output := work(c, input)
+
+	expectedLength := float64(length) * outRate / sampleRate
+	// The filters hold back their length in samples
+	if math.Abs(float64(len(output))-expectedLength) > expectedLength*0.02 {
+		t.Errorf("expected about %.0f samples, got %d", expectedLength, len(output))
+	}
+
+	// Skip the filters warm up
+	output = output[len(output)/10:]
+
+	if f := frequency(output, outRate); math.Abs(f-50) > 0.5 {
+		t.Errorf("expected the tone shifted to 50 Hz, got %f Hz", f)
+	}
+
+	if p := 10 * math.Log10(power(output)/0.01); math.Abs(p) > 0.5 {
+		t.Errorf("expected the tone power to be kept, got %f dB", p)
+	}
+
+	// Moving the channel to the strong tone
+	c.SetOffset(-offset)
+	c.Reset()
+	output = work(c, tone(sampleRate, -offset, 1, length/10))
+	output = output[len(output)/2:]
+	if f := frequency(output, outRate); math.Abs(f) > 0.5 {
+		t.Errorf("expected the tone at DC after SetOffset, got %f Hz", f)
+	}
+}
+
+func TestChannelizerRejection(t *testing.T) {
+	const (
+		sampleRate = 1.2e6
+		bandwidth  = 400
+	)
+
+	c := MakeChannelizer(sampleRate, 0, bandwidth)
+	length := int(sampleRate / 2)
+
+	for _, f := range []float64{2 * bandwidth, 10e3, 300e3} {
+		c.Reset()
+		output := work(c, tone(sampleRate, f, 1, length))
+		output = output[len(output)/2:]
+		if p := 10 * math.Log10(power(output)); p > -40 {
+			t.Errorf("expected a tone at %.0f Hz to be rejected, got %f dB", f, p)
+		}
+	}
+}
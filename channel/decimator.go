@@ -0,0 +1,48 @@
+package channel
+
+import (
+	"github.com/racerxdl/segdsp/dsp"
+)
+
+// Decimator is a low pass FIR filter that keeps its history between calls, so the output is continuous
+// regardless of the input block sizes. segdsp FirFilter drops the samples that do not fill a whole decimation step.
+type Decimator struct {
+	decimation int
+	taps       []float32
+	history    []complex64
+	output     []complex64
+}
+
+func MakeDecimator(decimation int, taps []float32) *Decimator {
+	if decimation < 1 {
+		decimation = 1
+	}
+
+	return &Decimator{
+		decimation: decimation,
+		taps:       taps,
+	}
+}
+
+// Work filters and decimates samples. The returned slice is reused by the next call.
+func (d *Decimator) Work(samples []complex64) []complex64 {
+	d.history = append(d.history, samples...)
+	d.output = d.output[:0]
+
+	i := 0
+	for ; i+len(d.taps) <= len(d.history); i += d.decimation {
+		d.output = append(d.output, dsp.DotProductResult(d.history[i:], d.taps))
+	}
+
+	d.history = d.history[:copy(d.history, d.history[i:])]
+
+	return d.output
+}
+
+func (d *Decimator) GetDecimation() int {
+	return d.decimation
+}
+
+func (d *Decimator) Reset() {
+	d.history = d.history[:0]
+}
@@ -0,0 +1,55 @@
+package channel
+
+import (
+	"math"
+)
+
+const (
+	ncoTableBits = 16
+	ncoShift     = 32 - ncoTableBits
+)
+
+// ncoTable holds one turn of the complex exponential. The phase truncation error stays under -75 dB.
+var ncoTable = makeNCOTable()
+
+func makeNCOTable() []complex64 {
+	table := make([]complex64, 1<<ncoTableBits)
+	for i := range table {
+		s, c := math.Sincos(2 * math.Pi * float64(i) / float64(len(table)))
+		table[i] = complex(float32(c), float32(s))
+	}
+	return table
+}
+
+// NCO is a numerically controlled oscillator with a 32 bit phase accumulator. It looks the oscillator up in a
+// shared table, so mixing a full rate stream does not call math.Sincos for every sample.
+type NCO struct {
+	sampleRate float64
+	phase      uint32
+	increment  uint32
+}
+
+func MakeNCO(sampleRate, frequency float64) *NCO {
+	n := &NCO{
+		sampleRate: sampleRate,
+	}
+
+	n.SetFrequency(frequency)
+
+	return n
+}
+
+// SetFrequency changes the oscillator frequency keeping its phase. Negative frequencies rotate clockwise.
+func (n *NCO) SetFrequency(frequency float64) {
+	turns := frequency / n.sampleRate
+	turns -= math.Floor(turns)
+	n.increment = uint32(uint64(math.Round(turns*(1<<32))) & math.MaxUint32)
+}
+
+// Mix multiplies samples by the oscillator and writes the result to out, which must be as long as samples
+func (n *NCO) Mix(out, samples []complex64) {
+	for i, v := range samples {
+		out[i] = v * ncoTable[n.phase>>ncoShift]
+		n.phase += n.increment
+	}
+}
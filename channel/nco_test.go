@@ -0,0 +1,35 @@
+package channel
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestNCO(t *testing.T) {
+	const sampleRate = 1.2e6
+
+	for _, f := range []float64{0, 1000.25, -143e3, 599e3} {
+		n := MakeNCO(sampleRate, f)
+		ones := make([]complex64, 100000)
+		for i := range ones {
+			ones[i] = 1
+		}
+		out := make([]complex64, len(ones))
+		n.Mix(out, ones)
+
+		if got := frequency(out, sampleRate); math.Abs(got-f) > 0.01 {
+			t.Errorf("expected %f Hz, got %f Hz", f, got)
+		}
+
+		// Compare with the exact oscillator, the table error must be well under the channel dynamic range
+		maxError := 0.0
+		for i, v := range out {
+			phase := 2 * math.Pi * math.Mod(f*float64(i)/sampleRate, 1)
+			maxError = math.Max(maxError, cmplx.Abs(complex128(v)-cmplx.Rect(1, phase)))
+		}
+		if e := 20 * math.Log10(maxError); e > -75 {
+			t.Errorf("%f Hz: expected the error under -75 dB, got %f dB", f, e)
+		}
+	}
+}
@@ -1,12 +1,53 @@
 package config
 
 const (
-	DefaultSourceAddress   = "127.0.0.1:1235"
-	DefaultSampleRate      = 1800e3
-	DefaultCenterFrequency = 740000000
-	DefaultBeaconOffset    = 143e3
-	DefaultWorkDecimation  = 32
-	DefaultGain            = 20
+	DefaultSourceAddress     = "127.0.0.1:1235"
+	DefaultSampleRate        = 1800e3
+	DefaultCenterFrequency   = 740000000
+	DefaultLNBFrequency      = 9750000000
+	DefaultUpconverterOffset = 0
+	DefaultBeaconFrequency   = 0
+	DefaultBeaconOffset      = 143e3
+	DefaultWorkDecimation    = 32
+	DefaultGain              = 20
+)
+
+const (
+	DefaultAutoGainEnable      = false
+	DefaultAutoGainTargetLow   = -25
+	DefaultAutoGainTargetHigh  = -15
+	DefaultAutoGainMaxClipRate = 0.0001
+	DefaultAutoGainMinGain     = 0
+	DefaultAutoGainMaxGain     = 49.6
+	DefaultAutoGainStep        = 3
+	DefaultAutoGainInterval    = 2
+	DefaultAutoGainHoldTime    = 10
+	DefaultAutoGainByIndex     = false
+)
+
+const (
+	DefaultHardwareCorrectionEnable     = false
+	DefaultHardwareCorrectionMode       = "frequency"
+	DefaultHardwareCorrectionMinStep    = 100
+	DefaultHardwareCorrectionHysteresis = 500
+	DefaultHardwareCorrectionInterval   = 5
+	DefaultHardwareCorrectionLatency    = 0.1
+)
+
+const (
+	DefaultTemperatureEnable        = false
+	DefaultTemperatureInterval      = 10
+	DefaultTemperatureModelInterval = 60
+	DefaultTemperatureModelSamples  = 10080
+	DefaultTemperatureMinSamples    = 60
+	DefaultTemperatureMinSpan       = 2
+	DefaultTemperatureModelFile     = "qo100-temperature.json"
+	DefaultTemperatureHoldover      = true
+	DefaultTemperatureHoldoverDelay = 10
+)
+
+const (
+	DefaultPipelineName = "main"
 )
 
 const (
@@ -17,12 +58,41 @@ const (
 	DefaultMaxRTLConnections = 5
 )
 
+const (
+	DefaultRigctlEnable          = false
+	DefaultRigctlAddress         = ":4532"
+	DefaultRigctlTarget          = "virtual"
+	DefaultRigctlFrequencyOffset = 0
+	DefaultRigctlMode            = "USB"
+	DefaultRigctlPassband        = 2700
+)
+
+const (
+	DefaultUplinkEnable            = false
+	DefaultUplinkAddress           = "127.0.0.1:4533"
+	DefaultUplinkFrequency         = 2400250000
+	DefaultUplinkFollowRigctl      = false
+	DefaultUplinkTransponderOffset = 8089500000
+	// The LNB and the transmitter share the reference. A reference error that moves the beacon by drift Hertz moves
+	// the uplink by -drift * uplink / LNB LO, which is corrected by this ratio (about 0.246 on QO-100).
+	DefaultUplinkDriftRatio    = float64(DefaultUplinkFrequency) / DefaultLNBFrequency
+	DefaultUplinkMinStep       = 5
+	DefaultUplinkMinInterval   = 1
+	DefaultUplinkMaxCorrection = 5000
+)
+
 const (
 	DefaultFFTWindowMaxVal = -70
 	DefaultFFTWindowRange  = 40
 	DefaultFFTWindowWidth  = 512
 	DefaultFFTWindowHeight = 256
 	DefaultFFTHighQuality  = true
+	DefaultFFTSize         = 1024
+	DefaultFFTFPS          = 15
+	DefaultFFTWindow       = "hann"
+	DefaultFFTAveraging    = "exponential"
+	DefaultFFTAveragingN   = 2
+	DefaultFFTSmoothing    = 0.4
 )
 
 const (
@@ -39,15 +109,168 @@ const (
 )
 
 const (
-	DefaultCostasLoopBandwidth = 0.01
+	DefaultCostasLoopBandwidth             = 0.01
+	DefaultCostasLoopGearShifting          = true
+	DefaultCostasLoopAcquisitionBandwidth  = 0.05
+	DefaultCostasLoopIntermediateBandwidth = 0.01
+	DefaultCostasLoopTrackingBandwidth     = 0.002
+	DefaultCostasLoopLockThreshold         = 0.8
+	DefaultCostasLoopUnlockThreshold       = 0.4
+	DefaultCostasLoopLockHoldTime          = 2
+	DefaultCostasLoopUnlockHoldTime        = 0.5
+)
+
+const (
+	DefaultResamplerEnable        = false
+	DefaultResamplerPPM           = 0
+	DefaultResamplerMeasurePPM    = true
+	DefaultResamplerMeasureWindow = 300
+)
+
+const (
+	DefaultRetuneFlushTime   = 0.05
+	DefaultRetuneHoldTimeout = 30
+)
+
+const (
+	DefaultDriftLogEnable    = false
+	DefaultDriftLogDirectory = "driftlog"
+	DefaultDriftLogFormat    = "csv"
+	DefaultDriftLogInterval  = 1
+	DefaultDriftLogMaxDays   = 30
+	DefaultDriftLogMaxHours  = 24
+)
+
+var DefaultDriftLogTaus = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000}
+
+const (
+	DefaultSignalsEnable          = false
+	DefaultSignalsFFTSize         = 32768
+	DefaultSignalsInterval        = 1
+	DefaultSignalsAveraging       = 4
+	DefaultSignalsThreshold       = 8
+	DefaultSignalsMergeGap        = 300
+	DefaultSignalsMinBandwidth    = 0
+	DefaultSignalsEdgeExclusion   = 0.05
+	DefaultSignalsMinDetections   = 2
+	DefaultSignalsHoldTime        = 5
+	DefaultSignalsCWMaxBandwidth  = 300
+	DefaultSignalsSSBMaxBandwidth = 3500
+	DefaultSignalsDigitalFlatness = 0.7
+	DefaultSignalsEventLog        = ""
+)
+
+const (
+	DefaultOccupancyEnable       = false
+	DefaultOccupancyDirectory    = "occupancy"
+	DefaultOccupancyBucketSize   = 3600
+	DefaultOccupancyBins         = 1024
+	DefaultOccupancySaveInterval = 60
+	DefaultOccupancyMaxDays      = 90
+	DefaultOccupancyMaxHours     = 168
+	DefaultOccupancyRowHeight    = 4
+)
+
+const (
+	DefaultAlertsEnable           = false
+	DefaultAlertsDelay            = 10
+	DefaultAlertsReferenceTime    = 600
+	DefaultAlertsHistory          = 50
+	DefaultAlertsBeaconPowerDrop  = 6
+	DefaultAlertsNoiseFloorRise   = 6
+	DefaultAlertsCarrierRange     = 5000
+	DefaultAlertsCarrierTolerance = 100
+	DefaultAlertsLockLostTime     = 60
+	DefaultWebhookTimeout         = 5
+	DefaultWebhookRetries         = 2
+)
+
+const (
+	DefaultBeaconBandwidth = 1000
+)
+
+// The PSK decoder defaults follow the AMSAT Phase 3 telemetry block format of the QO-100 narrowband BPSK beacon
+const (
+	DefaultPSKDecoderEnable        = false
+	DefaultPSKDecoderSymbolRate    = 400
+	DefaultPSKDecoderSyncWord      = "3915ED30"
+	DefaultPSKDecoderMaxSyncErrors = 2
+	DefaultPSKDecoderFrameLength   = 512
+	DefaultPSKDecoderDifferential  = false
+	DefaultPSKDecoderFrameHistory  = 20
+	DefaultPSKDecoderValidFor      = 60
+	DefaultPSKDecoderRequireDecode = false
+)
+
+const (
+	DefaultCWDecoderEnable          = false
+	DefaultCWDecoderFrequency       = 10489500000
+	DefaultCWDecoderBandwidth       = 400
+	DefaultCWDecoderWPM             = 20
+	DefaultCWDecoderExpectedText    = "QO-100"
+	DefaultCWDecoderMinConfidence   = 0.6
+	DefaultCWDecoderValidFor        = 300
+	DefaultCWDecoderTextLength      = 128
+	DefaultCWDecoderRequireVerified = false
+)
+
+const (
+	DefaultResidualEnable       = false
+	DefaultResidualBandwidth    = 1000
+	DefaultResidualFFTSize      = 8192
+	DefaultResidualInterval     = 1
+	DefaultResidualAveraging    = 4
+	DefaultResidualMaxError     = 10
+	DefaultResidualJitterWindow = 60
+)
+
+const (
+	DefaultStateEnable   = false
+	DefaultStateFile     = "qo100-state.json"
+	DefaultStateInterval = 10
+	DefaultStateMaxAge   = 600
 )
 
 var DefaultConfig = ProgramConfig{
 	Source: SourceConfig{
-		Address:         DefaultSourceAddress,
-		SampleRate:      DefaultSampleRate,
-		CenterFrequency: DefaultCenterFrequency,
-		Gain:            DefaultGain,
+		Address:           DefaultSourceAddress,
+		SampleRate:        DefaultSampleRate,
+		CenterFrequency:   DefaultCenterFrequency,
+		LNBFrequency:      DefaultLNBFrequency,
+		UpconverterOffset: DefaultUpconverterOffset,
+		Gain:              DefaultGain,
+		AutoGain: AutoGainConfig{
+			Enable:      DefaultAutoGainEnable,
+			TargetLow:   DefaultAutoGainTargetLow,
+			TargetHigh:  DefaultAutoGainTargetHigh,
+			MaxClipRate: DefaultAutoGainMaxClipRate,
+			MinGain:     DefaultAutoGainMinGain,
+			MaxGain:     DefaultAutoGainMaxGain,
+			Step:        DefaultAutoGainStep,
+			Interval:    DefaultAutoGainInterval,
+			HoldTime:    DefaultAutoGainHoldTime,
+			ByIndex:     DefaultAutoGainByIndex,
+		},
+		HardwareCorrection: HardwareCorrectionConfig{
+			Enable:     DefaultHardwareCorrectionEnable,
+			Mode:       DefaultHardwareCorrectionMode,
+			MinStep:    DefaultHardwareCorrectionMinStep,
+			Hysteresis: DefaultHardwareCorrectionHysteresis,
+			Interval:   DefaultHardwareCorrectionInterval,
+			Latency:    DefaultHardwareCorrectionLatency,
+		},
+		Temperature: TemperatureConfig{
+			Enable:        DefaultTemperatureEnable,
+			Interval:      DefaultTemperatureInterval,
+			Sensors:       []TemperatureSensorConfig{},
+			ModelInterval: DefaultTemperatureModelInterval,
+			ModelSamples:  DefaultTemperatureModelSamples,
+			MinSamples:    DefaultTemperatureMinSamples,
+			MinSpan:       DefaultTemperatureMinSpan,
+			ModelFile:     DefaultTemperatureModelFile,
+			Holdover:      DefaultTemperatureHoldover,
+			HoldoverDelay: DefaultTemperatureHoldoverDelay,
+		},
 	},
 	Server: ServerConfig{
 		RTLTCPAddress:     DefaultRTLTCPAddress,
@@ -63,18 +286,100 @@ var DefaultConfig = ProgramConfig{
 				Range:  DefaultFFTWindowRange,
 				Width:  DefaultFFTWindowWidth,
 				Height: DefaultFFTWindowHeight,
+
+				Size:            DefaultFFTSize,
+				FPS:             DefaultFFTFPS,
+				Window:          DefaultFFTWindow,
+				Averaging:       DefaultFFTAveraging,
+				AveragingFrames: DefaultFFTAveragingN,
+				Smoothing:       DefaultFFTSmoothing,
 			},
 			FullFFT: FFTWindowSetting{
 				MaxVal: DefaultFFTWindowMaxVal,
 				Range:  DefaultFFTWindowRange,
 				Width:  DefaultFFTWindowWidth,
 				Height: DefaultFFTWindowHeight,
+
+				Size:            DefaultFFTSize,
+				FPS:             DefaultFFTFPS,
+				Window:          DefaultFFTWindow,
+				Averaging:       DefaultFFTAveraging,
+				AveragingFrames: DefaultFFTAveragingN,
+				Smoothing:       DefaultFFTSmoothing,
 			},
 		},
+		Rigctl: RigctlConfig{
+			Enable:          DefaultRigctlEnable,
+			Address:         DefaultRigctlAddress,
+			Target:          DefaultRigctlTarget,
+			FrequencyOffset: DefaultRigctlFrequencyOffset,
+			Mode:            DefaultRigctlMode,
+			Passband:        DefaultRigctlPassband,
+		},
+		Uplink: UplinkConfig{
+			Enable:            DefaultUplinkEnable,
+			Address:           DefaultUplinkAddress,
+			Frequency:         DefaultUplinkFrequency,
+			FollowRigctl:      DefaultUplinkFollowRigctl,
+			TransponderOffset: DefaultUplinkTransponderOffset,
+			DriftRatio:        DefaultUplinkDriftRatio,
+			MinStep:           DefaultUplinkMinStep,
+			MinInterval:       DefaultUplinkMinInterval,
+			MaxCorrection:     DefaultUplinkMaxCorrection,
+		},
+		DriftLog: DriftLogConfig{
+			Enable:    DefaultDriftLogEnable,
+			Directory: DefaultDriftLogDirectory,
+			Format:    DefaultDriftLogFormat,
+			Interval:  DefaultDriftLogInterval,
+			MaxDays:   DefaultDriftLogMaxDays,
+			MaxHours:  DefaultDriftLogMaxHours,
+			Taus:      DefaultDriftLogTaus,
+		},
+		Signals: SignalsConfig{
+			Enable:          DefaultSignalsEnable,
+			FFTSize:         DefaultSignalsFFTSize,
+			Interval:        DefaultSignalsInterval,
+			Averaging:       DefaultSignalsAveraging,
+			Threshold:       DefaultSignalsThreshold,
+			MergeGap:        DefaultSignalsMergeGap,
+			MinBandwidth:    DefaultSignalsMinBandwidth,
+			EdgeExclusion:   DefaultSignalsEdgeExclusion,
+			MinDetections:   DefaultSignalsMinDetections,
+			HoldTime:        DefaultSignalsHoldTime,
+			CWMaxBandwidth:  DefaultSignalsCWMaxBandwidth,
+			SSBMaxBandwidth: DefaultSignalsSSBMaxBandwidth,
+			DigitalFlatness: DefaultSignalsDigitalFlatness,
+			EventLog:        DefaultSignalsEventLog,
+		},
+		Occupancy: OccupancyConfig{
+			Enable:       DefaultOccupancyEnable,
+			Directory:    DefaultOccupancyDirectory,
+			BucketSize:   DefaultOccupancyBucketSize,
+			Bins:         DefaultOccupancyBins,
+			SaveInterval: DefaultOccupancySaveInterval,
+			MaxDays:      DefaultOccupancyMaxDays,
+			MaxHours:     DefaultOccupancyMaxHours,
+			RowHeight:    DefaultOccupancyRowHeight,
+		},
+		Alerts: AlertsConfig{
+			Enable:           DefaultAlertsEnable,
+			Delay:            DefaultAlertsDelay,
+			ReferenceTime:    DefaultAlertsReferenceTime,
+			History:          DefaultAlertsHistory,
+			BeaconPowerDrop:  DefaultAlertsBeaconPowerDrop,
+			NoiseFloorRise:   DefaultAlertsNoiseFloorRise,
+			CarrierRange:     DefaultAlertsCarrierRange,
+			CarrierTolerance: DefaultAlertsCarrierTolerance,
+			KnownCarriers:    []float64{},
+			LockLostTime:     DefaultAlertsLockLostTime,
+			Webhooks:         []WebhookConfig{},
+		},
 	},
 	Processing: ProcessingConfig{
-		BeaconOffset:   DefaultBeaconOffset,
-		WorkDecimation: DefaultWorkDecimation,
+		BeaconFrequency: DefaultBeaconFrequency,
+		BeaconOffset:    DefaultBeaconOffset,
+		WorkDecimation:  DefaultWorkDecimation,
 		AGC: AGCConfig{
 			AttackRate: DefaultAGCAttackRate,
 			DecayRate:  DefaultAGCDecayRate,
@@ -83,11 +388,69 @@ var DefaultConfig = ProgramConfig{
 			MaxGain:    DefaultAGCMaxGain,
 		},
 		CostasLoop: LoopConfig{
-			Bandwidth: DefaultCostasLoopBandwidth,
+			Bandwidth:             DefaultCostasLoopBandwidth,
+			GearShifting:          DefaultCostasLoopGearShifting,
+			AcquisitionBandwidth:  DefaultCostasLoopAcquisitionBandwidth,
+			IntermediateBandwidth: DefaultCostasLoopIntermediateBandwidth,
+			TrackingBandwidth:     DefaultCostasLoopTrackingBandwidth,
+			LockThreshold:         DefaultCostasLoopLockThreshold,
+			UnlockThreshold:       DefaultCostasLoopUnlockThreshold,
+			LockHoldTime:          DefaultCostasLoopLockHoldTime,
+			UnlockHoldTime:        DefaultCostasLoopUnlockHoldTime,
 		},
 		Translation: TranslationConfig{
 			TransitionWidth: DefaultTranslatorTransitionWidth,
 			Gain:            DefaultTranslatorGain,
 		},
+		Resampler: ResamplerConfig{
+			Enable:        DefaultResamplerEnable,
+			PPM:           DefaultResamplerPPM,
+			MeasurePPM:    DefaultResamplerMeasurePPM,
+			MeasureWindow: DefaultResamplerMeasureWindow,
+		},
+		Retune: RetuneConfig{
+			FlushTime:   DefaultRetuneFlushTime,
+			HoldTimeout: DefaultRetuneHoldTimeout,
+		},
+		State: StateConfig{
+			Enable:   DefaultStateEnable,
+			File:     DefaultStateFile,
+			Interval: DefaultStateInterval,
+			MaxAge:   DefaultStateMaxAge,
+		},
+		Beacon: BeaconConfig{
+			Bandwidth: DefaultBeaconBandwidth,
+		},
+		PSKDecoder: PSKDecoderConfig{
+			Enable:        DefaultPSKDecoderEnable,
+			SymbolRate:    DefaultPSKDecoderSymbolRate,
+			SyncWord:      DefaultPSKDecoderSyncWord,
+			MaxSyncErrors: DefaultPSKDecoderMaxSyncErrors,
+			FrameLength:   DefaultPSKDecoderFrameLength,
+			Differential:  DefaultPSKDecoderDifferential,
+			FrameHistory:  DefaultPSKDecoderFrameHistory,
+			ValidFor:      DefaultPSKDecoderValidFor,
+			RequireDecode: DefaultPSKDecoderRequireDecode,
+		},
+		CWDecoder: CWDecoderConfig{
+			Enable:          DefaultCWDecoderEnable,
+			Frequency:       DefaultCWDecoderFrequency,
+			Bandwidth:       DefaultCWDecoderBandwidth,
+			WPM:             DefaultCWDecoderWPM,
+			ExpectedText:    DefaultCWDecoderExpectedText,
+			MinConfidence:   DefaultCWDecoderMinConfidence,
+			ValidFor:        DefaultCWDecoderValidFor,
+			TextLength:      DefaultCWDecoderTextLength,
+			RequireVerified: DefaultCWDecoderRequireVerified,
+		},
+		Residual: ResidualConfig{
+			Enable:       DefaultResidualEnable,
+			Bandwidth:    DefaultResidualBandwidth,
+			FFTSize:      DefaultResidualFFTSize,
+			Interval:     DefaultResidualInterval,
+			Averaging:    DefaultResidualAveraging,
+			MaxError:     DefaultResidualMaxError,
+			JitterWindow: DefaultResidualJitterWindow,
+		},
 	},
 }
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"github.com/quan-to/slog"
+	"math"
+)
+
+var log = slog.Scope("Config")
+
+// beaconMismatchTolerance is the difference in Hertz between the BeaconOffset and the offset derived from the
+// BeaconFrequency above which the BeaconOffset is reported as ignored
+const beaconMismatchTolerance = 1
+
+// ToRF converts a frequency seen by the source (IF) to the absolute RF frequency
+func (s SourceConfig) ToRF(ifFrequency float64) float64 {
+	return ifFrequency - s.UpconverterOffset + s.LNBFrequency
+}
+
+// ToIF converts an absolute RF frequency to the frequency seen by the source (IF)
+func (s SourceConfig) ToIF(rfFrequency float64) float64 {
+	return rfFrequency - s.LNBFrequency + s.UpconverterOffset
+}
+
+// resolveBeacon derives the BeaconOffset from the absolute BeaconFrequency.
+// If BeaconFrequency is not set, it is derived from the BeaconOffset instead.
+func (pc PipelineConfig) resolveBeacon() PipelineConfig {
+	center := float64(pc.Source.CenterFrequency)
+
+	if pc.Processing.BeaconFrequency != 0 {
+		offset := float32(pc.Source.ToIF(pc.Processing.BeaconFrequency) - center)
+		if pc.Processing.BeaconOffset != 0 && math.Abs(float64(offset-pc.Processing.BeaconOffset)) > beaconMismatchTolerance {
+			log.Warn("Pipeline %s: BeaconFrequency %.0f Hz is %.0f Hz from the center, the BeaconOffset of %.0f Hz is ignored", pc.Name, pc.Processing.BeaconFrequency, offset, pc.Processing.BeaconOffset)
+		}
+		pc.Processing.BeaconOffset = offset
+	} else {
+		pc.Processing.BeaconFrequency = pc.Source.ToRF(center + float64(pc.Processing.BeaconOffset))
+	}
+
+	return pc
+}
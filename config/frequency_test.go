@@ -0,0 +1,30 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestBeaconOffsetDefault(t *testing.T) {
+	p := DefaultConfig.GetPipelines()[0]
+
+	if p.Processing.BeaconOffset != DefaultBeaconOffset {
+		t.Errorf("expected the default beacon offset, got %f", p.Processing.BeaconOffset)
+	}
+
+	expected := float64(DefaultLNBFrequency + DefaultCenterFrequency + DefaultBeaconOffset)
+	if p.Processing.BeaconFrequency != expected {
+		t.Errorf("expected beacon frequency %f, got %f", expected, p.Processing.BeaconFrequency)
+	}
+}
+
+func TestBeaconFrequencyOverridesOffset(t *testing.T) {
+	pc := loadString(t, `
+[Processing]
+  BeaconFrequency = 10490050000.0
+`)
+
+	p := pc.GetPipelines()[0]
+	if p.Processing.BeaconOffset != 50000 {
+		t.Errorf("expected beacon offset 50000, got %f", p.Processing.BeaconOffset)
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"github.com/BurntSushi/toml"
 	"io/ioutil"
 	"os"
+	"reflect"
+	"strings"
 )
 
+// LoadConfig reads the configuration file. Keys missing from the file take their value from DefaultConfig, so
+// sections added by newer versions get working defaults instead of zero values.
 func LoadConfig(filename string) (pc ProgramConfig, err error) {
 	var data []byte
 
@@ -16,10 +20,99 @@ func LoadConfig(filename string) (pc ProgramConfig, err error) {
 	}
 
 	_, err = toml.Decode(string(data), &pc)
+	if err != nil {
+		return
+	}
+
+	var defined map[string]interface{}
+	_, err = toml.Decode(string(data), &defined)
+	if err != nil {
+		return
+	}
+
+	applyDefaults(reflect.ValueOf(&pc).Elem(), reflect.ValueOf(DefaultConfig), defined)
 
 	return
 }
 
+// elementDefaults returns the default value of the elements of a slice of tables
+func elementDefaults(t reflect.Type) (reflect.Value, bool) {
+	switch t {
+	case reflect.TypeOf(PipelineConfig{}):
+		return reflect.ValueOf(DefaultPipelineConfig()), true
+	case reflect.TypeOf(WebhookConfig{}):
+		return reflect.ValueOf(WebhookConfig{Timeout: DefaultWebhookTimeout, Retries: DefaultWebhookRetries}), true
+	}
+
+	return reflect.Value{}, false
+}
+
+// lookupKey finds a struct field in the decoded table. TOML keys match the field names without case.
+func lookupKey(table map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := table[name]; ok {
+		return v, true
+	}
+
+	for k, v := range table {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+// tablesOf returns the tables of an array of tables
+func tablesOf(value interface{}) []map[string]interface{} {
+	switch v := value.(type) {
+	case []map[string]interface{}:
+		return v
+	case []interface{}:
+		tables := make([]map[string]interface{}, 0, len(v))
+		for _, e := range v {
+			if t, ok := e.(map[string]interface{}); ok {
+				tables = append(tables, t)
+			}
+		}
+		return tables
+	}
+
+	return nil
+}
+
+// applyDefaults copies the fields of def that are not defined in the table to v
+func applyDefaults(v, def reflect.Value, table map[string]interface{}) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		name := v.Type().Field(i).Name
+
+		value, ok := lookupKey(table, name)
+		if !ok {
+			if field.CanSet() {
+				field.Set(def.Field(i))
+			}
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.Struct:
+			if sub, ok := value.(map[string]interface{}); ok {
+				applyDefaults(field, def.Field(i), sub)
+			}
+		case reflect.Slice:
+			elementDef, ok := elementDefaults(field.Type().Elem())
+			if !ok {
+				continue
+			}
+			for j, sub := range tablesOf(value) {
+				if j < field.Len() {
+					applyDefaults(field.Index(j), elementDef, sub)
+				}
+			}
+		}
+	}
+}
+
 func SaveConfig(filename string, pc ProgramConfig) error {
 	b := &bytes.Buffer{}
 	enc := toml.NewEncoder(b)
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadString(t *testing.T, data string) ProgramConfig {
+	dir, err := ioutil.TempDir("", "qo100-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "qo100.toml")
+	err = ioutil.WriteFile(filename, []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pc, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pc
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	pc := loadString(t, `
+[Source]
+  Address = "10.0.0.1:1234"
+[Processing]
+  BeaconOffset = 100000.0
+  [Processing.CostasLoop]
+    Bandwidth = 0.02
+    GearShifting = false
+[Server]
+  [Server.WebSettings.SegFFT]
+    Smoothing = 0.0
+`)
+
+	if pc.Source.Address != "10.0.0.1:1234" {
+		t.Errorf("address not decoded: %q", pc.Source.Address)
+	}
+	if pc.Source.SampleRate != DefaultSampleRate {
+		t.Errorf("expected default sample rate, got %d", pc.Source.SampleRate)
+	}
+
+	loop := pc.Processing.CostasLoop
+	if loop.Bandwidth != 0.02 || loop.GearShifting {
+		t.Errorf("loop settings not decoded: %+v", loop)
+	}
+	if loop.LockThreshold != DefaultCostasLoopLockThreshold || loop.UnlockThreshold != DefaultCostasLoopUnlockThreshold {
+		t.Errorf("expected default lock thresholds, got %+v", loop)
+	}
+	if pc.Processing.Retune.HoldTimeout != DefaultRetuneHoldTimeout {
+		t.Errorf("expected default hold timeout, got %f", pc.Processing.Retune.HoldTimeout)
+	}
+
+	if pc.Server.WebSettings.SegFFT.Smoothing != 0 {
+		t.Errorf("explicit zero overwritten: %f", pc.Server.WebSettings.SegFFT.Smoothing)
+	}
+	if pc.Server.WebSettings.FullFFT.Smoothing != DefaultFFTSmoothing {
+		t.Errorf("expected default smoothing, got %f", pc.Server.WebSettings.FullFFT.Smoothing)
+	}
+	if len(pc.Server.DriftLog.Taus) != len(DefaultDriftLogTaus) {
+		t.Errorf("expected default taus, got %v", pc.Server.DriftLog.Taus)
+	}
+}
+
+func TestLoadConfigPipelineDefaults(t *testing.T) {
+	pc := loadString(t, `
+[[Pipelines]]
+  Name = "dish1"
+  [Pipelines.Source]
+    Address = "10.0.0.1:1234"
+  [Pipelines.Alerts]
+    Enable = true
+    [[Pipelines.Alerts.Webhooks]]
+      URL = "http://localhost/a"
+    [[Pipelines.Alerts.Webhooks]]
+      URL = "http://localhost/b"
+      Retries = 0
+[[Pipelines]]
+  Name = "dish2"
+`)
+
+	if len(pc.Pipelines) != 2 {
+		t.Fatalf("expected 2 pipelines, got %d", len(pc.Pipelines))
+	}
+
+	p := pc.Pipelines[0]
+	if p.Name != "dish1" || p.Source.Address != "10.0.0.1:1234" {
+		t.Errorf("pipeline not decoded: %q %q", p.Name, p.Source.Address)
+	}
+	if p.Source.CenterFrequency != DefaultCenterFrequency {
+		t.Errorf("expected default center frequency, got %d", p.Source.CenterFrequency)
+	}
+	if p.Processing.CostasLoop.LockThreshold != DefaultCostasLoopLockThreshold {
+		t.Errorf("expected default lock threshold, got %f", p.Processing.CostasLoop.LockThreshold)
+	}
+	if !p.Alerts.Enable || p.Alerts.Delay != DefaultAlertsDelay {
+		t.Errorf("expected enabled alerts with default delay, got %+v", p.Alerts)
+	}
+
+	hooks := p.Alerts.Webhooks
+	if len(hooks) != 2 {
+		t.Fatalf("expected 2 webhooks, got %d", len(hooks))
+	}
+	if hooks[0].Retries != DefaultWebhookRetries || hooks[0].Timeout != DefaultWebhookTimeout {
+		t.Errorf("expected default webhook settings, got %+v", hooks[0])
+	}
+	if hooks[1].Retries != 0 {
+		t.Errorf("explicit zero retries overwritten: %d", hooks[1].Retries)
+	}
+
+	if pc.Pipelines[1].Name != "dish2" || pc.Pipelines[1].WebSettings.FullFFT.Size != DefaultFFTSize {
+		t.Errorf("second pipeline without defaults: %+v", pc.Pipelines[1].WebSettings)
+	}
+}
@@ -1,10 +1,140 @@
 package config
 
+type HardwareCorrectionConfig struct {
+	Enable     bool
+	Mode       string
+	MinStep    float64
+	Hysteresis float64
+	Interval   float64
+	Latency    float64
+}
+
+type TemperatureSensorConfig struct {
+	Name   string
+	Type   string
+	Path   string
+	Field  string
+	Scale  float64
+	Offset float64
+}
+
+type TemperatureConfig struct {
+	Enable        bool
+	Interval      float64
+	Sensors       []TemperatureSensorConfig
+	ModelInput    string
+	ModelInterval float64
+	ModelSamples  int
+	MinSamples    int
+	MinSpan       float64
+	ModelFile     string
+	Holdover      bool
+	HoldoverDelay float64
+}
+
+type AutoGainConfig struct {
+	Enable      bool
+	TargetLow   float64 // RMS level of the raw samples in dBFS
+	TargetHigh  float64
+	MaxClipRate float64 // Fraction of clipped samples
+	MinGain     float64
+	MaxGain     float64
+	Step        float64 // Minimum dB between steps
+	Interval    float64 // Seconds of samples measured for each decision
+	HoldTime    float64 // Seconds after a change before the gain is raised again
+	ByIndex     bool    // Select the tuner gains with SetTunerGainByIndex instead of SetGain
+}
+
 type SourceConfig struct {
+	Address            string
+	SampleRate         uint32
+	CenterFrequency    uint32
+	LNBFrequency       float64
+	UpconverterOffset  float64
+	Gain               float32
+	AutoGain           AutoGainConfig
+	HardwareCorrection HardwareCorrectionConfig
+	Temperature        TemperatureConfig
+}
+
+type RigctlConfig struct {
+	Enable          bool
 	Address         string
-	SampleRate      uint32
-	CenterFrequency uint32
-	Gain            float32
+	Target          string
+	FrequencyOffset float64
+	Mode            string
+	Passband        int
+}
+
+type UplinkConfig struct {
+	Enable            bool
+	Address           string
+	Frequency         float64
+	FollowRigctl      bool
+	TransponderOffset float64
+	DriftRatio        float64
+	MinStep           float64
+	MinInterval       float64
+	MaxCorrection     float64
+}
+
+type DriftLogConfig struct {
+	Enable    bool
+	Directory string
+	Format    string
+	Interval  float64
+	MaxDays   int
+	MaxHours  float64
+	Taus      []float64
+}
+
+type SignalsConfig struct {
+	Enable          bool
+	FFTSize         int
+	Interval        float64
+	Averaging       int
+	Threshold       float64 // dB above the noise floor
+	MergeGap        float64 // Hertz below the threshold that still belong to the same signal
+	MinBandwidth    float64
+	EdgeExclusion   float64 // Fraction of the band ignored on each edge
+	MinDetections   int
+	HoldTime        float64
+	CWMaxBandwidth  float64
+	SSBMaxBandwidth float64
+	DigitalFlatness float64
+	EventLog        string
+}
+
+type OccupancyConfig struct {
+	Enable       bool
+	Directory    string
+	BucketSize   float64 // Seconds
+	Bins         int
+	SaveInterval float64
+	MaxDays      int
+	MaxHours     float64
+	RowHeight    int // Pixels of each row of the PNG render
+}
+
+type WebhookConfig struct {
+	URL     string
+	Rules   []string // Rules sent to this webhook. Empty sends all of them.
+	Timeout float64
+	Retries int
+}
+
+type AlertsConfig struct {
+	Enable           bool
+	Delay            float64 // Seconds a condition must hold before its alert fires
+	ReferenceTime    float64 // Time constant of the beacon power and noise floor references in seconds
+	History          int
+	BeaconPowerDrop  float64 // dB
+	NoiseFloorRise   float64 // dB
+	CarrierRange     float64 // Hertz around the beacon watched for unknown carriers
+	CarrierTolerance float64
+	KnownCarriers    []float64 // Absolute frequencies of the expected carriers near the beacon
+	LockLostTime     float64
+	Webhooks         []WebhookConfig
 }
 
 type ServerConfig struct {
@@ -14,6 +144,12 @@ type ServerConfig struct {
 	MaxRTLConnections int
 	AllowControl      bool
 	WebSettings       WebSettings
+	Rigctl            RigctlConfig
+	Uplink            UplinkConfig
+	DriftLog          DriftLogConfig
+	Signals           SignalsConfig
+	Occupancy         OccupancyConfig
+	Alerts            AlertsConfig
 }
 
 type AGCConfig struct {
@@ -25,7 +161,15 @@ type AGCConfig struct {
 }
 
 type LoopConfig struct {
-	Bandwidth float32
+	Bandwidth             float32
+	GearShifting          bool
+	AcquisitionBandwidth  float32
+	IntermediateBandwidth float32
+	TrackingBandwidth     float32
+	LockThreshold         float32
+	UnlockThreshold       float32
+	LockHoldTime          float64
+	UnlockHoldTime        float64
 }
 
 type TranslationConfig struct {
@@ -33,18 +177,107 @@ type TranslationConfig struct {
 	Gain            float64
 }
 
+type ResamplerConfig struct {
+	Enable        bool
+	PPM           float64
+	MeasurePPM    bool
+	MeasureWindow float64
+}
+
+type RetuneConfig struct {
+	FlushTime   float64
+	HoldTimeout float64
+}
+
+type StateConfig struct {
+	Enable   bool
+	File     string
+	Interval float64
+	MaxAge   float64
+}
+
+type BeaconConfig struct {
+	Bandwidth float64
+}
+
+type PSKDecoderConfig struct {
+	Enable        bool
+	SymbolRate    float64
+	SyncWord      string
+	MaxSyncErrors int
+	FrameLength   int
+	Differential  bool
+	FrameHistory  int
+	ValidFor      float64
+	RequireDecode bool
+}
+
+type CWDecoderConfig struct {
+	Enable          bool
+	Frequency       float64 // Absolute frequency of the CW beacon in Hertz
+	Bandwidth       float64
+	WPM             float64 // Initial speed, the decoder adapts to the received speed
+	ExpectedText    string
+	MinConfidence   float64
+	ValidFor        float64
+	TextLength      int
+	RequireVerified bool
+}
+
+type ResidualConfig struct {
+	Enable       bool
+	Bandwidth    float64
+	FFTSize      int
+	Interval     float64
+	Averaging    float64
+	MaxError     float64
+	JitterWindow int
+}
+
 type ProcessingConfig struct {
-	BeaconOffset   float32
-	WorkDecimation uint32
-	AGC            AGCConfig
-	CostasLoop     LoopConfig
-	Translation    TranslationConfig
+	BeaconFrequency float64
+	BeaconOffset    float32
+	WorkDecimation  uint32
+	AGC             AGCConfig
+	CostasLoop      LoopConfig
+	Translation     TranslationConfig
+	Resampler       ResamplerConfig
+	Retune          RetuneConfig
+	State           StateConfig
+	Beacon          BeaconConfig
+	PSKDecoder      PSKDecoderConfig
+	CWDecoder       CWDecoderConfig
+	Residual        ResidualConfig
+}
+
+type CorrectionConfig struct {
+	Publish        bool
+	Subscribe      string
+	FrequencyRatio float64
+}
+
+type PipelineConfig struct {
+	Name              string
+	Source            SourceConfig
+	Processing        ProcessingConfig
+	RTLTCPAddress     string
+	MaxRTLConnections int
+	AllowControl      bool
+	WebSettings       WebSettings
+	Correction        CorrectionConfig
+	Rigctl            RigctlConfig
+	Uplink            UplinkConfig
+	DriftLog          DriftLogConfig
+	Signals           SignalsConfig
+	Occupancy         OccupancyConfig
+	Alerts            AlertsConfig
 }
 
 type ProgramConfig struct {
 	Source     SourceConfig
 	Server     ServerConfig
 	Processing ProcessingConfig
+	Pipelines  []PipelineConfig
 }
 
 type FFTWindowSetting struct {
@@ -52,6 +285,13 @@ type FFTWindowSetting struct {
 	Range  int `json:"range"`
 	Width  int `json:"width"`
 	Height int `json:"height"`
+
+	Size            int     `json:"-"` // Output bins, power of two
+	FPS             float64 `json:"-"`
+	Window          string  `json:"-"` // hann, hamming, blackman-harris or flat-top
+	Averaging       string  `json:"-"` // exponential or linear
+	AveragingFrames int     `json:"-"`
+	Smoothing       float64 `json:"-"` // Weight of the previous bin, 0 disables it
 }
 
 type WebSettings struct {
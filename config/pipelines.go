@@ -0,0 +1,42 @@
+package config
+
+// GetPipelines returns the configured pipelines.
+// If no pipeline is configured, a single pipeline is built from the Source, Processing and Server sections.
+// The beacon offset of each pipeline is derived from its absolute beacon frequency.
+func (pc ProgramConfig) GetPipelines() []PipelineConfig {
+	if len(pc.Pipelines) > 0 {
+		pipelines := make([]PipelineConfig, len(pc.Pipelines))
+		for i, p := range pc.Pipelines {
+			pipelines[i] = p.resolveBeacon()
+		}
+		return pipelines
+	}
+
+	return []PipelineConfig{pc.mainPipeline().resolveBeacon()}
+}
+
+// mainPipeline builds the pipeline described by the Source, Processing and Server sections
+func (pc ProgramConfig) mainPipeline() PipelineConfig {
+	return PipelineConfig{
+		Name:              DefaultPipelineName,
+		Source:            pc.Source,
+		Processing:        pc.Processing,
+		RTLTCPAddress:     pc.Server.RTLTCPAddress,
+		MaxRTLConnections: pc.Server.MaxRTLConnections,
+		AllowControl:      pc.Server.AllowControl,
+		WebSettings:       pc.Server.WebSettings,
+		Rigctl:            pc.Server.Rigctl,
+		Uplink:            pc.Server.Uplink,
+		DriftLog:          pc.Server.DriftLog,
+		Signals:           pc.Server.Signals,
+		Occupancy:         pc.Server.Occupancy,
+		Alerts:            pc.Server.Alerts,
+	}
+}
+
+// DefaultPipelineConfig returns the defaults of a pipeline in the Pipelines list. It has no name.
+func DefaultPipelineConfig() PipelineConfig {
+	p := DefaultConfig.mainPipeline()
+	p.Name = ""
+	return p
+}
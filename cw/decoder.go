@@ -0,0 +1,304 @@
+package cw
+
+import (
+	"encoding/json"
+	"github.com/racerxdl/qo100-dedrift/channel"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	envelopeTime      = 0.005 // Envelope smoothing time constant in seconds
+	levelDecayTime    = 2.0   // Time constant of the signal and noise level trackers in seconds
+	keyHysteresis     = 1.5   // dB
+	halfAmplitude     = 6.0   // dB
+	minKeySNR         = 6.0   // dB between the signal and noise levels to consider the channel keyed
+	minWPM            = 5
+	maxWPM            = 60
+	ditAlpha          = 0.2
+	confidenceAlpha   = 0.1
+	glitchRatio       = 0.3 // Marks shorter than this fraction of a dot are ignored
+	maxCharacterCodes = 8
+	defaultTextLength = 128
+)
+
+// Status is the decoder state
+type Status struct {
+	Text         string    `json:"text"`
+	Confidence   float32   `json:"confidence"` // Average timing quality of the last characters, from 0 to 1
+	WPM          float32   `json:"wpm"`
+	SNR          float32   `json:"snr"` // dB between the key down and key up levels
+	Characters   int       `json:"characters"`
+	Verified     bool      `json:"verified"`
+	LastVerified time.Time `json:"lastVerified"`
+}
+
+type OnCharacter func(status Status)
+
+// Decoder decodes the Morse code of a CW beacon and checks it against the expected beacon text.
+// It takes the full band stream after the drift correction and extracts the beacon channel from it.
+type Decoder struct {
+	cfg          config.CWDecoderConfig
+	channelizer  *channel.Channelizer
+	sampleTime   float64
+	envelopeGain float64
+	levelGain    float64
+	onCharacter  OnCharacter
+	expected     string
+
+	envelope   float64
+	high       float64 // Key down level in dB
+	low        float64 // Key up level in dB
+	keyDown    bool
+	duration   float64 // Seconds in the current key state
+	dit        float64 // Dot length in seconds
+	code       strings.Builder
+	quality    float64
+	elements   int
+	wordSpaced bool
+
+	lock      sync.Mutex
+	text      []rune
+	unmatched []rune // Text since the last match of the expected text
+	status    Status
+}
+
+func MakeDecoder(cfg config.CWDecoderConfig, sampleRate, offset float64) *Decoder {
+	c := channel.MakeChannelizer(sampleRate, offset, cfg.Bandwidth)
+	sampleTime := 1 / c.GetSampleRate()
+
+	wpm := cfg.WPM
+	if wpm <= 0 {
+		wpm = 20
+	}
+
+	return &Decoder{
+		cfg:          cfg,
+		channelizer:  c,
+		sampleTime:   sampleTime,
+		envelopeGain: 1 - math.Exp(-sampleTime/envelopeTime),
+		levelGain:    1 - math.Exp(-sampleTime/levelDecayTime),
+		expected:     normalize(cfg.ExpectedText),
+		dit:          wpmToDit(wpm),
+		text:         make([]rune, 0),
+	}
+}
+
+func wpmToDit(wpm float64) float64 {
+	return 1.2 / wpm // PARIS timing
+}
+
+// normalize returns the upper case text without spaces, so it can be matched against the decoded text
+func normalize(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, text)
+}
+
+// SetOnCharacter sets the callback called for each decoded character. It runs in the goroutine that calls Work.
+func (d *Decoder) SetOnCharacter(cb OnCharacter) {
+	d.onCharacter = cb
+}
+
+// SetOffset changes the beacon frequency relative to the center of the input, like after a retune
+func (d *Decoder) SetOffset(offset float64) {
+	d.channelizer.SetOffset(offset)
+	d.channelizer.Reset()
+	d.code.Reset()
+	d.keyDown = false
+	d.duration = 0
+}
+
+// Work processes the corrected full band samples
+func (d *Decoder) Work(samples []complex64) {
+	for _, v := range d.channelizer.Work(samples) {
+		p := float64(real(v)*real(v) + imag(v)*imag(v))
+		d.envelope += d.envelopeGain * (p - d.envelope)
+		d.pushLevel(10 * math.Log10(math.Max(d.envelope, 1e-30)))
+	}
+}
+
+func (d *Decoder) pushLevel(level float64) {
+	// Fast attack, slow decay trackers of the key down and key up levels
+	if level > d.high || d.high == 0 {
+		d.high = level
+	} else {
+		d.high += d.levelGain * (level - d.high)
+	}
+
+	if level < d.low || d.low == 0 {
+		d.low = level
+	} else {
+		d.low += d.levelGain * (level - d.low)
+	}
+
+	d.duration += d.sampleTime
+
+	if d.high-d.low < minKeySNR {
+		if d.keyDown {
+			d.keyUp()
+		}
+		d.checkGap()
+		return
+	}
+
+	// Half the key down amplitude, so the filter rise and fall times do not bias the mark lengths
+	threshold := d.high - math.Min(halfAmplitude, (d.high-d.low)/2)
+
+	switch {
+	case !d.keyDown && level > threshold+keyHysteresis:
+		d.checkGap()
+		d.keyDown = true
+		d.duration = 0
+	case d.keyDown && level < threshold-keyHysteresis:
+		d.keyUp()
+	case !d.keyDown:
+		d.checkGap()
+	}
+}
+
+func (d *Decoder) keyUp() {
+	mark := d.duration
+	d.keyDown = false
+	d.duration = 0
+
+	if mark < d.dit*glitchRatio {
+		return
+	}
+
+	var quality, unit float64
+	if mark < 2*d.dit {
+		d.code.WriteByte('.')
+		unit = mark
+		quality = 1 - math.Abs(mark-d.dit)/d.dit
+	} else {
+		d.code.WriteByte('-')
+		unit = mark / 3
+		quality = 1 - math.Abs(mark-3*d.dit)/(3*d.dit)
+	}
+
+	d.dit += ditAlpha * (unit - d.dit)
+	d.dit = math.Max(wpmToDit(maxWPM), math.Min(wpmToDit(minWPM), d.dit))
+
+	d.quality += math.Max(0, quality)
+	d.elements++
+	d.wordSpaced = false
+
+	if d.code.Len() > maxCharacterCodes {
+		d.flushCharacter()
+	}
+}
+
+// checkGap ends the character or the word when the key stays up long enough
+func (d *Decoder) checkGap() {
+	if d.code.Len() > 0 && d.duration >= 2*d.dit {
+		d.flushCharacter()
+	}
+
+	if !d.wordSpaced && d.duration >= 5*d.dit {
+		d.wordSpaced = true
+		d.addCharacter(' ', -1)
+	}
+}
+
+func (d *Decoder) flushCharacter() {
+	c, ok := lookup(d.code.String())
+
+	quality := 0.0
+	if ok && d.elements > 0 {
+		quality = d.quality / float64(d.elements)
+	}
+
+	d.code.Reset()
+	d.quality = 0
+	d.elements = 0
+
+	d.addCharacter(c, quality)
+}
+
+// addCharacter appends c to the text. Spaces do not change the confidence, so quality is negative for them.
+func (d *Decoder) addCharacter(c rune, quality float64) {
+	d.lock.Lock()
+
+	if c == ' ' && (len(d.text) == 0 || d.text[len(d.text)-1] == ' ') {
+		d.lock.Unlock()
+		return
+	}
+
+	maxLength := d.cfg.TextLength
+	if maxLength <= 0 {
+		maxLength = defaultTextLength
+	}
+
+	d.text = append(d.text, c)
+	if len(d.text) > maxLength {
+		d.text = d.text[len(d.text)-maxLength:]
+	}
+
+	d.unmatched = append(d.unmatched, c)
+	if len(d.unmatched) > maxLength {
+		d.unmatched = d.unmatched[len(d.unmatched)-maxLength:]
+	}
+
+	if quality >= 0 {
+		d.status.Confidence += float32(confidenceAlpha * (quality - float64(d.status.Confidence)))
+		d.status.Characters++
+	}
+
+	d.status.Text = string(d.text)
+	d.status.WPM = float32(1.2 / d.dit)
+	d.status.SNR = float32(d.high - d.low)
+
+	if d.expected != "" && float64(d.status.Confidence) >= d.cfg.MinConfidence && strings.Contains(normalize(string(d.unmatched)), d.expected) {
+		d.status.LastVerified = time.Now()
+		d.unmatched = d.unmatched[:0]
+	}
+
+	status := d.status
+	status.Verified = d.verified()
+	d.lock.Unlock()
+
+	if d.onCharacter != nil && c != ' ' {
+		d.onCharacter(status)
+	}
+}
+
+// GetStatus returns the decoder state. It is safe to be called from any goroutine.
+func (d *Decoder) GetStatus() Status {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	status := d.status
+	status.Verified = d.verified()
+	return status
+}
+
+// Verified returns true if the beacon text was decoded in the last ValidFor seconds
+func (d *Decoder) Verified() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.verified()
+}
+
+func (d *Decoder) verified() bool {
+	validFor := time.Duration(d.cfg.ValidFor * float64(time.Second))
+	return !d.status.LastVerified.IsZero() && time.Since(d.status.LastVerified) < validFor
+}
+
+// Handler serves the decoder state
+func (d *Decoder) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, _ := json.Marshal(d.GetStatus())
+
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(200)
+		_, _ = w.Write(data)
+	}
+}
@@ -0,0 +1,151 @@
+package cw
+
+import (
+	"github.com/racerxdl/qo100-dedrift/config"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+const (
+	testSampleRate = 48000
+	testOffset     = 5000
+)
+
+// encode returns the Morse code of text, with the characters separated by spaces and the words by slashes
+func encode(text string) []string {
+	codes := map[rune]string{}
+	for code, c := range morseTable {
+		codes[c] = code
+	}
+
+	var result []string
+	for _, c := range strings.ToUpper(text) {
+		if c == ' ' {
+			result = append(result, "/")
+			continue
+		}
+		result = append(result, codes[c])
+	}
+	return result
+}
+
+// keyedTone returns the samples of text keyed at wpm on a tone at offset Hertz, with some noise
+func keyedTone(text string, wpm float64, r *rand.Rand) []complex64 {
+	dit := int(wpmToDit(wpm) * testSampleRate)
+
+	var keying []bool
+	key := func(down bool, units int) {
+		for i := 0; i < units*dit; i++ {
+			keying = append(keying, down)
+		}
+	}
+
+	key(false, 10)
+	for _, code := range encode(text) {
+		if code == "/" {
+			key(false, 4) // 7 units with the gap after the last character
+			continue
+		}
+		for _, e := range code {
+			if e == '.' {
+				key(true, 1)
+			} else {
+				key(true, 3)
+			}
+			key(false, 1)
+		}
+		key(false, 2)
+	}
+	key(false, 10)
+
+	samples := make([]complex64, len(keying))
+	for i, down := range keying {
+		v := complex(float32(r.NormFloat64()*0.01), float32(r.NormFloat64()*0.01))
+		if down {
+			s, c := math.Sincos(2 * math.Pi * testOffset * float64(i) / testSampleRate)
+			v += complex(float32(c)*0.5, float32(s)*0.5)
+		}
+		samples[i] = v
+	}
+
+	return samples
+}
+
+func runDecoder(d *Decoder, samples []complex64) {
+	for i := 0; i < len(samples); i += 4096 {
+		end := i + 4096
+		if end > len(samples) {
+			end = len(samples)
+		}
+		d.Work(samples[i:end])
+	}
+}
+
+func TestDecoderText(t *testing.T) {
+	cfg := config.DefaultConfig.Processing.CWDecoder
+	r := rand.New(rand.NewSource(1))
+
+	for _, wpm := range []float64{12, 20, 28} {
+		cfg.WPM = 20 // The speed is estimated from the marks
+		d := MakeDecoder(cfg, testSampleRate, testOffset)
+
+		characters := 0
+		d.SetOnCharacter(func(status Status) {
+			characters++
+		})
+
+		runDecoder(d, keyedTone("QO-100 QO-100 QO-100 BEACON", wpm, r))
+
+		// The first character is lost while the key levels are learned
+		status := d.GetStatus()
+		if !strings.Contains(status.Text, "QO-100 QO-100 BEACON") {
+			t.Errorf("%.0f WPM: expected the beacon text, got %q", wpm, status.Text)
+		}
+		if math.Abs(float64(status.WPM)-wpm) > wpm*0.1 {
+			t.Errorf("%.0f WPM: expected the speed to be estimated, got %f WPM", wpm, status.WPM)
+		}
+		if status.Confidence < float32(cfg.MinConfidence) {
+			t.Errorf("%.0f WPM: expected a confidence over %f, got %f", wpm, cfg.MinConfidence, status.Confidence)
+		}
+		if !status.Verified || !d.Verified() {
+			t.Errorf("%.0f WPM: expected the beacon to be verified", wpm)
+		}
+		if characters != status.Characters {
+			t.Errorf("%.0f WPM: expected %d character callbacks, got %d", wpm, status.Characters, characters)
+		}
+	}
+}
+
+func TestDecoderUnverified(t *testing.T) {
+	cfg := config.DefaultConfig.Processing.CWDecoder
+	r := rand.New(rand.NewSource(2))
+
+	d := MakeDecoder(cfg, testSampleRate, testOffset)
+	runDecoder(d, keyedTone("CQ CQ DE TEST", 20, r))
+
+	status := d.GetStatus()
+	if !strings.Contains(status.Text, "CQ DE TEST") {
+		t.Errorf("expected the keyed text, got %q", status.Text)
+	}
+	if status.Verified {
+		t.Errorf("expected text without the beacon text not to be verified")
+	}
+
+	// A keyed tone outside of the channel is not decoded, only the noise in the channel is
+	d = MakeDecoder(cfg, testSampleRate, -testOffset)
+	runDecoder(d, keyedTone("QO-100 QO-100 QO-100", 20, r))
+	if status := d.GetStatus(); status.Verified || strings.Contains(status.Text, "QO") {
+		t.Errorf("expected the beacon out of the channel not to be decoded, got %q", status.Text)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if c, ok := lookup("--.-"); !ok || c != 'Q' {
+		t.Errorf("expected Q, got %c", c)
+	}
+	if c, ok := lookup("........"); ok || c != unknownCharacter {
+		t.Errorf("expected an unknown character, got %c", c)
+	}
+}
@@ -0,0 +1,61 @@
+package cw
+
+const unknownCharacter = '*'
+
+var morseTable = map[string]rune{
+	".-":     'A',
+	"-...":   'B',
+	"-.-.":   'C',
+	"-..":    'D',
+	".":      'E',
+	"..-.":   'F',
+	"--.":    'G',
+	"....":   'H',
+	"..":     'I',
+	".---":   'J',
+	"-.-":    'K',
+	".-..":   'L',
+	"--":     'M',
+	"-.":     'N',
+	"---":    'O',
+	".--.":   'P',
+	"--.-":   'Q',
+	".-.":    'R',
+	"...":    'S',
+	"-":      'T',
+	"..-":    'U',
+	"...-":   'V',
+	".--":    'W',
+	"-..-":   'X',
+	"-.--":   'Y',
+	"--..":   'Z',
+	"-----":  '0',
+	".----":  '1',
+	"..---":  '2',
+	"...--":  '3',
+	"....-":  '4',
+	".....":  '5',
+	"-....":  '6',
+	"--...":  '7',
+	"---..":  '8',
+	"----.":  '9',
+	".-.-.-": '.',
+	"--..--": ',',
+	"..--..": '?',
+	"-..-.":  '/',
+	"-....-": '-',
+	"-...-":  '=',
+	".-.-.":  '+',
+	"---...": ':',
+	".----.": '\'',
+	".--.-.": '@',
+}
+
+// lookup returns the character for a Morse code made of dots and dashes
+func lookup(code string) (rune, bool) {
+	c, ok := morseTable[code]
+	if !ok {
+		return unknownCharacter, false
+	}
+	return c, true
+}
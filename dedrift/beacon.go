@@ -0,0 +1,159 @@
+package dedrift
+
+import (
+	"github.com/racerxdl/segdsp/dsp"
+	"github.com/racerxdl/segdsp/dsp/fft"
+	"math"
+	"sort"
+)
+
+const beaconMeterFFTSize = 1024
+
+// BeaconMeasurement is the beacon signal quality measured over the segment stream
+type BeaconMeasurement struct {
+	Power      float32 // Beacon power in dBFS over the beacon bandwidth
+	NoiseFloor float32 // Noise density in dBFS/Hz
+	SNR        float32 // SNR in dB over the beacon bandwidth
+	CN0        float32 // Carrier to noise density ratio in dB-Hz
+	Offset     float32 // Beacon position from the segment center in Hertz
+}
+
+// BeaconMeter estimates the beacon power and the noise floor from the averaged spectrum of the segment stream.
+// The beacon is the strongest bandwidth wide signal inside the translator passband and the noise floor is the median
+// of the remaining passband bins.
+type BeaconMeter struct {
+	sampleRate float32
+	bandwidth  float64
+	passband   float64
+	scale      float64 // Converts the segment power back to the input full scale
+
+	window    []float64
+	windowSum float64
+	pending   []complex64
+	frame     []complex64
+	psd       []float64
+	frames    int
+}
+
+// MakeBeaconMeter creates a meter for a segment stream at sampleRate with a beacon of the specified bandwidth.
+// Only the bins inside ±passband / 2 are used. The gain is the translator gain, removed from the reported power.
+func MakeBeaconMeter(sampleRate float32, bandwidth, passband, gain float64) *BeaconMeter {
+	m := &BeaconMeter{
+		sampleRate: sampleRate,
+		bandwidth:  bandwidth,
+		passband:   passband,
+		scale:      1 / (gain * gain),
+		window:     dsp.HammingWindow(beaconMeterFFTSize),
+		frame:      make([]complex64, beaconMeterFFTSize),
+		psd:        make([]float64, beaconMeterFFTSize),
+	}
+
+	for _, v := range m.window {
+		m.windowSum += v * v
+	}
+
+	return m
+}
+
+// Work accumulates the spectrum of the samples
+func (m *BeaconMeter) Work(samples []complex64) {
+	m.pending = append(m.pending, samples...)
+
+	consumed := 0
+	for len(m.pending)-consumed >= beaconMeterFFTSize {
+		for i, v := range m.pending[consumed : consumed+beaconMeterFFTSize] {
+			w := float32(m.window[i])
+			m.frame[i] = complex(real(v)*w, imag(v)*w)
+		}
+
+		for i, v := range fft.FFT(m.frame) {
+			m.psd[i] += float64(real(v)*real(v) + imag(v)*imag(v))
+		}
+
+		m.frames++
+		consumed += beaconMeterFFTSize
+	}
+
+	// Keep the remaining samples at the start of the buffer
+	m.pending = m.pending[:copy(m.pending, m.pending[consumed:])]
+}
+
+func toDB(v float64) float32 {
+	return float32(10 * math.Log10(math.Max(v, 1e-30)))
+}
+
+// Measure returns the measurement over the spectrum accumulated since the last call and resets it.
+// It returns false if no spectrum was accumulated.
+func (m *BeaconMeter) Measure() (BeaconMeasurement, bool) {
+	if m.frames == 0 {
+		return BeaconMeasurement{}, false
+	}
+
+	n := beaconMeterFFTSize
+	binWidth := float64(m.sampleRate) / float64(n)
+	norm := m.scale / (float64(m.frames) * float64(n) * m.windowSum)
+
+	// Power per bin, ordered from the lowest to the highest frequency
+	bins := make([]float64, n)
+	for i, v := range m.psd {
+		bins[(i+n/2)%n] = v * norm
+		m.psd[i] = 0
+	}
+	m.frames = 0
+
+	first := n/2 - int(m.passband/2/binWidth)
+	last := n/2 + int(m.passband/2/binWidth)
+	if first < 0 {
+		first = 0
+	}
+	if last > n-1 {
+		last = n - 1
+	}
+
+	width := int(math.Ceil(m.bandwidth / binWidth))
+	if width < 1 {
+		width = 1
+	}
+	if width > last-first {
+		width = last - first
+	}
+
+	// Strongest window inside the passband
+	best, bestStart := -1.0, first
+	sum := 0.0
+	for i := first; i <= last; i++ {
+		sum += bins[i]
+		if i-first >= width {
+			sum -= bins[i-width]
+		}
+		if i-first >= width-1 && sum > best {
+			best = sum
+			bestStart = i - width + 1
+		}
+	}
+
+	noiseBins := make([]float64, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		if i < bestStart || i >= bestStart+width {
+			noiseBins = append(noiseBins, bins[i])
+		}
+	}
+
+	if len(noiseBins) == 0 {
+		return BeaconMeasurement{}, false
+	}
+
+	sort.Float64s(noiseBins)
+	noise := noiseBins[len(noiseBins)/2]
+	noiseInBand := noise * float64(width)
+	signal := math.Max(best-noiseInBand, 0)
+	n0 := noise / binWidth
+
+	return BeaconMeasurement{
+		Power:      toDB(best),
+		NoiseFloor: toDB(n0),
+		SNR:        toDB(signal / noiseInBand),
+		CN0:        toDB(signal / n0),
+		Offset:     float32((float64(bestStart-n/2) + float64(width)/2) * binWidth),
+	}, true
+}
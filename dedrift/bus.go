@@ -0,0 +1,70 @@
+package dedrift
+
+import (
+	"sync"
+	"time"
+)
+
+// Correction is a drift measurement published on the CorrectionBus
+type Correction struct {
+	Source    string
+	Drift     float32 // Drift in Hertz
+	Frequency float64 // Absolute frequency in Hertz where the drift was measured
+	Locked    bool
+	Time      time.Time
+}
+
+// CorrectionBus distributes the drift measured by a pipeline to the pipelines that cannot see the beacon
+type CorrectionBus struct {
+	lock        sync.Mutex
+	subscribers map[string][]chan Correction
+	last        map[string]Correction
+}
+
+func MakeCorrectionBus() *CorrectionBus {
+	return &CorrectionBus{
+		subscribers: map[string][]chan Correction{},
+		last:        map[string]Correction{},
+	}
+}
+
+// Publish sends the correction to all subscribers of its source. Slow subscribers only receive the latest value.
+func (b *CorrectionBus) Publish(c Correction) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.last[c.Source] = c
+
+	for _, ch := range b.subscribers[c.Source] {
+		select {
+		case <-ch: // Drop the stale value
+		default:
+		}
+		ch <- c
+	}
+}
+
+// Subscribe returns a channel that receives the corrections published by source.
+// If source already published a correction, it is delivered immediately.
+func (b *CorrectionBus) Subscribe(source string) <-chan Correction {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	ch := make(chan Correction, 1)
+	b.subscribers[source] = append(b.subscribers[source], ch)
+
+	if c, ok := b.last[source]; ok {
+		ch <- c
+	}
+
+	return ch
+}
+
+// Last returns the last correction published by source
+func (b *CorrectionBus) Last(source string) (Correction, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	c, ok := b.last[source]
+	return c, ok
+}
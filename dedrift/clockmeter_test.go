@@ -0,0 +1,47 @@
+package dedrift
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSampleClockMeterWindow(t *testing.T) {
+	sampleRate := 1e6
+	m := MakeSampleClockMeter(sampleRate, 10*time.Second)
+	start := m.created.Add(clockMeterWarmup)
+
+	// 100 ppm fast for 20 seconds, then 50 ppm slow
+	now := start
+	m.add(now, 0)
+	for i := 1; i <= 200; i++ {
+		now = now.Add(100 * time.Millisecond)
+		ppm := 100.0
+		if i > 100 {
+			ppm = -50
+		}
+		m.add(now, int(math.Round(sampleRate*(1+ppm*1e-6)/10)))
+
+		if i == 50 {
+			if _, ok := m.PPM(); ok {
+				t.Errorf("expected no measurement before a full window")
+			}
+		}
+		if i == 100 {
+			if ppm, ok := m.PPM(); !ok || math.Abs(ppm-100) > 1 {
+				t.Errorf("expected 100 ppm, got %f (%v)", ppm, ok)
+			}
+		}
+	}
+
+	// Only the last window is measured
+	ppm, ok := m.PPM()
+	if !ok || math.Abs(ppm+50) > 1 {
+		t.Errorf("expected -50 ppm over the last window, got %f (%v)", ppm, ok)
+	}
+
+	m.Reset()
+	if _, ok := m.PPM(); ok {
+		t.Errorf("expected no measurement after a reset")
+	}
+}
@@ -0,0 +1,682 @@
+package dedrift
+
+import (
+	"fmt"
+	"github.com/quan-to/slog"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"github.com/racerxdl/qo100-dedrift/cw"
+	"github.com/racerxdl/qo100-dedrift/psk"
+	"github.com/racerxdl/segdsp/dsp"
+	"github.com/racerxdl/segdsp/tools"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	TwoPi        = float32(math.Pi * 2)
+	MinusTwoPi   = -TwoPi
+	OneOverTwoPi = float32(1 / (2 * math.Pi))
+)
+
+const (
+	retuneQueueLength     = 16
+	beaconMeasureInterval = time.Second
+)
+
+type hardwareStep struct {
+	step    float32
+	latency time.Duration
+}
+
+type pendingHardwareStep struct {
+	step    float32 // radians per segment sample
+	applyAt uint64  // processed sample count
+}
+
+type OnFFT func(segFFT, fullFFT []float32)
+
+// Status is a snapshot of the Dedrifter lock state
+type Status struct {
+	BeaconOffset        float32
+	Drift               float32
+	PendingHardware     float32 // Hertz stepped upstream that are not applied to the correction yet
+	LockDetector        float32
+	BeaconPower         float32 // dBFS over the beacon bandwidth
+	NoiseFloor          float32 // dBFS/Hz
+	BeaconSNR           float32 // dB over the beacon bandwidth
+	BeaconCN0           float32 // dB-Hz
+	AGCGain             float32 // dB
+	Locked              bool
+	HoldingCorrection   bool
+	External            bool
+	Holdover            bool
+	Gear                int
+	LoopBandwidth       float32
+	SampleClockPPM      float64
+	SampleClockMeasured bool
+	ResamplerPPM        float64
+	Retunes             int
+	BeaconDecoded       bool // A PSK beacon frame was decoded recently
+	BeaconVerified      bool // The CW beacon text was decoded recently
+	ResidualError       float32
+	ResidualWidth       float32
+	ResidualJitter      float32
+	ResidualMeasured    bool
+}
+
+// Dedrifter locks into the QO-100 beacon and removes its drift from the full band
+type Dedrifter struct {
+	cfg           config.ProcessingConfig
+	sampleRate    float32
+	segSampleRate float32
+	log           *slog.Instance
+
+	buffer0 []complex64
+	buffer1 []complex64
+
+	translator   *dsp.FrequencyTranslator
+	agc          *dsp.AttackDecayAGC
+	costas       costasLoop
+	lockDetector *LockDetector
+	gearShifter  *GearShifter
+	interp       *dsp.FloatInterpolator
+	dcblock      *dsp.DCFilter
+	resampler    *FractionalResampler
+	clockMeter   *SampleClockMeter
+	phase        float32
+
+	retuneChan     chan float32
+	flushUntil     time.Time
+	holdCorrection bool
+	heldFrequency  float32
+	holdStarted    time.Time
+	heldShift      []float32
+	retunes        int
+
+	hardwareStepChan chan hardwareStep
+	pendingSteps     []pendingHardwareStep
+	processedSamples uint64
+
+	external          bool
+	externalLock      sync.Mutex
+	externalFrequency float32
+	externalLocked    bool
+
+	holdoverLock      sync.Mutex
+	holdover          bool
+	holdoverFrequency float32
+
+	beaconMeter       *BeaconMeter
+	beacon            BeaconMeasurement
+	agcInputPower     float64
+	agcOutputPower    float64
+	agcGain           float32
+	lastBeaconMeasure time.Time
+
+	pskDecoder *psk.Decoder
+	cwDecoder  *cw.Decoder
+
+	residual *ResidualWorker
+
+	spectrum *SpectrumWorker
+
+	statusLock sync.Mutex
+	status     Status
+}
+
+func MakeDedrifter(cfg config.ProcessingConfig, sampleRate uint32) (*Dedrifter, error) {
+	d := &Dedrifter{
+		cfg:               cfg,
+		sampleRate:        float32(sampleRate),
+		segSampleRate:     float32(sampleRate) / float32(cfg.WorkDecimation),
+		log:               slog.Scope("Dedrifter"),
+		retuneChan:        make(chan float32, retuneQueueLength),
+		hardwareStepChan:  make(chan hardwareStep, retuneQueueLength),
+		lastBeaconMeasure: time.Now(),
+	}
+
+	outSampleRate := float64(sampleRate) / float64(cfg.WorkDecimation)
+	translatorTaps := dsp.MakeLowPass(cfg.Translation.Gain, float64(sampleRate), (outSampleRate/2)-cfg.Translation.TransitionWidth, cfg.Translation.TransitionWidth)
+	d.log.Info("Translator Taps Length: %d", len(translatorTaps))
+	d.translator = dsp.MakeFrequencyTranslator(int(cfg.WorkDecimation), -cfg.BeaconOffset, float32(sampleRate), translatorTaps)
+	d.agc = d.makeAGC()
+	d.beaconMeter = MakeBeaconMeter(d.segSampleRate, cfg.Beacon.Bandwidth, outSampleRate-2*cfg.Translation.TransitionWidth, cfg.Translation.Gain)
+	d.costas = makeCostasLoop(cfg.CostasLoop.Bandwidth)
+	d.lockDetector = MakeLockDetector()
+
+	if cfg.CostasLoop.GearShifting {
+		lc := cfg.CostasLoop
+		d.gearShifter = MakeGearShifter(d.costas,
+			[]float32{lc.AcquisitionBandwidth, lc.IntermediateBandwidth, lc.TrackingBandwidth},
+			lc.LockThreshold, lc.UnlockThreshold,
+			time.Duration(lc.LockHoldTime*float64(time.Second)), time.Duration(lc.UnlockHoldTime*float64(time.Second)))
+		d.log.Info("Costas Loop gear shifting enabled")
+	}
+
+	if cfg.PSKDecoder.Enable {
+		var err error
+		d.pskDecoder, err = psk.MakeDecoder(cfg.PSKDecoder, d.segSampleRate)
+		if err != nil {
+			return nil, fmt.Errorf("error creating PSK decoder: %s", err)
+		}
+		d.log.Info("PSK beacon decoder enabled at %f symbols/s", cfg.PSKDecoder.SymbolRate)
+	}
+
+	if cfg.CWDecoder.Enable {
+		if cfg.BeaconFrequency == 0 {
+			return nil, fmt.Errorf("the CW decoder needs the BeaconFrequency to place the CW beacon")
+		}
+		d.cwDecoder = cw.MakeDecoder(cfg.CWDecoder, float64(sampleRate), d.cwOffset())
+		d.log.Info("CW beacon decoder enabled at %.0f Hz offset", d.cwOffset())
+	}
+
+	if cfg.Residual.Enable {
+		d.residual = MakeResidualWorker(cfg.Residual, float64(sampleRate), float64(cfg.BeaconOffset))
+		d.log.Info("Residual drift meter enabled with %.3f Hz resolution", d.residual.GetResolution())
+	}
+
+	d.spectrum = MakeSpectrumWorker(d.segSampleRate, d.sampleRate)
+
+	d.interp = dsp.MakeFloatInterpolator(int(cfg.WorkDecimation))
+	d.log.Info("Output Sample Rate: %f", outSampleRate)
+	d.dcblock = dsp.MakeDCFilter()
+	d.clockMeter = MakeSampleClockMeter(float64(sampleRate), time.Duration(cfg.Resampler.MeasureWindow*float64(time.Second)))
+
+	if cfg.Resampler.Enable {
+		d.resampler = MakeFractionalResampler(cfg.Resampler.PPM, int(cfg.WorkDecimation))
+		d.log.Info("Fractional Resampler enabled with %f PPM", cfg.Resampler.PPM)
+	}
+
+	d.updateStatus()
+
+	return d, nil
+}
+
+func (d *Dedrifter) makeAGC() *dsp.AttackDecayAGC {
+	return dsp.MakeAttackDecayAGC(d.cfg.AGC.AttackRate, d.cfg.AGC.DecayRate, d.cfg.AGC.Reference, d.cfg.AGC.Gain, d.cfg.AGC.MaxGain)
+}
+
+// SetOnFFT sets the callback that receives the segment and full band FFTs. It runs in the spectrum worker goroutines.
+// Each stream has its own frame rate, the FFT of the other stream is nil.
+func (d *Dedrifter) SetOnFFT(cb OnFFT) {
+	d.spectrum.SetOnFFT(cb)
+}
+
+// Spectrum returns the worker that computes the FFTs
+func (d *Dedrifter) Spectrum() *SpectrumWorker {
+	return d.spectrum
+}
+
+// Residual returns the residual drift meter worker or nil if it is disabled
+func (d *Dedrifter) Residual() *ResidualWorker {
+	return d.residual
+}
+
+// PSKDecoder returns the PSK beacon decoder or nil if it is disabled
+func (d *Dedrifter) PSKDecoder() *psk.Decoder {
+	return d.pskDecoder
+}
+
+// CWDecoder returns the CW beacon decoder or nil if it is disabled
+func (d *Dedrifter) CWDecoder() *cw.Decoder {
+	return d.cwDecoder
+}
+
+// cwOffset returns the CW beacon frequency relative to the center of the full band
+func (d *Dedrifter) cwOffset() float64 {
+	return float64(d.cfg.BeaconOffset) + d.cfg.CWDecoder.Frequency - d.cfg.BeaconFrequency
+}
+
+// SetFFTSettings rebuilds the segment and full band FFT engines from the web settings. It must be called before the
+// spectrum worker is started.
+func (d *Dedrifter) SetFFTSettings(settings config.WebSettings) {
+	d.spectrum.SetSettings(settings)
+}
+
+// Retune queues a change of the beacon offset to be handled by the next Process call.
+// The samples received in the next FlushTime seconds are discarded and the last correction is held until relock.
+func (d *Dedrifter) Retune(beaconOffset float32) {
+	select {
+	case d.retuneChan <- beaconOffset:
+	default:
+		d.log.Error("Retune queue is full. Ignoring retune to %f Hz offset", beaconOffset)
+	}
+}
+
+// SetExternalCorrection disables the Costas Loop and corrects the full band with the drift (in Hertz) measured somewhere else.
+// The locked flag is the lock state of the source of the correction. It is safe to be called from any goroutine.
+func (d *Dedrifter) SetExternalCorrection(drift float32, locked bool) {
+	d.externalLock.Lock()
+	d.external = true
+	d.externalFrequency = drift * TwoPi / d.segSampleRate
+	d.externalLocked = locked
+	d.externalLock.Unlock()
+}
+
+// SetHoldover corrects the full band with a predicted drift (in Hertz) while the beacon is not available.
+// The Costas Loop keeps running, so it can lock again when the beacon returns. It is safe to be called from any goroutine.
+func (d *Dedrifter) SetHoldover(drift float32, active bool) {
+	d.holdoverLock.Lock()
+	d.holdover = active
+	d.holdoverFrequency = drift * TwoPi / d.segSampleRate
+	d.holdoverLock.Unlock()
+}
+
+func (d *Dedrifter) getHoldover() (bool, float32) {
+	d.holdoverLock.Lock()
+	defer d.holdoverLock.Unlock()
+	return d.holdover, d.holdoverFrequency
+}
+
+func (d *Dedrifter) getExternalCorrection() (bool, float32) {
+	d.externalLock.Lock()
+	defer d.externalLock.Unlock()
+	return d.external, d.externalFrequency
+}
+
+// ApplyHardwareStep informs that the upstream tuner was moved by step Hertz to follow the drift.
+// After latency (the time the upstream takes to deliver samples with the new frequency) the step is removed from the
+// digital correction, so the NCO only absorbs the residual and the output stays phase-continuous.
+func (d *Dedrifter) ApplyHardwareStep(step float32, latency time.Duration) {
+	select {
+	case d.hardwareStepChan <- hardwareStep{step: step, latency: latency}:
+	default:
+		d.log.Error("Hardware step queue is full. Ignoring step of %f Hz", step)
+	}
+}
+
+func (d *Dedrifter) updateHardwareSteps() {
+	received := true
+	for received {
+		select {
+		case s := <-d.hardwareStepChan:
+			d.pendingSteps = append(d.pendingSteps, pendingHardwareStep{
+				step:    s.step * TwoPi / d.segSampleRate,
+				applyAt: d.processedSamples + uint64(s.latency.Seconds()*float64(d.sampleRate)),
+			})
+		default:
+			received = false
+		}
+	}
+
+	for len(d.pendingSteps) > 0 && d.processedSamples >= d.pendingSteps[0].applyAt {
+		step := d.pendingSteps[0].step
+		d.costas.SetFrequency(d.costas.GetFrequency() - step)
+		if d.holdCorrection {
+			d.heldFrequency -= step
+		}
+		d.pendingSteps = d.pendingSteps[1:]
+	}
+}
+
+func (d *Dedrifter) GetSampleRate() float32 {
+	return d.sampleRate
+}
+
+func (d *Dedrifter) GetSegmentSampleRate() float32 {
+	return d.segSampleRate
+}
+
+// GetStatus returns a snapshot of the current lock state. It is safe to be called from any goroutine.
+func (d *Dedrifter) GetStatus() Status {
+	d.statusLock.Lock()
+	defer d.statusLock.Unlock()
+	return d.status
+}
+
+// GetDrift returns the current drift correction in Hertz
+func (d *Dedrifter) GetDrift() float32 {
+	return d.GetStatus().Drift
+}
+
+func (d *Dedrifter) GetBeaconOffset() float32 {
+	return d.GetStatus().BeaconOffset
+}
+
+func (d *Dedrifter) IsLocked() bool {
+	return d.GetStatus().Locked
+}
+
+func (d *Dedrifter) IsHoldingCorrection() bool {
+	return d.GetStatus().HoldingCorrection
+}
+
+func (d *Dedrifter) retune(beaconOffset float32) {
+	d.log.Info("Changed beacon offset from %f Hz to %f Hz. Recalculating.", d.cfg.BeaconOffset, beaconOffset)
+	d.cfg.BeaconOffset = beaconOffset
+	d.translator.SetFrequency(-beaconOffset)
+
+	// Samples that are still arriving were captured with the old center frequency
+	d.flushUntil = time.Now().Add(time.Duration(d.cfg.Retune.FlushTime * float64(time.Second)))
+
+	// The drift is the same in Hertz for the new offset, so re-seed the loop with it and hold the old correction until relock
+	if !d.holdCorrection {
+		d.heldFrequency = d.costas.GetFrequency()
+	}
+	d.holdCorrection = true
+	d.holdStarted = time.Now()
+	d.costas.SetFrequency(d.heldFrequency)
+	d.costas.SetPhase(0)
+	d.lockDetector.Reset()
+	d.agc = d.makeAGC()
+
+	if d.pskDecoder != nil {
+		d.pskDecoder.Reset()
+	}
+
+	if d.cwDecoder != nil {
+		d.cwDecoder.SetOffset(d.cwOffset())
+	}
+
+	if d.residual != nil {
+		d.residual.SetOffset(float64(beaconOffset))
+	}
+
+	// The samples dropped around the retune would show as a slow sample clock
+	d.clockMeter.Reset()
+
+	if d.gearShifter != nil && d.gearShifter.GetGear() > 1 {
+		d.gearShifter.SetGear(1)
+	}
+
+	d.retunes++
+}
+
+func (d *Dedrifter) updateCorrectionHold() {
+	if !d.holdCorrection {
+		return
+	}
+
+	locked := d.lockDetector.Value() >= d.cfg.CostasLoop.LockThreshold
+	timeout := time.Since(d.holdStarted) > time.Duration(d.cfg.Retune.HoldTimeout*float64(time.Second))
+
+	if locked || timeout {
+		if timeout && !locked {
+			d.log.Warn("Loop did not relock after %s. Releasing held correction.", time.Since(d.holdStarted))
+		} else {
+			d.log.Info("Loop relocked after %s", time.Since(d.holdStarted))
+		}
+		d.holdCorrection = false
+	}
+}
+
+// correctionFrequency returns the frequency being applied to the full band in radians per segment sample
+func (d *Dedrifter) correctionFrequency() float32 {
+	if external, frequency := d.getExternalCorrection(); external {
+		return frequency
+	}
+	if d.holdCorrection {
+		return d.heldFrequency
+	}
+	if holdover, frequency := d.getHoldover(); holdover {
+		return frequency
+	}
+	return d.costas.GetFrequency()
+}
+
+func (d *Dedrifter) constantShift(length int, frequency float32) []float32 {
+	if len(d.heldShift) < length {
+		d.heldShift = make([]float32, length)
+	}
+
+	d.heldShift = d.heldShift[:length]
+	for i := range d.heldShift {
+		d.heldShift[i] = frequency
+	}
+
+	return d.heldShift
+}
+
+func (d *Dedrifter) frequencyShift(length int) []float32 {
+	if external, frequency := d.getExternalCorrection(); external {
+		return d.constantShift(length, frequency)
+	}
+
+	if d.holdCorrection {
+		return d.constantShift(length, d.heldFrequency)
+	}
+
+	if holdover, frequency := d.getHoldover(); holdover {
+		return d.constantShift(length, frequency)
+	}
+
+	return d.costas.GetFrequencyShift()
+}
+
+// ResetSampleClock restarts the sample clock measurement, like when the upstream reconnects.
+// It is safe to be called from any goroutine.
+func (d *Dedrifter) ResetSampleClock() {
+	d.clockMeter.Reset()
+}
+
+func (d *Dedrifter) updateSampleClock() {
+	ppm, ok := d.clockMeter.PPM()
+	if !ok {
+		return
+	}
+
+	if d.resampler != nil && d.cfg.Resampler.MeasurePPM {
+		d.resampler.SetPPM(ppm)
+	}
+}
+
+func power(samples []complex64) float64 {
+	p := 0.0
+	for _, v := range samples {
+		p += float64(real(v)*real(v) + imag(v)*imag(v))
+	}
+	return p
+}
+
+func (d *Dedrifter) updateBeaconMeasurement() {
+	if time.Since(d.lastBeaconMeasure) < beaconMeasureInterval {
+		return
+	}
+
+	d.lastBeaconMeasure = time.Now()
+
+	if m, ok := d.beaconMeter.Measure(); ok {
+		d.beacon = m
+	}
+
+	if d.agcInputPower > 0 && d.agcOutputPower > 0 {
+		d.agcGain = toDB(d.agcOutputPower / d.agcInputPower)
+	}
+
+	d.agcInputPower = 0
+	d.agcOutputPower = 0
+}
+
+func (d *Dedrifter) updateStatus() {
+	ppm, measured := d.clockMeter.PPM()
+	external, _ := d.getExternalCorrection()
+	holdover, _ := d.getHoldover()
+	locked := !d.holdCorrection && d.lockDetector.Value() >= d.cfg.CostasLoop.LockThreshold
+
+	decoded := false
+	if d.pskDecoder != nil {
+		decoded = d.pskDecoder.Decoded(time.Duration(d.cfg.PSKDecoder.ValidFor * float64(time.Second)))
+		if d.cfg.PSKDecoder.RequireDecode {
+			locked = locked && decoded
+		}
+	}
+
+	verified := false
+	if d.cwDecoder != nil {
+		verified = d.cwDecoder.Verified()
+		if d.cfg.CWDecoder.RequireVerified {
+			locked = locked && verified
+		}
+	}
+
+	if external {
+		d.externalLock.Lock()
+		locked = d.externalLocked
+		d.externalLock.Unlock()
+	}
+
+	var residual ResidualMeasurement
+	residualMeasured := false
+	if d.residual != nil {
+		residual, residualMeasured = d.residual.GetMeasurement()
+	}
+
+	pending := float32(0)
+	for _, step := range d.pendingSteps {
+		pending += step.step * d.segSampleRate / TwoPi
+	}
+
+	s := Status{
+		BeaconOffset:        d.cfg.BeaconOffset,
+		PendingHardware:     pending,
+		Drift:               d.correctionFrequency() * d.segSampleRate / TwoPi,
+		LockDetector:        d.lockDetector.Value(),
+		BeaconPower:         d.beacon.Power,
+		NoiseFloor:          d.beacon.NoiseFloor,
+		BeaconSNR:           d.beacon.SNR,
+		BeaconCN0:           d.beacon.CN0,
+		AGCGain:             d.agcGain,
+		Locked:              locked,
+		HoldingCorrection:   d.holdCorrection,
+		External:            external,
+		Holdover:            holdover,
+		LoopBandwidth:       d.costas.GetLoopBandwidth(),
+		SampleClockPPM:      ppm,
+		SampleClockMeasured: measured,
+		Retunes:             d.retunes,
+		BeaconDecoded:       decoded,
+		BeaconVerified:      verified,
+		ResidualError:       residual.Error,
+		ResidualWidth:       residual.Width,
+		ResidualJitter:      residual.Jitter,
+		ResidualMeasured:    residualMeasured,
+	}
+
+	if d.gearShifter != nil {
+		s.Gear = d.gearShifter.GetGear()
+	}
+
+	if d.resampler != nil {
+		s.ResamplerPPM = d.resampler.GetPPM()
+	}
+
+	d.statusLock.Lock()
+	d.status = s
+	d.statusLock.Unlock()
+}
+
+func (d *Dedrifter) checkAndResizeBuffers(length int) {
+	if len(d.buffer0) < length {
+		d.buffer0 = make([]complex64, length)
+	}
+	if len(d.buffer1) < length {
+		d.buffer1 = make([]complex64, length)
+	}
+}
+
+func swapAndTrimSlices(a *[]complex64, b *[]complex64, length int) {
+	*a = (*a)[:length]
+	*b = (*b)[:length]
+
+	c := *b
+	*b = *a
+	*a = c
+}
+
+// Process runs the dedrift chain over the input samples and returns the corrected samples.
+// The input is corrected in place unless the resampler is enabled.
+// The output can be shorter than the input (or empty) when the resampler is enabled or after a retune.
+func (d *Dedrifter) Process(in []complex64) []complex64 {
+	select {
+	case beaconOffset := <-d.retuneChan:
+		d.retune(beaconOffset)
+	default:
+	}
+
+	d.clockMeter.Add(len(in))
+	d.processedSamples += uint64(len(in))
+	d.updateHardwareSteps()
+
+	if time.Now().Before(d.flushUntil) {
+		return nil
+	}
+
+	originalData := in
+
+	if d.resampler != nil {
+		originalData = d.resampler.Work(originalData)
+		if len(originalData) == 0 {
+			return nil
+		}
+	}
+
+	d.dcblock.WorkInline(originalData)
+
+	d.checkAndResizeBuffers(len(originalData))
+
+	a := d.buffer0
+	b := d.buffer1
+
+	a = a[:len(originalData)]
+
+	copy(a, originalData)
+
+	l := d.translator.WorkBuffer(a, b)
+	swapAndTrimSlices(&a, &b, l)
+
+	d.beaconMeter.Work(a)
+	d.agcInputPower += power(a)
+
+	l = d.agc.WorkBuffer(a, b)
+	swapAndTrimSlices(&a, &b, l)
+
+	d.agcOutputPower += power(a)
+	d.updateBeaconMeasurement()
+
+	if external, _ := d.getExternalCorrection(); !external {
+		l = d.costas.WorkBuffer(a, b)
+		swapAndTrimSlices(&a, &b, l)
+
+		d.lockDetector.Work(a)
+
+		if d.pskDecoder != nil {
+			d.pskDecoder.Work(a)
+		}
+
+		if d.gearShifter != nil && !d.holdCorrection && d.gearShifter.Update(d.lockDetector.Value()) {
+			d.log.Info("Costas Loop shifted to gear %d (bandwidth %f)", d.gearShifter.GetGear(), d.gearShifter.GetBandwidth())
+		}
+
+		d.updateCorrectionHold()
+	}
+
+	d.updateSampleClock()
+
+	fs := d.frequencyShift(l)
+	fs = d.interp.Work(fs)
+
+	for i, v := range fs {
+		c := tools.PhaseToComplex(d.phase)
+		originalData[i] *= c
+		d.phase -= v
+		if d.phase > TwoPi || d.phase < MinusTwoPi { // Wrap phase between - 2 * pi and + 2 * pi
+			d.phase = d.phase*OneOverTwoPi - float32(int(d.phase*OneOverTwoPi))
+			d.phase = d.phase * TwoPi
+		}
+	}
+
+	if d.cwDecoder != nil {
+		d.cwDecoder.Work(originalData)
+	}
+
+	if d.residual != nil {
+		d.residual.Work(originalData)
+	}
+
+	d.updateStatus()
+
+	d.spectrum.Work(a, originalData)
+
+	return originalData
+}
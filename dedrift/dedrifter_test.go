@@ -0,0 +1,29 @@
+package dedrift
+
+import (
+	"github.com/racerxdl/qo100-dedrift/config"
+	"testing"
+)
+
+func TestMakeDedrifterCWNeedsBeaconFrequency(t *testing.T) {
+	cfg := config.DefaultConfig.Processing
+	cfg.CWDecoder.Enable = true
+
+	// The config loader resolves the BeaconFrequency, a zero one would place the CW beacon at the wrong offset
+	cfg.BeaconFrequency = 0
+	_, err := MakeDedrifter(cfg, config.DefaultSampleRate)
+	if err == nil {
+		t.Errorf("expected an error with the CW decoder enabled and no BeaconFrequency")
+	}
+
+	cfg.BeaconFrequency = cfg.CWDecoder.Frequency + 250e3
+	d, err := MakeDedrifter(cfg, config.DefaultSampleRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := float64(cfg.BeaconOffset) - 250e3
+	if d.cwOffset() != expected {
+		t.Errorf("expected the CW beacon at %.0f Hz, got %.0f Hz", expected, d.cwOffset())
+	}
+}
@@ -0,0 +1,117 @@
+package dedrift
+
+import (
+	"sync"
+)
+
+// sampleHistory keeps the last samples of a stream, so a frame can span several blocks
+type sampleHistory struct {
+	samples   []complex64
+	maxLength int
+}
+
+func makeSampleHistory(maxLength int) sampleHistory {
+	return sampleHistory{
+		samples:   make([]complex64, 0, maxLength*2),
+		maxLength: maxLength,
+	}
+}
+
+func (h *sampleHistory) write(samples []complex64) {
+	if len(samples) >= h.maxLength {
+		h.samples = append(h.samples[:0], samples[len(samples)-h.maxLength:]...)
+		return
+	}
+
+	if len(h.samples)+len(samples) > cap(h.samples) {
+		keep := h.maxLength - len(samples)
+		h.samples = h.samples[:copy(h.samples, h.samples[len(h.samples)-keep:])]
+	}
+
+	h.samples = append(h.samples, samples...)
+}
+
+// FrameWorker processes frames made of the last samples of a stream out of the DSP loop. The samples are copied when
+// a frame is due and processed in a separate goroutine. Frames are skipped while the previous one is still being
+// processed, so a slow consumer never delays the IQ output.
+type FrameWorker struct {
+	history sampleHistory
+	process func(frame []complex64)
+
+	frames  chan []complex64
+	free    chan []complex64
+	done    chan bool
+	stopped chan bool
+
+	lock          sync.Mutex
+	droppedFrames int
+}
+
+// MakeFrameWorker creates a worker that calls process with at least length of the last samples once the stream has
+// enough of them. It runs in the worker goroutine and must not keep the frame.
+func MakeFrameWorker(length int, process func(frame []complex64)) *FrameWorker {
+	w := &FrameWorker{
+		history: makeSampleHistory(length),
+		process: process,
+		frames:  make(chan []complex64, 1),
+		free:    make(chan []complex64, 1),
+		done:    make(chan bool),
+		stopped: make(chan bool),
+	}
+
+	w.free <- make([]complex64, 0, length)
+
+	return w
+}
+
+func (w *FrameWorker) Start() {
+	go w.loop()
+}
+
+// Stop ends the worker and waits for the frame being processed
+func (w *FrameWorker) Stop() {
+	close(w.done)
+	<-w.stopped
+}
+
+// Work keeps the last samples and copies them to the worker when due is true. It never blocks.
+func (w *FrameWorker) Work(samples []complex64, due bool) {
+	w.history.write(samples)
+
+	if !due {
+		return
+	}
+
+	var frame []complex64
+
+	select {
+	case frame = <-w.free:
+	default:
+		w.lock.Lock()
+		w.droppedFrames++
+		w.lock.Unlock()
+		return
+	}
+
+	w.frames <- append(frame[:0], w.history.samples...)
+}
+
+func (w *FrameWorker) loop() {
+	for {
+		select {
+		case frame := <-w.frames:
+			w.process(frame)
+			w.free <- frame
+		case <-w.done:
+			close(w.stopped)
+			return
+		}
+	}
+}
+
+// GetDroppedFrames returns the number of frames skipped because the worker was busy
+func (w *FrameWorker) GetDroppedFrames() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.droppedFrames
+}
@@ -0,0 +1,149 @@
+package dedrift
+
+import (
+	"github.com/racerxdl/segdsp/dsp"
+	"time"
+)
+
+const (
+	lockDetectorAlpha = 1e-3
+)
+
+// costasLoop is the dsp.CostasLoop with the control loop methods exposed by the segdsp implementations
+type costasLoop interface {
+	dsp.CostasLoop
+	SetLoopBandwidth(bw float32) error
+	GetLoopBandwidth() float32
+	SetFrequency(freq float32)
+	GetPhase() float32
+	SetPhase(phase float32)
+}
+
+func makeCostasLoop(bandwidth float32) costasLoop {
+	return dsp.MakeCostasLoop2(bandwidth).(costasLoop)
+}
+
+// LockDetector measures the BPSK lock quality from the Costas Loop output.
+// The value is the averaged cos(2 * phase error), close to 1 when locked and close to 0 when not.
+type LockDetector struct {
+	value float32
+}
+
+func MakeLockDetector() *LockDetector {
+	return &LockDetector{}
+}
+
+func (ld *LockDetector) Work(samples []complex64) {
+	v := ld.value
+	for _, s := range samples {
+		i2 := real(s) * real(s)
+		q2 := imag(s) * imag(s)
+		p := i2 + q2
+		if p == 0 {
+			continue
+		}
+		v += lockDetectorAlpha * ((i2-q2)/p - v)
+	}
+	ld.value = v
+}
+
+func (ld *LockDetector) Value() float32 {
+	return ld.value
+}
+
+func (ld *LockDetector) Reset() {
+	ld.value = 0
+}
+
+// GearShifter steps the Costas Loop bandwidth from a wide acquisition bandwidth to a narrow tracking bandwidth
+// as the lock detector gets confident, and widens it again when the lock is stressed.
+type GearShifter struct {
+	loop            costasLoop
+	bandwidths      []float32
+	gear            int
+	lockThreshold   float32
+	unlockThreshold float32
+	lockHoldTime    time.Duration
+	unlockHoldTime  time.Duration
+	aboveSince      time.Time
+	belowSince      time.Time
+}
+
+func MakeGearShifter(loop costasLoop, bandwidths []float32, lockThreshold, unlockThreshold float32, lockHoldTime, unlockHoldTime time.Duration) *GearShifter {
+	gs := &GearShifter{
+		loop:            loop,
+		bandwidths:      bandwidths,
+		lockThreshold:   lockThreshold,
+		unlockThreshold: unlockThreshold,
+		lockHoldTime:    lockHoldTime,
+		unlockHoldTime:  unlockHoldTime,
+	}
+
+	gs.SetGear(0)
+
+	return gs
+}
+
+func (gs *GearShifter) SetGear(gear int) {
+	if gear < 0 {
+		gear = 0
+	}
+
+	if gear >= len(gs.bandwidths) {
+		gear = len(gs.bandwidths) - 1
+	}
+
+	gs.gear = gear
+	_ = gs.loop.SetLoopBandwidth(gs.bandwidths[gear])
+	gs.aboveSince = time.Time{}
+	gs.belowSince = time.Time{}
+}
+
+func (gs *GearShifter) GetGear() int {
+	return gs.gear
+}
+
+func (gs *GearShifter) GetBandwidth() float32 {
+	return gs.bandwidths[gs.gear]
+}
+
+// Update feeds the current lock detector value and returns true if the gear has changed
+func (gs *GearShifter) Update(lockValue float32) bool {
+	now := time.Now()
+
+	if lockValue < gs.unlockThreshold {
+		gs.aboveSince = time.Time{}
+		if gs.gear == 0 {
+			return false
+		}
+
+		if gs.belowSince.IsZero() {
+			gs.belowSince = now
+		}
+
+		if now.Sub(gs.belowSince) >= gs.unlockHoldTime {
+			gs.SetGear(gs.gear - 1)
+			return true
+		}
+
+		return false
+	}
+
+	gs.belowSince = time.Time{}
+
+	if lockValue < gs.lockThreshold || gs.gear == len(gs.bandwidths)-1 {
+		gs.aboveSince = time.Time{}
+		return false
+	}
+
+	if gs.aboveSince.IsZero() {
+		gs.aboveSince = now
+	}
+
+	if now.Sub(gs.aboveSince) >= gs.lockHoldTime {
+		gs.SetGear(gs.gear + 1)
+		return true
+	}
+
+	return false
+}
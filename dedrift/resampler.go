@@ -0,0 +1,169 @@
+package dedrift
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	clockMeterWarmup             = 10 * time.Second
+	clockMeterCheckpointInterval = time.Second
+)
+
+// FractionalResampler is a cubic (3rd order Lagrange / Farrow) resampler used to correct small sample clock errors.
+// The output is always returned in multiples of blockSize, the remaining samples are held until the next call.
+type FractionalResampler struct {
+	rate      float64 // Input samples consumed per output sample
+	position  float64
+	history   []complex64
+	pending   []complex64
+	blockSize int
+}
+
+func MakeFractionalResampler(ppm float64, blockSize int) *FractionalResampler {
+	if blockSize < 1 {
+		blockSize = 1
+	}
+
+	fr := &FractionalResampler{
+		position:  1,
+		history:   make([]complex64, 3),
+		pending:   make([]complex64, 0),
+		blockSize: blockSize,
+	}
+
+	fr.SetPPM(ppm)
+
+	return fr
+}
+
+func (fr *FractionalResampler) SetPPM(ppm float64) {
+	fr.rate = 1 + ppm*1e-6
+}
+
+func (fr *FractionalResampler) GetPPM() float64 {
+	return (fr.rate - 1) * 1e6
+}
+
+func (fr *FractionalResampler) Work(input []complex64) []complex64 {
+	samples := append(fr.history, input...)
+	l := len(samples)
+
+	for int(fr.position)+2 < l {
+		i := int(fr.position)
+		mu := complex(float32(fr.position-float64(i)), 0)
+
+		x0 := samples[i-1]
+		x1 := samples[i]
+		x2 := samples[i+1]
+		x3 := samples[i+2]
+
+		c1 := -x0/3 - x1/2 + x2 - x3/6
+		c2 := x0/2 - x1 + x2/2
+		c3 := -x0/6 + x1/2 - x2/2 + x3/6
+
+		fr.pending = append(fr.pending, ((c3*mu+c2)*mu+c1)*mu+x1)
+		fr.position += fr.rate
+	}
+
+	fr.history = append(fr.history[:0], samples[l-3:]...)
+	fr.position -= float64(l - 3)
+
+	n := len(fr.pending) - len(fr.pending)%fr.blockSize
+	output := make([]complex64, n)
+	copy(output, fr.pending[:n])
+	fr.pending = append(fr.pending[:0], fr.pending[n:]...)
+
+	return output
+}
+
+// clockCheckpoint is the number of samples counted by the SampleClockMeter at a point in time
+type clockCheckpoint struct {
+	time  time.Time
+	count uint64
+}
+
+// SampleClockMeter estimates the upstream sample clock error by comparing the received sample count with wall clock
+// time over a sliding window. It is safe to be called from any goroutine.
+type SampleClockMeter struct {
+	sync.Mutex
+	sampleRate  float64
+	window      time.Duration
+	created     time.Time
+	count       uint64
+	checkpoints []clockCheckpoint
+}
+
+func MakeSampleClockMeter(sampleRate float64, window time.Duration) *SampleClockMeter {
+	return &SampleClockMeter{
+		sampleRate:  sampleRate,
+		window:      window,
+		created:     time.Now(),
+		checkpoints: make([]clockCheckpoint, 0),
+	}
+}
+
+func (m *SampleClockMeter) Add(samples int) {
+	m.add(time.Now(), samples)
+}
+
+func (m *SampleClockMeter) add(now time.Time, samples int) {
+	m.Lock()
+	defer m.Unlock()
+
+	if now.Sub(m.created) < clockMeterWarmup { // Ignore the initial burst of buffered samples
+		return
+	}
+
+	if len(m.checkpoints) == 0 {
+		// These samples were received before the measurement started
+		m.checkpoints = append(m.checkpoints, clockCheckpoint{time: now})
+		return
+	}
+
+	m.count += uint64(samples)
+
+	if now.Sub(m.checkpoints[len(m.checkpoints)-1].time) < clockMeterCheckpointInterval {
+		return
+	}
+
+	m.checkpoints = append(m.checkpoints, clockCheckpoint{time: now, count: m.count})
+
+	// Keep the newest checkpoint that is at least a window old as the start of the window
+	for len(m.checkpoints) > 2 && now.Sub(m.checkpoints[1].time) >= m.window {
+		m.checkpoints = m.checkpoints[1:]
+	}
+}
+
+// Reset restarts the measurement, like after a retune or when the upstream reconnects
+func (m *SampleClockMeter) Reset() {
+	m.Lock()
+	defer m.Unlock()
+
+	m.created = time.Now()
+	m.count = 0
+	m.checkpoints = m.checkpoints[:0]
+}
+
+// PPM returns the sample clock error in parts per million measured over the last window and if a full window
+// was already measured
+func (m *SampleClockMeter) PPM() (float64, bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	if len(m.checkpoints) < 2 {
+		return 0, false
+	}
+
+	first := m.checkpoints[0]
+	last := m.checkpoints[len(m.checkpoints)-1]
+
+	elapsed := last.time.Sub(first.time)
+	if elapsed < m.window || elapsed <= 0 {
+		return 0, false
+	}
+
+	measuredRate := float64(last.count-first.count) / elapsed.Seconds()
+
+	return (measuredRate/m.sampleRate - 1) * 1e6, true
+}
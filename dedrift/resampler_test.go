@@ -0,0 +1,75 @@
+package dedrift
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func tone(n int, start, frequency float64) []complex64 {
+	samples := make([]complex64, n)
+	for i := range samples {
+		samples[i] = complex64(cmplx.Exp(complex(0, 2*math.Pi*frequency*(start+float64(i)))))
+	}
+
+	return samples
+}
+
+func TestFractionalResamplerPassthrough(t *testing.T) {
+	fr := MakeFractionalResampler(0, 1)
+	input := tone(1000, 0, 0.01)
+
+	output := fr.Work(input)
+
+	// The interpolator delays the input by two samples
+	if len(output) != len(input) {
+		t.Fatalf("expected %d samples, got %d", len(input), len(output))
+	}
+
+	for i := 2; i < len(output); i++ {
+		if output[i] != input[i-2] {
+			t.Fatalf("expected sample %d to be %v, got %v", i, input[i-2], output[i])
+		}
+	}
+}
+
+func TestFractionalResamplerRate(t *testing.T) {
+	ppm := 1000.0
+	fr := MakeFractionalResampler(ppm, 64)
+	if math.Abs(fr.GetPPM()-ppm) > 1e-6 {
+		t.Errorf("expected %f ppm, got %f", ppm, fr.GetPPM())
+	}
+
+	frequency := 0.001
+	inputs := 0
+	outputs := 0
+	maxError := 0.0
+
+	for block := 0; block < 100; block++ {
+		output := fr.Work(tone(1000, float64(inputs), frequency))
+		inputs += 1000
+
+		if len(output)%64 != 0 {
+			t.Fatalf("expected the output in blocks of 64 samples, got %d", len(output))
+		}
+
+		// Output sample k interpolates the input at k * rate - 2
+		for _, v := range output {
+			if outputs > 10 {
+				position := float64(outputs)*(1+ppm*1e-6) - 2
+				expected := cmplx.Exp(complex(0, 2*math.Pi*frequency*position))
+				maxError = math.Max(maxError, cmplx.Abs(complex128(v)-expected))
+			}
+			outputs++
+		}
+	}
+
+	expected := float64(inputs) / (1 + ppm*1e-6)
+	if math.Abs(float64(outputs)-expected) > 64+3 {
+		t.Errorf("expected about %f samples, got %d", expected, outputs)
+	}
+
+	if maxError > 1e-4 {
+		t.Errorf("expected the interpolation error under 1e-4, got %e", maxError)
+	}
+}
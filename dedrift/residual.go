@@ -0,0 +1,188 @@
+package dedrift
+
+import (
+	"github.com/racerxdl/qo100-dedrift/channel"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"github.com/racerxdl/segdsp/dsp"
+	"github.com/racerxdl/segdsp/dsp/fft"
+	"math"
+)
+
+// ResidualMeasurement is the correction quality measured on the beacon after the drift correction
+type ResidualMeasurement struct {
+	Error  float32 // Beacon frequency error after the correction in Hertz
+	Width  float32 // -3 dB width of the beacon line in Hertz
+	Jitter float32 // Standard deviation of Error over the jitter window in Hertz
+}
+
+// ResidualMeter takes a narrow, high resolution spectrum of the beacon from the corrected full band.
+// The channel is squared before the FFT, which removes the BPSK modulation of the beacon and leaves a line at twice
+// its frequency error. A CW beacon is not affected by the squaring besides the same frequency doubling.
+type ResidualMeter struct {
+	cfg         config.ResidualConfig
+	channelizer *channel.Channelizer
+	sampleRate  float64
+	fftSize     int
+
+	window  []float64
+	samples []complex64
+	frame   []complex64
+	psd     []float64
+	frames  int
+	errors  []float64
+}
+
+// MakeResidualMeter creates a meter for the beacon at offset Hertz from the center of the full band
+func MakeResidualMeter(cfg config.ResidualConfig, sampleRate, offset float64) *ResidualMeter {
+	c := channel.MakeChannelizer(sampleRate, offset, cfg.Bandwidth)
+
+	fftSize := 1
+	for fftSize < cfg.FFTSize {
+		fftSize <<= 1
+	}
+
+	return &ResidualMeter{
+		cfg:         cfg,
+		channelizer: c,
+		sampleRate:  c.GetSampleRate(),
+		fftSize:     fftSize,
+		window:      dsp.BlackmanHarris(fftSize, 92),
+		frame:       make([]complex64, fftSize),
+		psd:         make([]float64, fftSize),
+	}
+}
+
+// SetOffset changes the beacon frequency relative to the center of the full band, like after a retune
+func (m *ResidualMeter) SetOffset(offset float64) {
+	m.channelizer.SetOffset(offset)
+	m.Reset()
+	m.errors = m.errors[:0]
+}
+
+// Reset drops the channel history and the samples kept for the next measurement, like after a gap in the stream
+func (m *ResidualMeter) Reset() {
+	m.channelizer.Reset()
+	m.samples = m.samples[:0]
+}
+
+// GetResolution returns the frequency resolution of the measurement in Hertz
+func (m *ResidualMeter) GetResolution() float64 {
+	return m.sampleRate / float64(m.fftSize) / 2
+}
+
+// Work adds the corrected full band samples
+func (m *ResidualMeter) Work(samples []complex64) {
+	for _, v := range m.channelizer.Work(samples) {
+		m.samples = append(m.samples, v*v)
+	}
+
+	// Only the last fftSize samples are used, the measurements overlap when the interval is shorter than the FFT
+	if len(m.samples) > m.fftSize {
+		m.samples = m.samples[:copy(m.samples, m.samples[len(m.samples)-m.fftSize:])]
+	}
+}
+
+// Measure computes the spectrum of the last samples and returns the measurement.
+// It returns false if there are not enough samples yet.
+func (m *ResidualMeter) Measure() (ResidualMeasurement, bool) {
+	if len(m.samples) < m.fftSize {
+		return ResidualMeasurement{}, false
+	}
+
+	for i, v := range m.samples {
+		w := float32(m.window[i])
+		m.frame[i] = complex(real(v)*w, imag(v)*w)
+	}
+
+	// Exponential average of the power spectrum, ordered from the lowest to the highest frequency
+	alpha := 1.0
+	if m.cfg.Averaging > 1 && m.frames > 0 {
+		alpha = 1 / m.cfg.Averaging
+	}
+
+	n := m.fftSize
+	for i, v := range fft.FFT(m.frame) {
+		p := float64(real(v)*real(v) + imag(v)*imag(v))
+		o := (i + n/2) % n
+		m.psd[o] += alpha * (p - m.psd[o])
+	}
+	m.frames++
+
+	binWidth := m.sampleRate / float64(n)
+
+	// The squared beacon line is at twice the error, so search twice the maximum error
+	span := int(2 * m.cfg.MaxError / binWidth)
+	first := n/2 - span
+	last := n/2 + span
+	if first < 1 {
+		first = 1
+	}
+	if last > n-2 {
+		last = n - 2
+	}
+
+	peak := first
+	for i := first; i <= last; i++ {
+		if m.psd[i] > m.psd[peak] {
+			peak = i
+		}
+	}
+
+	// Parabolic interpolation of the peak in dB
+	a, b, c := toDB(m.psd[peak-1]), toDB(m.psd[peak]), toDB(m.psd[peak+1])
+	delta := 0.0
+	if d := float64(a - 2*b + c); d != 0 {
+		delta = 0.5 * float64(a-c) / d
+	}
+
+	lineFrequency := (float64(peak-n/2) + delta) * binWidth
+
+	// -3 dB width with linear interpolation on both sides
+	half := m.psd[peak] / 2
+	lower := float64(peak)
+	for i := peak; i > 0; i-- {
+		if m.psd[i-1] < half {
+			lower = float64(i) - (m.psd[i]-half)/(m.psd[i]-m.psd[i-1])
+			break
+		}
+	}
+	upper := float64(peak)
+	for i := peak; i < n-1; i++ {
+		if m.psd[i+1] < half {
+			upper = float64(i) + (m.psd[i]-half)/(m.psd[i]-m.psd[i+1])
+			break
+		}
+	}
+
+	residual := lineFrequency / 2
+
+	m.errors = append(m.errors, residual)
+	if len(m.errors) > m.cfg.JitterWindow {
+		m.errors = m.errors[:copy(m.errors, m.errors[len(m.errors)-m.cfg.JitterWindow:])]
+	}
+
+	return ResidualMeasurement{
+		Error:  float32(residual),
+		Width:  float32((upper - lower) * binWidth / 2),
+		Jitter: float32(standardDeviation(m.errors)),
+	}, true
+}
+
+func standardDeviation(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+
+	return math.Sqrt(variance / float64(len(values)-1))
+}
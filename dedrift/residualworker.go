@@ -0,0 +1,162 @@
+package dedrift
+
+import (
+	"github.com/racerxdl/qo100-dedrift/config"
+	"sync"
+	"time"
+)
+
+// residualWorkerBlocks is the number of sample blocks that can wait for the residual worker
+const residualWorkerBlocks = 4
+
+// residualBlock is a copy of the corrected full band. When retune is set, the channel moves to offset before the
+// samples are added. When gap is set, blocks were dropped before this one.
+type residualBlock struct {
+	samples []complex64
+	offset  float64
+	retune  bool
+	gap     bool
+	retunes int // Retunes requested when the block was queued
+}
+
+// ResidualWorker runs the ResidualMeter out of the DSP loop. The channel extraction and the FFT run in a separate
+// goroutine over copies of the corrected full band. Blocks are dropped while every buffer is waiting for the worker,
+// and the measurement restarts after a gap so it never spans missing samples.
+type ResidualWorker struct {
+	meter    *ResidualMeter
+	interval time.Duration
+
+	blocks chan residualBlock
+	free   chan residualBlock
+	done   chan bool
+
+	// Only accessed from the DSP loop
+	pendingOffset float64
+	pendingRetune bool
+	dropped       bool
+
+	// Only accessed from the worker
+	lastMeasure time.Time
+
+	lock        sync.Mutex
+	measurement ResidualMeasurement
+	measured    bool
+	retunes     int
+}
+
+func MakeResidualWorker(cfg config.ResidualConfig, sampleRate, offset float64) *ResidualWorker {
+	w := &ResidualWorker{
+		meter:       MakeResidualMeter(cfg, sampleRate, offset),
+		interval:    time.Duration(cfg.Interval * float64(time.Second)),
+		blocks:      make(chan residualBlock, residualWorkerBlocks),
+		free:        make(chan residualBlock, residualWorkerBlocks),
+		done:        make(chan bool),
+		lastMeasure: time.Now(),
+	}
+
+	for i := 0; i < residualWorkerBlocks; i++ {
+		w.free <- residualBlock{}
+	}
+
+	return w
+}
+
+// GetResolution returns the frequency resolution of the measurement in Hertz
+func (w *ResidualWorker) GetResolution() float64 {
+	return w.meter.GetResolution()
+}
+
+func (w *ResidualWorker) Start() {
+	go w.loop()
+}
+
+func (w *ResidualWorker) Stop() {
+	close(w.done)
+}
+
+// SetOffset moves the beacon channel, like after a retune. It applies from the next block and drops the last
+// measurement.
+func (w *ResidualWorker) SetOffset(offset float64) {
+	w.pendingOffset = offset
+	w.pendingRetune = true
+
+	w.lock.Lock()
+	w.measured = false
+	w.retunes++
+	w.lock.Unlock()
+}
+
+// Work copies the corrected full band samples to the worker. It never blocks.
+func (w *ResidualWorker) Work(samples []complex64) {
+	var block residualBlock
+
+	select {
+	case block = <-w.free:
+	default:
+		w.dropped = true
+		return
+	}
+
+	block.samples = append(block.samples[:0], samples...)
+	block.offset = w.pendingOffset
+	block.retune = w.pendingRetune
+	block.gap = w.dropped
+
+	w.lock.Lock()
+	block.retunes = w.retunes
+	w.lock.Unlock()
+
+	w.pendingRetune = false
+	w.dropped = false
+
+	w.blocks <- block
+}
+
+func (w *ResidualWorker) loop() {
+	for {
+		select {
+		case block := <-w.blocks:
+			w.process(block)
+			w.free <- block
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *ResidualWorker) process(block residualBlock) {
+	if block.retune {
+		w.meter.SetOffset(block.offset)
+	} else if block.gap {
+		w.meter.Reset()
+	}
+
+	w.meter.Work(block.samples)
+
+	if time.Since(w.lastMeasure) < w.interval {
+		return
+	}
+
+	w.lastMeasure = time.Now()
+
+	m, ok := w.meter.Measure()
+	if !ok {
+		return
+	}
+
+	w.lock.Lock()
+	// A retune requested after this block was queued already dropped the measurement
+	if block.retunes == w.retunes {
+		w.measurement = m
+		w.measured = true
+	}
+	w.lock.Unlock()
+}
+
+// GetMeasurement returns the last measurement and false if there is none since the last retune.
+// It is safe to be called from any goroutine.
+func (w *ResidualWorker) GetMeasurement() (ResidualMeasurement, bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.measurement, w.measured
+}
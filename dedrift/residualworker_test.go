@@ -0,0 +1,67 @@
+package dedrift
+
+import (
+	"github.com/racerxdl/qo100-dedrift/config"
+	"math"
+	"testing"
+	"time"
+)
+
+func waitMeasurement(w *ResidualWorker) (ResidualMeasurement, bool) {
+	for i := 0; i < 200; i++ {
+		if m, ok := w.GetMeasurement(); ok {
+			return m, true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return ResidualMeasurement{}, false
+}
+
+func TestResidualWorker(t *testing.T) {
+	const (
+		sampleRate = 48000
+		offset     = 5000
+		errorHz    = 2.0
+	)
+
+	cfg := config.DefaultConfig.Processing.Residual
+	cfg.Interval = 0
+
+	w := MakeResidualWorker(cfg, sampleRate, offset)
+	w.Start()
+	defer w.Stop()
+
+	// Blocks are queued faster than the worker runs, wait for a free buffer like the DSP loop pace would
+	feed := func(frequency float64, seconds int) {
+		for i := 0; i < seconds*sampleRate; i += 4800 {
+			for len(w.free) == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			w.Work(tone(4800, float64(i), frequency/sampleRate))
+		}
+	}
+
+	feed(offset+errorHz, 6)
+	m, ok := waitMeasurement(w)
+	if !ok {
+		t.Fatal("expected a measurement")
+	}
+	if math.Abs(float64(m.Error)-errorHz) > w.GetResolution() {
+		t.Errorf("expected a %.1f Hz error, got %f Hz", errorHz, m.Error)
+	}
+
+	// A retune drops the measurement until the new channel fills the FFT
+	w.SetOffset(-offset)
+	if _, ok := w.GetMeasurement(); ok {
+		t.Errorf("expected no measurement right after a retune")
+	}
+
+	feed(-offset-errorHz, 6)
+	m, ok = waitMeasurement(w)
+	if !ok {
+		t.Fatal("expected a measurement after the retune")
+	}
+	if math.Abs(float64(m.Error)+errorHz) > w.GetResolution() {
+		t.Errorf("expected a -%.1f Hz error, got %f Hz", errorHz, m.Error)
+	}
+}
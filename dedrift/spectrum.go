@@ -0,0 +1,253 @@
+package dedrift
+
+import (
+	"github.com/quan-to/slog"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"github.com/racerxdl/segdsp/dsp/fft"
+	"github.com/racerxdl/segdsp/tools"
+	"math"
+	"time"
+)
+
+const (
+	AveragingExponential = "exponential"
+	AveragingLinear      = "linear"
+)
+
+// hqFFTMaxRatio limits the high quality FFT length to this many times the number of output bins
+const hqFFTMaxRatio = 16
+
+// fftPlan holds the window and the input buffer of one FFT length
+type fftPlan struct {
+	window []float32
+	input  []complex64
+	scale  float32 // Power scale, includes the window noise gain and the FFT length corrections
+}
+
+// FFTEngine computes the averaged spectrum of a single FFT stream. The windows and buffers of each FFT length are
+// built once and reused for the next frames.
+type FFTEngine struct {
+	cfg         config.FFTWindowSetting
+	log         *slog.Instance
+	sampleRate  float32
+	highQuality bool
+	interval    time.Duration
+	lastFrame   time.Time
+
+	plans     map[int]*fftPlan
+	maxLength int
+
+	average []float32   // Averaged frame, before smoothing
+	history [][]float32 // Last AveragingFrames frames for the linear average
+	frames  int
+}
+
+// MakeFFTEngine builds an engine for the stream settings. Unset settings use the defaults, an invalid window type
+// falls back to the default window.
+func MakeFFTEngine(name string, cfg config.FFTWindowSetting, sampleRate float32, highQuality bool) *FFTEngine {
+	e := &FFTEngine{
+		log:         slog.Scope("FFT " + name),
+		sampleRate:  sampleRate,
+		highQuality: highQuality,
+		lastFrame:   time.Now(),
+		plans:       map[int]*fftPlan{},
+	}
+
+	if cfg.Size <= 0 {
+		cfg.Size = config.DefaultFFTSize
+	}
+	if !tools.IsPowerOf2(cfg.Size) {
+		size := 1
+		for size < cfg.Size {
+			size <<= 1
+		}
+		e.log.Warn("FFT size %d is not a power of two, using %d", cfg.Size, size)
+		cfg.Size = size
+	}
+	if cfg.FPS <= 0 {
+		cfg.FPS = config.DefaultFFTFPS
+	}
+	if cfg.Window == "" {
+		cfg.Window = config.DefaultFFTWindow
+	}
+	if _, err := MakeWindow(cfg.Window, cfg.Size); err != nil {
+		e.log.Error("%s, using %s", err, config.DefaultFFTWindow)
+		cfg.Window = config.DefaultFFTWindow
+	}
+	if cfg.Averaging != AveragingExponential && cfg.Averaging != AveragingLinear {
+		if cfg.Averaging != "" {
+			e.log.Error("Unknown averaging %q, using %s", cfg.Averaging, config.DefaultFFTAveraging)
+		}
+		cfg.Averaging = config.DefaultFFTAveraging
+	}
+	if cfg.AveragingFrames <= 0 {
+		cfg.AveragingFrames = config.DefaultFFTAveragingN
+	}
+	cfg.Smoothing = math.Min(math.Max(cfg.Smoothing, 0), 0.99)
+
+	e.cfg = cfg
+	e.interval = time.Duration(float64(time.Second) / cfg.FPS)
+	e.average = make([]float32, cfg.Size)
+
+	e.maxLength = cfg.Size
+	if highQuality {
+		e.maxLength *= hqFFTMaxRatio
+	}
+
+	if cfg.Averaging == AveragingLinear {
+		e.history = make([][]float32, cfg.AveragingFrames)
+		for i := range e.history {
+			e.history[i] = make([]float32, cfg.Size)
+		}
+	}
+
+	e.log.Debug("%d bins at %.1f FPS, %s window, %s averaging over %d frames", cfg.Size, cfg.FPS, cfg.Window, cfg.Averaging, cfg.AveragingFrames)
+
+	return e
+}
+
+// Due returns true and starts a new frame period when the frame interval has elapsed
+func (e *FFTEngine) Due() bool {
+	if time.Since(e.lastFrame) < e.interval {
+		return false
+	}
+
+	e.lastFrame = time.Now()
+
+	return true
+}
+
+// MaxLength returns the number of samples used by the longest FFT
+func (e *FFTEngine) MaxLength() int {
+	return e.maxLength
+}
+
+// length returns the FFT length used for n samples, or 0 if there are not enough samples for a frame
+func (e *FFTEngine) length(n int) int {
+	size := e.cfg.Size
+	if n < size {
+		return 0
+	}
+
+	if !e.highQuality {
+		return size
+	}
+
+	length := size
+	for length*2 <= n && length*2 <= e.maxLength {
+		length *= 2
+	}
+
+	return length
+}
+
+func (e *FFTEngine) plan(length int) *fftPlan {
+	p, ok := e.plans[length]
+	if ok {
+		return p
+	}
+
+	window, _ := MakeWindow(e.cfg.Window, length)
+
+	// The noise power per bin grows with the FFT length, so longer FFTs are scaled down to the output size
+	scale := hannNoiseGain / windowNoiseGain(window) / float64(e.sampleRate) * float64(e.cfg.Size) / float64(length)
+
+	p = &fftPlan{
+		window: make([]float32, length),
+		input:  make([]complex64, length),
+		scale:  float32(scale),
+	}
+
+	for i, v := range window {
+		p.window[i] = float32(v)
+	}
+
+	fft.EnsureRadix2Factors(length)
+	e.plans[length] = p
+
+	return p
+}
+
+// Compute returns the averaged spectrum of the last samples in dB, with the center frequency in the middle.
+// It returns nil when there are less samples than the FFT size.
+func (e *FFTEngine) Compute(samples []complex64) []float32 {
+	length := e.length(len(samples))
+	if length == 0 {
+		return nil
+	}
+
+	samples = samples[len(samples)-length:]
+
+	p := e.plan(length)
+	for i, w := range p.window {
+		v := samples[i]
+		p.input[i] = complex(real(v)*w, imag(v)*w)
+	}
+
+	fftCData := fft.FFT(p.input)
+
+	size := e.cfg.Size
+	nDiv := length / size
+	frame := make([]float32, size)
+
+	for i := 0; i < size; i++ {
+		// Average the power of the nDiv FFT bins of each output bin, then convert it to dB
+		v := float32(0)
+		for _, c := range fftCData[i*nDiv : (i+1)*nDiv] {
+			v += tools.ComplexAbsSquared(c)
+		}
+		m := math.Max(float64(v*p.scale/float32(nDiv)), 1e-20)
+
+		// Put in the right output place
+		frame[(i+size/2)%size] = float32(10 * math.Log10(m))
+	}
+
+	e.accumulate(frame)
+
+	out := make([]float32, size)
+	copy(out, e.average)
+
+	if e.cfg.Smoothing > 0 {
+		s := float32(e.cfg.Smoothing)
+		for i := 1; i < size; i++ {
+			out[i] = out[i-1]*s + out[i]*(1-s)
+		}
+	}
+
+	return out
+}
+
+// accumulate adds a frame to the average
+func (e *FFTEngine) accumulate(frame []float32) {
+	n := e.cfg.AveragingFrames
+
+	if e.cfg.Averaging == AveragingLinear {
+		copy(e.history[e.frames%n], frame)
+		e.frames++
+
+		count := e.frames
+		if count > n {
+			count = n
+		}
+
+		for i := range e.average {
+			sum := float32(0)
+			for _, h := range e.history[:count] {
+				sum += h[i]
+			}
+			e.average[i] = sum / float32(count)
+		}
+
+		return
+	}
+
+	if e.frames == 0 {
+		copy(e.average, frame)
+	} else {
+		for i, v := range frame {
+			e.average[i] = (e.average[i]*float32(n-1) + v) / float32(n)
+		}
+	}
+
+	e.frames++
+}
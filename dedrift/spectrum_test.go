@@ -0,0 +1,202 @@
+package dedrift
+
+import (
+	"github.com/racerxdl/qo100-dedrift/config"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func testFFTSettings(size int, window string) config.FFTWindowSetting {
+	return config.FFTWindowSetting{
+		Size:            size,
+		FPS:             10,
+		Window:          window,
+		Averaging:       AveragingLinear,
+		AveragingFrames: 1,
+	}
+}
+
+func noise(n int, seed int64) []complex64 {
+	r := rand.New(rand.NewSource(seed))
+	samples := make([]complex64, n)
+	for i := range samples {
+		samples[i] = complex(float32(r.NormFloat64()), float32(r.NormFloat64()))
+	}
+
+	return samples
+}
+
+func peakBin(spectrum []float32) int {
+	peak := 0
+	for i, v := range spectrum {
+		if v > spectrum[peak] {
+			peak = i
+		}
+	}
+
+	return peak
+}
+
+func TestFFTEngineTone(t *testing.T) {
+	size := 1024
+	e := MakeFFTEngine("test", testFFTSettings(size, WindowHann), 1e6, false)
+
+	if e.Compute(make([]complex64, size-1)) != nil {
+		t.Errorf("expected no spectrum with less samples than the FFT size")
+	}
+
+	// Bin k above the center is at output index size/2 + k
+	for _, k := range []int{-300, 0, 100} {
+		spectrum := e.Compute(tone(size, 0, float64(k)/float64(size)))
+		if len(spectrum) != size {
+			t.Fatalf("expected %d bins, got %d", size, len(spectrum))
+		}
+		if peak := peakBin(spectrum); peak != size/2+k {
+			t.Errorf("expected the tone at bin %d, got %d", size/2+k, peak)
+		}
+	}
+}
+
+func TestFFTEngineHighQuality(t *testing.T) {
+	size := 256
+	e := MakeFFTEngine("test", testFFTSettings(size, WindowHann), 1e6, true)
+
+	if e.MaxLength() != size*hqFFTMaxRatio {
+		t.Errorf("expected a max length of %d, got %d", size*hqFFTMaxRatio, e.MaxLength())
+	}
+
+	if l := e.length(size*4 + 10); l != size*4 {
+		t.Errorf("expected a %d FFT, got %d", size*4, l)
+	}
+	if l := e.length(size * 100); l != size*hqFFTMaxRatio {
+		t.Errorf("expected a %d FFT, got %d", size*hqFFTMaxRatio, l)
+	}
+
+	spectrum := e.Compute(tone(size*8, 0, 10.0/float64(size)))
+	if len(spectrum) != size || peakBin(spectrum) != size/2+10 {
+		t.Errorf("expected %d bins with the tone at bin %d, got %d bins with the peak at %d", size, size/2+10, len(spectrum), peakBin(spectrum))
+	}
+}
+
+func TestFFTEngineNoiseFloor(t *testing.T) {
+	// The noise floor should not move with the window
+	size := 1024
+	samples := noise(size*64, 1)
+
+	floor := map[string]float64{}
+	for _, window := range []string{WindowHann, WindowHamming, WindowBlackmanHarris, WindowFlatTop} {
+		settings := testFFTSettings(size, window)
+		settings.AveragingFrames = 64
+		e := MakeFFTEngine("test", settings, 1e6, false)
+
+		var spectrum []float32
+		for i := 0; i < 64; i++ {
+			spectrum = e.Compute(samples[i*size : (i+1)*size])
+		}
+
+		sum := 0.0
+		for _, v := range spectrum {
+			sum += float64(v)
+		}
+		floor[window] = sum / float64(size)
+	}
+
+	for window, v := range floor {
+		if math.Abs(v-floor[WindowHann]) > 0.5 {
+			t.Errorf("expected the %s noise floor within 0.5 dB of the hann one (%f dB), got %f dB", window, floor[WindowHann], v)
+		}
+	}
+}
+
+func TestFFTEngineLinearAveraging(t *testing.T) {
+	size := 64
+	settings := testFFTSettings(size, WindowHann)
+	settings.AveragingFrames = 2
+	e := MakeFFTEngine("test", settings, 1e6, false)
+
+	a := e.Compute(tone(size, 0, 5.0/float64(size)))
+	b := e.Compute(tone(size, 0, -5.0/float64(size)))
+	c := e.Compute(tone(size, 0, -5.0/float64(size)))
+
+	// The second frame averages both tones, the third only has the last two frames
+	if b[size/2+5] >= a[size/2+5] || math.Abs(float64(b[size/2-5]-b[size/2+5])) > 1e-3 {
+		t.Errorf("expected both tones averaged, got %f and %f dB", b[size/2-5], b[size/2+5])
+	}
+	if c[size/2+5] >= b[size/2+5] {
+		t.Errorf("expected the first tone to leave the average, got %f dB", c[size/2+5])
+	}
+}
+
+func TestFFTEngineDefaults(t *testing.T) {
+	e := MakeFFTEngine("test", config.FFTWindowSetting{Size: 1000, Window: "square", Averaging: "median"}, 1e6, false)
+
+	if e.cfg.Size != 1024 {
+		t.Errorf("expected the size rounded to 1024, got %d", e.cfg.Size)
+	}
+	if e.cfg.Window != config.DefaultFFTWindow || e.cfg.Averaging != config.DefaultFFTAveraging {
+		t.Errorf("expected the default window and averaging, got %s and %s", e.cfg.Window, e.cfg.Averaging)
+	}
+	if e.cfg.FPS != config.DefaultFFTFPS || e.cfg.AveragingFrames != config.DefaultFFTAveragingN {
+		t.Errorf("expected the default FPS and averaging frames, got %f and %d", e.cfg.FPS, e.cfg.AveragingFrames)
+	}
+}
+
+func TestMakeWindow(t *testing.T) {
+	for _, window := range []string{WindowHann, WindowHamming, WindowBlackmanHarris, WindowFlatTop} {
+		taps, err := MakeWindow(window, 257)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(taps) != 257 {
+			t.Errorf("%s: expected 257 taps, got %d", window, len(taps))
+		}
+
+		// Symmetric, with the peak in the middle
+		for i := range taps {
+			if math.Abs(taps[i]-taps[len(taps)-1-i]) > 1e-9 || taps[i] > taps[128]+1e-9 {
+				t.Errorf("%s: expected a symmetric window peaking at the center", window)
+				break
+			}
+		}
+	}
+
+	if taps, _ := MakeWindow(WindowHann, 1024); math.Abs(windowNoiseGain(taps)-hannNoiseGain) > 1e-3 {
+		t.Errorf("expected the hann noise gain to be %f, got %f", hannNoiseGain, windowNoiseGain(taps))
+	}
+
+	if _, err := MakeWindow("square", 16); err == nil {
+		t.Errorf("expected an error with an unknown window")
+	}
+}
+
+func TestFFTEngineHighQualityNoiseFloor(t *testing.T) {
+	// The high quality FFT averages the power of nDiv bins into each output bin, so its noise floor should match
+	// the one of a plain FFT of the output size
+	size := 256
+	nDiv := 16
+	samples := noise(size*nDiv, 2)
+
+	linearMean := func(spectrum []float32) float64 {
+		sum := 0.0
+		for _, v := range spectrum {
+			sum += math.Pow(10, float64(v)/10)
+		}
+		return sum / float64(len(spectrum))
+	}
+
+	hq := MakeFFTEngine("test", testFFTSettings(size, WindowHann), 1e6, true)
+	hqFloor := 10 * math.Log10(linearMean(hq.Compute(samples)))
+
+	plain := MakeFFTEngine("test", testFFTSettings(size, WindowHann), 1e6, false)
+	sum := 0.0
+	for i := 0; i < nDiv; i++ {
+		sum += linearMean(plain.Compute(samples[i*size : (i+1)*size]))
+	}
+	plainFloor := 10 * math.Log10(sum/float64(nDiv))
+
+	if math.Abs(hqFloor-plainFloor) > 0.5 {
+		t.Errorf("expected the high quality noise floor within 0.5 dB of %f dB, got %f dB", plainFloor, hqFloor)
+	}
+}
@@ -0,0 +1,81 @@
+package dedrift
+
+import (
+	"github.com/racerxdl/qo100-dedrift/config"
+)
+
+// SpectrumWorker computes the segment and full band FFTs out of the DSP loop. Each stream has its own FrameWorker,
+// so the segment FFT is not delayed by a long full band FFT.
+type SpectrumWorker struct {
+	segSampleRate float32
+	sampleRate    float32
+
+	segFFT  *FFTEngine
+	fullFFT *FFTEngine
+	seg     *FrameWorker
+	full    *FrameWorker
+
+	onFFT OnFFT
+}
+
+func MakeSpectrumWorker(segSampleRate, sampleRate float32) *SpectrumWorker {
+	w := &SpectrumWorker{
+		segSampleRate: segSampleRate,
+		sampleRate:    sampleRate,
+	}
+
+	w.SetSettings(config.WebSettings{HighQualityFFT: config.DefaultFFTHighQuality})
+
+	return w
+}
+
+// SetSettings rebuilds the segment and full band FFT engines from the web settings. It must be called before Start.
+func (w *SpectrumWorker) SetSettings(settings config.WebSettings) {
+	w.segFFT = MakeFFTEngine("Segment", settings.SegFFT, w.segSampleRate, settings.HighQualityFFT)
+	w.fullFFT = MakeFFTEngine("Full", settings.FullFFT, w.sampleRate, settings.HighQualityFFT)
+	w.seg = MakeFrameWorker(w.segFFT.MaxLength(), w.processSeg)
+	w.full = MakeFrameWorker(w.fullFFT.MaxLength(), w.processFull)
+}
+
+// SetOnFFT sets the callback that receives the FFTs. It runs in the worker goroutine of each stream.
+// Each stream has its own frame rate, the FFT of the other stream is nil.
+func (w *SpectrumWorker) SetOnFFT(cb OnFFT) {
+	w.onFFT = cb
+}
+
+func (w *SpectrumWorker) Start() {
+	w.seg.Start()
+	w.full.Start()
+}
+
+func (w *SpectrumWorker) Stop() {
+	w.seg.Stop()
+	w.full.Stop()
+}
+
+// Work keeps the last samples of both streams and copies them when a frame is due. It never blocks.
+func (w *SpectrumWorker) Work(seg, full []complex64) {
+	if w.onFFT == nil {
+		return
+	}
+
+	w.seg.Work(seg, w.segFFT.Due())
+	w.full.Work(full, w.fullFFT.Due())
+}
+
+func (w *SpectrumWorker) processSeg(frame []complex64) {
+	if fft := w.segFFT.Compute(frame); fft != nil {
+		w.onFFT(fft, nil)
+	}
+}
+
+func (w *SpectrumWorker) processFull(frame []complex64) {
+	if fft := w.fullFFT.Compute(frame); fft != nil {
+		w.onFFT(nil, fft)
+	}
+}
+
+// GetDroppedFrames returns the number of frames skipped because the workers were busy
+func (w *SpectrumWorker) GetDroppedFrames() int {
+	return w.seg.GetDroppedFrames() + w.full.GetDroppedFrames()
+}
@@ -0,0 +1,104 @@
+package dedrift
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"time"
+)
+
+// stateOffsetTolerance is the maximum difference in Hertz between the saved and the current beacon offset for a state
+// to be used
+const stateOffsetTolerance = 1
+
+// State is the loop state persisted between restarts to shorten the time to lock
+type State struct {
+	Time          time.Time `json:"time"`
+	BeaconOffset  float32   `json:"beaconOffset"`
+	Drift         float32   `json:"drift"` // Drift in Hertz
+	Locked        bool      `json:"locked"`
+	Gear          int       `json:"gear"`
+	LoopBandwidth float32   `json:"loopBandwidth"`
+	ResamplerPPM  float64   `json:"resamplerPPM"`
+}
+
+// LoadState reads a State saved by SaveState
+func LoadState(filename string) (State, error) {
+	var s State
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return s, err
+	}
+
+	err = json.Unmarshal(data, &s)
+	return s, err
+}
+
+// SaveState writes the state to a temporary file and renames it over filename, so a crash never leaves a partial file
+func SaveState(filename string, s State) error {
+	data, err := json.MarshalIndent(s, "", "   ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filename + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filename)
+}
+
+// GetState returns the current loop state. It is safe to be called from any goroutine.
+func (d *Dedrifter) GetState() State {
+	status := d.GetStatus()
+
+	return State{
+		Time:          time.Now(),
+		BeaconOffset:  status.BeaconOffset,
+		Drift:         status.Drift,
+		Locked:        status.Locked,
+		Gear:          status.Gear,
+		LoopBandwidth: status.LoopBandwidth,
+		ResamplerPPM:  status.ResamplerPPM,
+	}
+}
+
+// Seed starts the loop from a saved state. The drift is held as the correction until the loop locks,
+// the same way as after a retune. States saved without lock only seed the resampler. States saved with another
+// beacon offset are ignored, since their drift was measured around another frequency.
+// It should be called before the first Process call.
+func (d *Dedrifter) Seed(s State) {
+	if math.Abs(float64(s.BeaconOffset-d.cfg.BeaconOffset)) > stateOffsetTolerance {
+		d.log.Info("State saved at %s has beacon offset %f Hz, current is %f Hz. Not seeding the loop.", s.Time.Format(time.RFC3339), s.BeaconOffset, d.cfg.BeaconOffset)
+		return
+	}
+
+	if d.resampler != nil && s.ResamplerPPM != 0 {
+		d.resampler.SetPPM(s.ResamplerPPM)
+	}
+
+	if !s.Locked {
+		d.log.Info("State saved at %s was not locked. Not seeding the loop.", s.Time.Format(time.RFC3339))
+		d.updateStatus()
+		return
+	}
+
+	d.log.Info("Seeding loop with %f Hz drift saved at %s", s.Drift, s.Time.Format(time.RFC3339))
+
+	frequency := s.Drift * TwoPi / d.segSampleRate
+	d.costas.SetFrequency(frequency)
+	d.heldFrequency = frequency
+	d.holdCorrection = true
+	d.holdStarted = time.Now()
+
+	// Like in a retune, do not start narrower than the intermediate gear so the loop can still pull in
+	if d.gearShifter != nil && s.Gear > 0 {
+		d.gearShifter.SetGear(1)
+	}
+
+	d.updateStatus()
+}
@@ -0,0 +1,71 @@
+package dedrift
+
+import (
+	"github.com/racerxdl/qo100-dedrift/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qo100-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "state.json")
+	saved := State{
+		Time:         time.Now().Round(time.Second),
+		BeaconOffset: 143e3,
+		Drift:        -1234.5,
+		Locked:       true,
+		Gear:         2,
+	}
+
+	err = SaveState(filename, saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadState(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !loaded.Time.Equal(saved.Time) || loaded.Drift != saved.Drift || loaded.BeaconOffset != saved.BeaconOffset || loaded.Gear != saved.Gear {
+		t.Errorf("expected %+v, got %+v", saved, loaded)
+	}
+}
+
+func TestSeedChecksBeaconOffset(t *testing.T) {
+	cfg := config.DefaultConfig.Processing
+
+	state := State{
+		Time:         time.Now(),
+		BeaconOffset: cfg.BeaconOffset + 10e3,
+		Drift:        500,
+		Locked:       true,
+	}
+
+	d, err := MakeDedrifter(cfg, config.DefaultSampleRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Seed(state)
+	if d.GetStatus().HoldingCorrection {
+		t.Errorf("expected a state with another beacon offset to be ignored")
+	}
+
+	state.BeaconOffset = cfg.BeaconOffset
+	d, err = MakeDedrifter(cfg, config.DefaultSampleRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Seed(state)
+	if !d.GetStatus().HoldingCorrection {
+		t.Errorf("expected the loop to hold the saved drift")
+	}
+}
@@ -0,0 +1,62 @@
+package dedrift
+
+import (
+	"fmt"
+	"github.com/racerxdl/segdsp/dsp"
+	"math"
+)
+
+const (
+	WindowHann           = "hann"
+	WindowHamming        = "hamming"
+	WindowBlackmanHarris = "blackman-harris"
+	WindowFlatTop        = "flat-top"
+)
+
+// hannNoiseGain is the mean of the squared Hann window. The spectrum power is scaled by it over the noise gain of
+// the selected window, so the noise floor stays at the same level whatever window is used.
+const hannNoiseGain = 0.375
+
+// MakeWindow returns a window of n taps of the given type
+func MakeWindow(windowType string, n int) ([]float64, error) {
+	switch windowType {
+	case WindowHann:
+		// segdsp HammingWindow computes the 0.5 / 0.5 cosine, which is a Hann window
+		return dsp.HammingWindow(n), nil
+	case WindowHamming:
+		return cosineWindow(n, 0.54, 0.46), nil
+	case WindowBlackmanHarris:
+		return dsp.BlackmanHarris(n, 92), nil
+	case WindowFlatTop:
+		return cosineWindow(n, 0.21557895, 0.41663158, 0.277263158, 0.083578947, 0.006947368), nil
+	}
+
+	return nil, fmt.Errorf("unknown window type %q", windowType)
+}
+
+// cosineWindow returns the generalized cosine window w[i] = c0 - c1 cos(x) + c2 cos(2x) - ...
+func cosineWindow(n int, coefficients ...float64) []float64 {
+	taps := make([]float64, n)
+	m := float64(n - 1)
+
+	for i := range taps {
+		x := 2 * math.Pi * float64(i) / m
+		sign := 1.0
+		for k, c := range coefficients {
+			taps[i] += sign * c * math.Cos(float64(k)*x)
+			sign = -sign
+		}
+	}
+
+	return taps
+}
+
+// windowNoiseGain returns the mean of the squared window taps
+func windowNoiseGain(window []float64) float64 {
+	sum := 0.0
+	for _, v := range window {
+		sum += v * v
+	}
+
+	return sum / float64(len(window))
+}
@@ -0,0 +1,253 @@
+package driftlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/quan-to/slog"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dayLayout = "20060102"
+
+// Logger writes drift records to daily rotated files named <name>-<YYYYMMDD>.<format>
+type Logger struct {
+	sync.Mutex
+	name      string
+	directory string
+	format    string
+	maxDays   int
+	log       *slog.Instance
+	pattern   *regexp.Regexp // Matches the file names of this logger only, not of pipelines named <name>-<suffix>
+	file      *os.File
+	fileDay   string
+}
+
+func MakeLogger(name string, cfg config.DriftLogConfig) (*Logger, error) {
+	if cfg.Format != FormatCSV && cfg.Format != FormatJSONL {
+		return nil, fmt.Errorf("invalid drift log format %q", cfg.Format)
+	}
+
+	err := os.MkdirAll(cfg.Directory, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Logger{
+		name:      name,
+		directory: cfg.Directory,
+		format:    cfg.Format,
+		maxDays:   cfg.MaxDays,
+		log:       slog.Scope("Drift Log " + name),
+		pattern:   regexp.MustCompile(fmt.Sprintf(`^%s-\d{8}\.%s$`, regexp.QuoteMeta(name), regexp.QuoteMeta(cfg.Format))),
+	}, nil
+}
+
+func (l *Logger) filename(day string) string {
+	return filepath.Join(l.directory, fmt.Sprintf("%s-%s.%s", l.name, day, l.format))
+}
+
+// files returns the log files of this logger sorted by day
+func (l *Logger) files() ([]string, error) {
+	entries, err := ioutil.ReadDir(l.directory)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0)
+	for _, e := range entries {
+		if !e.IsDir() && l.pattern.MatchString(e.Name()) {
+			files = append(files, filepath.Join(l.directory, e.Name()))
+		}
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func (l *Logger) fileDayOf(filename string) string {
+	base := filepath.Base(filename)
+	return strings.TrimSuffix(strings.TrimPrefix(base, l.name+"-"), "."+l.format)
+}
+
+func (l *Logger) rotate(day string) error {
+	if l.file != nil {
+		_ = l.file.Close()
+		l.file = nil
+	}
+
+	filename := l.filename(day)
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if stat.Size() == 0 && l.format == FormatCSV {
+		_, err = f.WriteString(csvHeader + "\n")
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+
+	l.log.Info("Logging drift to %s", filename)
+	l.file = f
+	l.fileDay = day
+	l.cleanup()
+
+	return nil
+}
+
+// cleanup removes the files older than MaxDays
+func (l *Logger) cleanup() {
+	if l.maxDays <= 0 {
+		return
+	}
+
+	files, err := l.files()
+	if err != nil {
+		l.log.Error("Error listing drift log files: %s", err)
+		return
+	}
+
+	oldest := time.Now().UTC().AddDate(0, 0, -l.maxDays).Format(dayLayout)
+	for _, f := range files {
+		if l.fileDayOf(f) < oldest {
+			l.log.Info("Removing old drift log %s", f)
+			err = os.Remove(f)
+			if err != nil {
+				l.log.Error("Error removing %s: %s", f, err)
+			}
+		}
+	}
+}
+
+// Log appends a record, rotating the file when the day changes
+func (l *Logger) Log(r Record) error {
+	l.Lock()
+	defer l.Unlock()
+
+	day := r.Time.UTC().Format(dayLayout)
+	if l.file == nil || day != l.fileDay {
+		err := l.rotate(day)
+		if err != nil {
+			return err
+		}
+	}
+
+	var line string
+
+	switch l.format {
+	case FormatCSV:
+		line = r.csvLine()
+	case FormatJSONL:
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		line = string(data) + "\n"
+	}
+
+	_, err := l.file.WriteString(line)
+	return err
+}
+
+// Read returns the records logged since the specified time
+func (l *Logger) Read(since time.Time) ([]Record, error) {
+	files, err := l.files()
+	if err != nil {
+		return nil, err
+	}
+
+	sinceDay := since.UTC().Format(dayLayout)
+	records := make([]Record, 0)
+
+	for _, f := range files {
+		if l.fileDayOf(f) < sinceDay {
+			continue
+		}
+
+		fileRecords, err := ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range fileRecords {
+			if !r.Time.Before(since) {
+				records = append(records, r)
+			}
+		}
+	}
+
+	return records, nil
+}
+
+// ReadLast returns the records logged in the last hours
+func (l *Logger) ReadLast(hours float64) ([]Record, error) {
+	return l.Read(time.Now().Add(-time.Duration(hours * float64(time.Hour))))
+}
+
+// ParseHours reads the hours query parameter of the request, limited to maxHours. It defaults to maxHours.
+func ParseHours(r *http.Request, maxHours float64) (float64, error) {
+	v := r.URL.Query().Get("hours")
+	if v == "" {
+		return maxHours, nil
+	}
+
+	hours, err := strconv.ParseFloat(v, 64)
+	if err != nil || hours <= 0 {
+		return 0, fmt.Errorf("invalid hours %q", v)
+	}
+
+	return math.Min(hours, maxHours), nil
+}
+
+// Handler serves the records of the last N hours, specified by the hours query parameter, up to maxHours
+func (l *Logger) Handler(maxHours float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hours, err := ParseHours(r, maxHours)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		records, err := l.ReadLast(hours)
+		if err != nil {
+			l.log.Error("Error reading drift log: %s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		data, _ := json.Marshal(records)
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(200)
+		_, _ = w.Write(data)
+	}
+}
+
+func (l *Logger) Close() {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.file != nil {
+		_ = l.file.Close()
+		l.file = nil
+	}
+}
@@ -0,0 +1,89 @@
+package driftlog
+
+import (
+	"github.com/racerxdl/qo100-dedrift/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func makeTestLogger(t *testing.T, name, directory string, maxDays int) *Logger {
+	l, err := MakeLogger(name, config.DriftLogConfig{
+		Directory: directory,
+		Format:    FormatCSV,
+		MaxDays:   maxDays,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return l
+}
+
+func TestLoggerIgnoresOtherPipelines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qo100-driftlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now().UTC()
+	old := now.AddDate(0, 0, -10).Format(dayLayout)
+
+	// Files of the pipeline "main-2" and other files sharing the "main-" prefix
+	others := []string{
+		"main-2-" + old + ".csv",
+		"main-2-" + now.Format(dayLayout) + ".csv",
+		"main-backup.csv",
+		"main-" + old + ".csv.bak",
+	}
+	for _, f := range others {
+		err = ioutil.WriteFile(filepath.Join(dir, f), []byte(csvHeader+"\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldFile := filepath.Join(dir, "main-"+old+".csv")
+	err = ioutil.WriteFile(oldFile, []byte(csvHeader+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	main := makeTestLogger(t, "main", dir, 5)
+	defer main.Close()
+
+	other := makeTestLogger(t, "main-2", dir, 0)
+	defer other.Close()
+
+	err = other.Log(Record{Time: now, Drift: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = main.Log(Record{Time: now, Drift: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(oldFile); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed", oldFile)
+	}
+
+	for _, f := range others {
+		if _, err := os.Stat(filepath.Join(dir, f)); err != nil {
+			t.Errorf("expected %s to be kept: %s", f, err)
+		}
+	}
+
+	records, err := main.ReadLast(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 || records[0].Drift != 100 {
+		t.Errorf("expected only the main record, got %+v", records)
+	}
+}
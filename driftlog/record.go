@@ -0,0 +1,148 @@
+package driftlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+var csvHeader = "time,drift,ppm,locked,snr,temperature"
+
+// Record is a single drift log entry
+type Record struct {
+	Time        time.Time `json:"time"`
+	Drift       float64   `json:"drift"` // Drift in Hertz
+	PPM         float64   `json:"ppm"`
+	Locked      bool      `json:"locked"`
+	SNR         float64   `json:"snr"`                   // Beacon SNR in dB
+	Temperature *float64  `json:"temperature,omitempty"` // Temperature in Celsius, if available
+}
+
+func (r Record) csvLine() string {
+	temperature := ""
+	if r.Temperature != nil {
+		temperature = strconv.FormatFloat(*r.Temperature, 'f', 3, 64)
+	}
+
+	return fmt.Sprintf("%s,%s,%s,%t,%s,%s\n",
+		r.Time.UTC().Format(time.RFC3339Nano),
+		strconv.FormatFloat(r.Drift, 'f', 3, 64),
+		strconv.FormatFloat(r.PPM, 'f', 6, 64),
+		r.Locked,
+		strconv.FormatFloat(r.SNR, 'f', 2, 64),
+		temperature)
+}
+
+func parseCSVLine(line string) (Record, error) {
+	var r Record
+	var err error
+
+	fields := strings.Split(line, ",")
+	if len(fields) != 6 {
+		return r, fmt.Errorf("expected 6 fields, got %d", len(fields))
+	}
+
+	r.Time, err = time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return r, err
+	}
+
+	if r.Drift, err = strconv.ParseFloat(fields[1], 64); err != nil {
+		return r, err
+	}
+
+	if r.PPM, err = strconv.ParseFloat(fields[2], 64); err != nil {
+		return r, err
+	}
+
+	if r.Locked, err = strconv.ParseBool(fields[3]); err != nil {
+		return r, err
+	}
+
+	if r.SNR, err = strconv.ParseFloat(fields[4], 64); err != nil {
+		return r, err
+	}
+
+	if fields[5] != "" {
+		temperature, err := strconv.ParseFloat(fields[5], 64)
+		if err != nil {
+			return r, err
+		}
+		r.Temperature = &temperature
+	}
+
+	return r, nil
+}
+
+// FormatOf returns the log format from the file extension
+func FormatOf(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case "." + FormatCSV:
+		return FormatCSV, nil
+	case "." + FormatJSONL, ".json":
+		return FormatJSONL, nil
+	}
+
+	return "", fmt.Errorf("unknown drift log format for %s", filename)
+}
+
+// Parse reads the records in the specified format. Lines that cannot be parsed (like a partially written last line) are skipped.
+func Parse(r io.Reader, format string) ([]Record, error) {
+	records := make([]Record, 0)
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line == csvHeader {
+			continue
+		}
+
+		var record Record
+		var err error
+
+		switch format {
+		case FormatCSV:
+			record, err = parseCSVLine(line)
+		case FormatJSONL:
+			err = json.Unmarshal([]byte(line), &record)
+		default:
+			return nil, fmt.Errorf("invalid drift log format %q", format)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+// ReadFile reads all records of a drift log file. The format is taken from the file extension.
+func ReadFile(filename string) ([]Record, error) {
+	format, err := FormatOf(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return Parse(f, format)
+}
@@ -0,0 +1,101 @@
+package driftlog
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	temperature := 23.125
+	records := []Record{
+		{Time: time.Date(2026, 10, 19, 12, 0, 0, 500, time.UTC), Drift: -1234.5, PPM: -0.126615, Locked: true, SNR: 18.25, Temperature: &temperature},
+		{Time: time.Date(2026, 10, 19, 12, 0, 1, 0, time.UTC), Drift: 10, PPM: 0.001026, SNR: 3.5},
+	}
+
+	for _, r := range records {
+		parsed, err := parseCSVLine(strings.TrimSpace(r.csvLine()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !parsed.Time.Equal(r.Time) || parsed.Drift != r.Drift || parsed.PPM != r.PPM || parsed.Locked != r.Locked || parsed.SNR != r.SNR {
+			t.Errorf("expected %+v, got %+v", r, parsed)
+		}
+
+		if (r.Temperature == nil) != (parsed.Temperature == nil) || (r.Temperature != nil && *parsed.Temperature != *r.Temperature) {
+			t.Errorf("expected temperature %v, got %v", r.Temperature, parsed.Temperature)
+		}
+	}
+}
+
+func TestParseCSVLineErrors(t *testing.T) {
+	lines := []string{
+		"2026-10-19T12:00:00Z,1.000,0.000100,true,10.00",
+		"2026-10-19T12:00:00Z,1.000,0.000100,true,10.00,,",
+		"yesterday,1.000,0.000100,true,10.00,",
+		"2026-10-19T12:00:00Z,drift,0.000100,true,10.00,",
+		"2026-10-19T12:00:00Z,1.000,0.000100,maybe,10.00,",
+		"2026-10-19T12:00:00Z,1.000,0.000100,true,10.00,hot",
+	}
+
+	for _, line := range lines {
+		if _, err := parseCSVLine(line); err == nil {
+			t.Errorf("expected an error parsing %q", line)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	csv := csvHeader + "\n" +
+		"2026-10-19T12:00:00Z,1.000,0.000100,true,10.00,\n" +
+		"\n" +
+		"2026-10-19T12:00:01Z,2.000,0.000200,false,9.00,21.500\n" +
+		"2026-10-19T12:00:02Z,3.0" // Partially written last line
+
+	records, err := Parse(strings.NewReader(csv), FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 2 || records[0].Drift != 1 || records[1].Drift != 2 || *records[1].Temperature != 21.5 {
+		t.Errorf("expected the 2 complete records, got %+v", records)
+	}
+
+	jsonl := `{"time":"2026-10-19T12:00:00Z","drift":1,"ppm":0.0001,"locked":true,"snr":10}` + "\n" +
+		`{"time":"2026-10-19T12:00:01Z","drift":2,` + "\n"
+
+	records, err = Parse(strings.NewReader(jsonl), FormatJSONL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 || records[0].Drift != 1 || !records[0].Locked || records[0].Temperature != nil {
+		t.Errorf("expected the complete record, got %+v", records)
+	}
+
+	_, err = Parse(strings.NewReader(jsonl), "xml")
+	if err == nil {
+		t.Errorf("expected an error with an invalid format")
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	cases := map[string]string{
+		"main-20261019.csv":   FormatCSV,
+		"main-20261019.CSV":   FormatCSV,
+		"main-20261019.jsonl": FormatJSONL,
+		"drift.json":          FormatJSONL,
+	}
+
+	for filename, expected := range cases {
+		format, err := FormatOf(filename)
+		if err != nil || format != expected {
+			t.Errorf("%s: expected %s, got %s (%v)", filename, expected, format, err)
+		}
+	}
+
+	if _, err := FormatOf("main-20261019.txt"); err == nil {
+		t.Errorf("expected an error with an unknown extension")
+	}
+}
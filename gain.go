@@ -0,0 +1,170 @@
+package main
+
+import (
+	"github.com/quan-to/slog"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"github.com/racerxdl/qo100-dedrift/metrics"
+	"github.com/racerxdl/qo100-dedrift/rtltcp"
+	"math"
+	"time"
+)
+
+// GainController steps the upstream tuner gain to keep the RMS level of the raw samples inside the target range.
+// The target range is the hysteresis: nothing changes while the level is inside it, the gain is only raised when
+// the next step would not take the level over the range and it waits HoldTime after each change. Clipping lowers
+// the gain without waiting.
+type GainController struct {
+	name   string
+	cfg    config.AutoGainConfig
+	log    *slog.Instance
+	client *rtltcp.Client
+	gains  []float64 // dB of each step, lowest first
+	steps  []int     // Tuner gain table index of each step, when the tuner gains are known
+	index  int
+
+	samples     int
+	clipped     int
+	power       float64
+	windowStart time.Time
+	lastChange  time.Time
+}
+
+// MakeGainController builds the gain steps from the tuner gain table, keeping a spacing of at least Step dB.
+// When the tuner gains are not known, the steps are Step dB apart and the tuner picks its closest gain.
+func MakeGainController(name string, cfg config.AutoGainConfig, gain float64, table rtltcp.GainTable, client *rtltcp.Client) *GainController {
+	gc := &GainController{
+		name:        name,
+		cfg:         cfg,
+		log:         slog.Scope("Gain " + name),
+		client:      client,
+		windowStart: time.Now(),
+		lastChange:  time.Now(),
+	}
+
+	if table.Valid() {
+		for i, g := range table.Gains {
+			if g < cfg.MinGain || g > cfg.MaxGain {
+				continue
+			}
+			if len(gc.gains) > 0 && g-gc.gains[len(gc.gains)-1] < cfg.Step {
+				continue
+			}
+			gc.gains = append(gc.gains, g)
+			gc.steps = append(gc.steps, i)
+		}
+	} else {
+		if cfg.ByIndex {
+			gc.log.Warn("The gains of the %s tuner are not known, using SetGain", rtltcp.TunerTypeToName[table.Tuner])
+			gc.cfg.ByIndex = false
+		}
+		step := math.Max(cfg.Step, 0.1)
+		for g := cfg.MinGain; g <= cfg.MaxGain+1e-9; g += step {
+			gc.gains = append(gc.gains, g)
+		}
+	}
+
+	gc.index = gc.nearest(gain)
+
+	return gc
+}
+
+// nearest returns the index of the step closest to gain
+func (gc *GainController) nearest(gain float64) int {
+	best := 0
+	for i, g := range gc.gains {
+		if math.Abs(g-gain) < math.Abs(gc.gains[best]-gain) {
+			best = i
+		}
+	}
+	return best
+}
+
+// Gain returns the current gain in dB
+func (gc *GainController) Gain() float64 {
+	if len(gc.gains) == 0 {
+		return 0
+	}
+	return gc.gains[gc.index]
+}
+
+// Start puts the tuner in manual gain mode and applies the initial gain
+func (gc *GainController) Start() error {
+	if len(gc.gains) == 0 {
+		gc.log.Error("Empty gain range %.1f to %.1f dB", gc.cfg.MinGain, gc.cfg.MaxGain)
+		return nil
+	}
+
+	err := gc.client.SetGainMode(true)
+	if err != nil {
+		return err
+	}
+
+	gc.log.Info("Automatic gain control between %.1f and %.1f dB, target %.1f to %.1f dBFS", gc.gains[0], gc.gains[len(gc.gains)-1], gc.cfg.TargetLow, gc.cfg.TargetHigh)
+
+	return gc.apply()
+}
+
+func (gc *GainController) apply() error {
+	var err error
+	if gc.cfg.ByIndex {
+		err = gc.client.SetTunerGainByIndex(uint32(gc.steps[gc.index]))
+	} else {
+		err = gc.client.SetGain(uint32(math.Round(gc.Gain() * 10)))
+	}
+
+	return err
+}
+
+// Update accumulates the levels read from the client and decides on a gain change at each Interval
+func (gc *GainController) Update(levels rtltcp.Levels) {
+	if len(gc.gains) == 0 || levels.Samples == 0 {
+		return
+	}
+
+	gc.samples += levels.Samples
+	gc.clipped += levels.Clipped
+	gc.power += math.Pow(10, levels.RMS/10) * float64(levels.Samples)
+
+	if time.Since(gc.windowStart) < time.Duration(gc.cfg.Interval*float64(time.Second)) {
+		return
+	}
+
+	clipRate := float64(gc.clipped) / float64(gc.samples)
+	rms := 10 * math.Log10(math.Max(gc.power/float64(gc.samples), 1e-20))
+
+	gc.samples, gc.clipped, gc.power = 0, 0, 0
+	gc.windowStart = time.Now()
+
+	clipping := clipRate > gc.cfg.MaxClipRate
+	settled := time.Since(gc.lastChange) > time.Duration(gc.cfg.HoldTime*float64(time.Second))
+	next := gc.index
+
+	switch {
+	case clipping || (rms > gc.cfg.TargetHigh && settled):
+		next--
+	case rms < gc.cfg.TargetLow && settled && gc.index < len(gc.gains)-1:
+		if rms+gc.gains[gc.index+1]-gc.gains[gc.index] <= gc.cfg.TargetHigh {
+			next++
+		}
+	}
+
+	if next < 0 || next >= len(gc.gains) || next == gc.index {
+		if clipping && next < 0 {
+			gc.log.Warn("Clipping %.3f%% of the samples at the lowest gain", clipRate*100)
+		}
+		return
+	}
+
+	previous := gc.Gain()
+	gc.index = next
+	gc.lastChange = time.Now()
+
+	err := gc.apply()
+	if err != nil {
+		gc.log.Error("Error setting gain: %s", err)
+		return
+	}
+
+	gc.log.Info("Gain changed from %.1f to %.1f dB (RMS %.1f dBFS, %.3f%% clipped)", previous, gc.Gain(), rms, clipRate*100)
+	metrics.GainChanges.WithLabelValues(gc.name).Inc()
+}
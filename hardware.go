@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/binary"
+	"github.com/quan-to/slog"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"github.com/racerxdl/qo100-dedrift/dedrift"
+	"github.com/racerxdl/qo100-dedrift/metrics"
+	"github.com/racerxdl/qo100-dedrift/rtltcp"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	HardwareCorrectionFrequency = "frequency"
+	HardwareCorrectionPPM       = "ppm"
+)
+
+// HardwareCorrector makes the upstream tuner follow the drift, leaving only the residual to the digital correction.
+// Update runs in the DSP loop while the center frequency is changed by the rtl_tcp clients and rigctl.
+type HardwareCorrector struct {
+	sync.Mutex
+	name            string
+	cfg             config.HardwareCorrectionConfig
+	log             *slog.Instance
+	client          *rtltcp.Client
+	dedrifter       *dedrift.Dedrifter
+	centerFrequency uint32
+	offset          int64 // Frequency mode: Hertz added to the center frequency
+	ppm             int32 // PPM mode: current frequency correction
+	lastUpdate      time.Time
+}
+
+func MakeHardwareCorrector(name string, cfg config.HardwareCorrectionConfig, centerFrequency uint32, client *rtltcp.Client, dedrifter *dedrift.Dedrifter) *HardwareCorrector {
+	return &HardwareCorrector{
+		name:            name,
+		cfg:             cfg,
+		log:             slog.Scope("Hardware " + name),
+		client:          client,
+		dedrifter:       dedrifter,
+		centerFrequency: centerFrequency,
+		lastUpdate:      time.Now(),
+	}
+}
+
+func (hc *HardwareCorrector) tunedFrequency(centerFrequency uint32) uint32 {
+	if hc.cfg.Mode == HardwareCorrectionFrequency {
+		return uint32(int64(centerFrequency) + hc.offset)
+	}
+
+	return centerFrequency
+}
+
+// Correction returns the drift in Hertz currently corrected by the upstream tuner
+func (hc *HardwareCorrector) Correction() float64 {
+	hc.Lock()
+	defer hc.Unlock()
+
+	return hc.correction()
+}
+
+func (hc *HardwareCorrector) correction() float64 {
+	return float64(hc.offset) - float64(hc.ppm)*float64(hc.centerFrequency)/1e6
+}
+
+// totalDrift returns the drift measured on the beacon, including the part corrected by the upstream tuner.
+// Steps that were sent upstream but are not applied to the digital correction yet are still in the residual, so they
+// are not counted twice.
+func totalDrift(status dedrift.Status, hardware *HardwareCorrector) float32 {
+	if hardware == nil {
+		return status.Drift
+	}
+
+	return status.Drift + float32(hardware.Correction()) - status.PendingHardware
+}
+
+// Tune tunes the upstream to a new nominal center frequency, keeping the current correction. It holds the corrector
+// lock so Update never steps the upstream from the previous center frequency while it changes.
+func (hc *HardwareCorrector) Tune(centerFrequency uint32) error {
+	hc.Lock()
+	defer hc.Unlock()
+
+	err := hc.client.SetCenterFrequency(hc.tunedFrequency(centerFrequency))
+	if err != nil {
+		return err
+	}
+
+	hc.centerFrequency = centerFrequency
+
+	return nil
+}
+
+func (hc *HardwareCorrector) minStep() float64 {
+	step := hc.cfg.MinStep
+	if hc.cfg.Mode == HardwareCorrectionPPM {
+		ppmStep := float64(hc.centerFrequency) / 1e6
+		if ppmStep > step {
+			step = ppmStep
+		}
+	}
+
+	return step
+}
+
+// Update checks the residual digital correction and steps the upstream tuner when it goes over the hysteresis.
+// Steps that were sent but are not applied to the digital correction yet are not part of the residual.
+func (hc *HardwareCorrector) Update() {
+	if time.Since(hc.lastUpdate) < time.Duration(hc.cfg.Interval*float64(time.Second)) {
+		return
+	}
+
+	hc.lastUpdate = time.Now()
+
+	hc.Lock()
+	defer hc.Unlock()
+
+	status := hc.dedrifter.GetStatus()
+	if !status.Locked || status.HoldingCorrection {
+		return
+	}
+
+	residual := float64(status.Drift - status.PendingHardware)
+	if math.Abs(residual) < hc.cfg.Hysteresis {
+		return
+	}
+
+	minStep := hc.minStep()
+	step := residual
+	if minStep > 0 {
+		step = math.Round(residual/minStep) * minStep
+	}
+
+	if step == 0 {
+		return
+	}
+
+	var err error
+
+	switch hc.cfg.Mode {
+	case HardwareCorrectionFrequency:
+		hc.offset += int64(math.Round(step))
+		step = math.Round(step)
+		err = hc.client.SetCenterFrequency(hc.tunedFrequency(hc.centerFrequency))
+	case HardwareCorrectionPPM:
+		// A slow crystal makes the signals appear higher, which is corrected by a negative PPM
+		ppmStep := int32(math.Round(-step / float64(hc.centerFrequency) * 1e6))
+		hc.ppm += ppmStep
+		step = -float64(ppmStep) * float64(hc.centerFrequency) / 1e6
+		err = hc.setFrequencyCorrection(hc.ppm)
+	default:
+		hc.log.Error("Invalid hardware correction mode %q", hc.cfg.Mode)
+		return
+	}
+
+	if err != nil {
+		hc.log.Error("Error sending hardware correction: %s", err)
+		return
+	}
+
+	hc.log.Debug("Stepped upstream by %f Hz (residual was %f Hz)", step, residual)
+	hc.dedrifter.ApplyHardwareStep(float32(step), time.Duration(hc.cfg.Latency*float64(time.Second)))
+
+	metrics.HardwareCorrection.WithLabelValues(hc.name).Set(hc.correction())
+	metrics.HardwareSteps.WithLabelValues(hc.name).Inc()
+}
+
+func (hc *HardwareCorrector) setFrequencyCorrection(ppm int32) error {
+	buff := make([]byte, 4)
+	binary.BigEndian.PutUint32(buff, uint32(ppm))
+
+	return hc.client.SendCommand(rtltcp.Command{
+		Type:  rtltcp.SetFrequencyCorrection,
+		Param: [4]byte{buff[0], buff[1], buff[2], buff[3]},
+	})
+}
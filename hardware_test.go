@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/racerxdl/qo100-dedrift/config"
+	"github.com/racerxdl/qo100-dedrift/dedrift"
+	"testing"
+)
+
+func TestTotalDrift(t *testing.T) {
+	status := dedrift.Status{Drift: 120}
+
+	if drift := totalDrift(status, nil); drift != 120 {
+		t.Errorf("expected the residual without hardware correction, got %f", drift)
+	}
+
+	hc := &HardwareCorrector{
+		cfg:             config.HardwareCorrectionConfig{Mode: HardwareCorrectionFrequency},
+		centerFrequency: 740000000,
+		offset:          500,
+	}
+
+	if drift := totalDrift(status, hc); drift != 620 {
+		t.Errorf("expected 620 Hz, got %f", drift)
+	}
+
+	// A 100 Hz step sent upstream is still in the residual until it reaches the stream
+	status = dedrift.Status{Drift: 220, PendingHardware: 100}
+	if drift := totalDrift(status, hc); drift != 620 {
+		t.Errorf("expected the pending step to be counted once, got %f", drift)
+	}
+}
@@ -6,7 +6,26 @@ import (
 	"net/http"
 )
 
+// PipelineLabel is the label that identifies which pipeline a metric belongs to
+const PipelineLabel = "pipeline"
+
 var registry = prometheus.NewRegistry()
+var pipelineLabels = []string{PipelineLabel}
+
+// SensorLabel is the label that identifies a temperature sensor
+const SensorLabel = "sensor"
+
+var sensorLabels = []string{PipelineLabel, SensorLabel}
+
+// TextLabel is the label that carries the text decoded from a beacon
+const TextLabel = "text"
+
+var textLabels = []string{PipelineLabel, TextLabel}
+
+// RuleLabel is the label that identifies an alert rule
+const RuleLabel = "rule"
+
+var ruleLabels = []string{PipelineLabel, RuleLabel}
 
 func init() {
 	registry.MustRegister(Connections)
@@ -21,62 +40,384 @@ func init() {
 	registry.MustRegister(WebConnections)
 	registry.MustRegister(MaxConnections)
 	registry.MustRegister(MaxWebConnections)
+	registry.MustRegister(SampleClockPPM)
+	registry.MustRegister(ResamplerPPM)
+	registry.MustRegister(LockDetector)
+	registry.MustRegister(LoopBandwidth)
+	registry.MustRegister(LoopGear)
+	registry.MustRegister(CorrectionHold)
+	registry.MustRegister(Retunes)
+	registry.MustRegister(ExternalCorrection)
+	registry.MustRegister(HardwareCorrection)
+	registry.MustRegister(HardwareSteps)
+	registry.MustRegister(UplinkFrequency)
+	registry.MustRegister(UplinkCorrection)
+	registry.MustRegister(UplinkClamped)
+	registry.MustRegister(UplinkConnected)
+	registry.MustRegister(UplinkUpdates)
+	registry.MustRegister(UplinkErrors)
+	registry.MustRegister(BeaconPower)
+	registry.MustRegister(NoiseFloor)
+	registry.MustRegister(BeaconSNR)
+	registry.MustRegister(BeaconCN0)
+	registry.MustRegister(AGCGain)
+	registry.MustRegister(Temperature)
+	registry.MustRegister(TemperatureErrors)
+	registry.MustRegister(TemperatureSlope)
+	registry.MustRegister(TemperatureIntercept)
+	registry.MustRegister(TemperatureSamples)
+	registry.MustRegister(TemperatureModelValid)
+	registry.MustRegister(Holdover)
+	registry.MustRegister(HoldoverPrediction)
+	registry.MustRegister(PSKFrames)
+	registry.MustRegister(PSKCRCErrors)
+	registry.MustRegister(PSKMER)
+	registry.MustRegister(BeaconDecoded)
+	registry.MustRegister(CWConfidence)
+	registry.MustRegister(CWWPM)
+	registry.MustRegister(CWCharacters)
+	registry.MustRegister(CWText)
+	registry.MustRegister(BeaconVerified)
+	registry.MustRegister(ResidualError)
+	registry.MustRegister(ResidualWidth)
+	registry.MustRegister(ResidualJitter)
+	registry.MustRegister(SignalsActive)
+	registry.MustRegister(SignalsTotal)
+	registry.MustRegister(SignalsNoiseFloor)
+	registry.MustRegister(SignalsDroppedFrames)
+	registry.MustRegister(AlertsActive)
+	registry.MustRegister(AlertsTotal)
+	registry.MustRegister(WebhookErrors)
+	registry.MustRegister(ADCClipRate)
+	registry.MustRegister(ADCClippedSamples)
+	registry.MustRegister(ADCRMS)
+	registry.MustRegister(ADCPeak)
+	registry.MustRegister(SourceGain)
+	registry.MustRegister(GainChanges)
+	registry.MustRegister(SpectrumDroppedFrames)
 }
 
 var (
-	Connections = prometheus.NewGauge(prometheus.GaugeOpts{
+	Connections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "connections",
 		Help: "Current number of connections",
-	})
-	TotalConnections = prometheus.NewCounter(prometheus.CounterOpts{
+	}, pipelineLabels)
+	TotalConnections = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "total_connections",
 		Help: "The total number of connections since server started",
-	})
-	MaxConnections = prometheus.NewCounter(prometheus.CounterOpts{
+	}, pipelineLabels)
+	MaxConnections = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "max_connections",
 		Help: "The max concurrent connections this server accepts",
-	})
-	BytesOut = prometheus.NewCounter(prometheus.CounterOpts{
+	}, pipelineLabels)
+	BytesOut = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "bytes_out",
 		Help: "Number of bytes sent",
-	})
-	BytesIn = prometheus.NewCounter(prometheus.CounterOpts{
+	}, pipelineLabels)
+	BytesIn = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "bytes_in",
 		Help: "Number of bytes received",
-	})
-	LockOffset = prometheus.NewGauge(prometheus.GaugeOpts{
+	}, pipelineLabels)
+	LockOffset = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "lock_offset",
 		Help: "Offset Frequency in Hertz of the current beacon lock",
-	})
-	ServerCenterFrequency = prometheus.NewGauge(prometheus.GaugeOpts{
+	}, pipelineLabels)
+	ServerCenterFrequency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Subsystem: "server",
 		Name:      "center_frequency",
 		Help:      "Server Center Frequency in Hertz",
-	})
-	ServerSampleRate = prometheus.NewGauge(prometheus.GaugeOpts{
+	}, pipelineLabels)
+	ServerSampleRate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Subsystem: "server",
 		Name:      "samplerate",
 		Help:      "Server Sample Rate in Samples Per Second",
-	})
-	SegmentCenterFrequency = prometheus.NewGauge(prometheus.GaugeOpts{
+	}, pipelineLabels)
+	SegmentCenterFrequency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Subsystem: "segment",
 		Name:      "center_frequency",
 		Help:      "Beacon Segment Center Frequency in Hertz",
-	})
-	SegmentSampleRate = prometheus.NewGauge(prometheus.GaugeOpts{
+	}, pipelineLabels)
+	SegmentSampleRate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Subsystem: "segment",
 		Name:      "samplerate",
 		Help:      "Beacon Segment Rate in Samples Per Second",
-	})
-	WebConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+	}, pipelineLabels)
+	WebConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Subsystem: "server",
 		Name:      "webconnections",
 		Help:      "Current WebSocket Connections",
-	})
-	MaxWebConnections = prometheus.NewCounter(prometheus.CounterOpts{
+	}, pipelineLabels)
+	MaxWebConnections = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "max_web_connections",
 		Help: "The max concurrent connections to websocket this server accepts",
-	})
+	}, pipelineLabels)
+	SampleClockPPM = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sample_clock_ppm",
+		Help: "Measured upstream sample clock error in PPM",
+	}, pipelineLabels)
+	ResamplerPPM = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "resampler",
+		Name:      "ppm",
+		Help:      "Sample clock error in PPM corrected by the resampler",
+	}, pipelineLabels)
+	LockDetector = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lock_detector",
+		Help: "Costas Loop lock detector value (1 is locked, 0 is unlocked)",
+	}, pipelineLabels)
+	LoopBandwidth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "loop",
+		Name:      "bandwidth",
+		Help:      "Current Costas Loop bandwidth in radians per sample",
+	}, pipelineLabels)
+	LoopGear = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "loop",
+		Name:      "gear",
+		Help:      "Current Costas Loop gear (0 is acquisition)",
+	}, pipelineLabels)
+	CorrectionHold = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "correction_hold",
+		Help: "If the last correction is being held while the loop relocks after a retune",
+	}, pipelineLabels)
+	Retunes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "retunes",
+		Help: "Number of center frequency changes since server started",
+	}, pipelineLabels)
+	ExternalCorrection = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "external_correction",
+		Help: "If the correction is received from another pipeline instead of the local Costas Loop",
+	}, pipelineLabels)
+	HardwareCorrection = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "hardware",
+		Name:      "correction",
+		Help:      "Correction in Hertz currently applied by the upstream tuner",
+	}, pipelineLabels)
+	HardwareSteps = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "hardware",
+		Name:      "steps",
+		Help:      "Number of correction steps sent to the upstream tuner",
+	}, pipelineLabels)
+	UplinkFrequency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "uplink",
+		Name:      "frequency",
+		Help:      "Last uplink frequency in Hertz sent to the transmitter",
+	}, pipelineLabels)
+	UplinkCorrection = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "uplink",
+		Name:      "correction",
+		Help:      "Drift correction in Hertz applied to the uplink frequency",
+	}, pipelineLabels)
+	UplinkClamped = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "uplink",
+		Name:      "clamped",
+		Help:      "If the uplink correction is being limited by the safety clamp",
+	}, pipelineLabels)
+	UplinkConnected = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "uplink",
+		Name:      "connected",
+		Help:      "If the connection to the transmitter rigctld is up",
+	}, pipelineLabels)
+	UplinkUpdates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "uplink",
+		Name:      "updates",
+		Help:      "Number of uplink frequency updates sent to the transmitter",
+	}, pipelineLabels)
+	UplinkErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "uplink",
+		Name:      "errors",
+		Help:      "Number of errors talking to the transmitter",
+	}, pipelineLabels)
+	BeaconPower = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "beacon",
+		Name:      "power",
+		Help:      "Beacon power in dBFS over the beacon bandwidth",
+	}, pipelineLabels)
+	NoiseFloor = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "noise_floor",
+		Help: "Noise density around the beacon in dBFS/Hz",
+	}, pipelineLabels)
+	BeaconSNR = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "beacon",
+		Name:      "snr",
+		Help:      "Beacon SNR in dB over the beacon bandwidth",
+	}, pipelineLabels)
+	BeaconCN0 = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "beacon",
+		Name:      "cn0",
+		Help:      "Beacon carrier to noise density ratio in dB-Hz",
+	}, pipelineLabels)
+	AGCGain = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "agc",
+		Name:      "gain",
+		Help:      "Segment AGC gain in dB",
+	}, pipelineLabels)
+	Temperature = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "temperature",
+		Help: "Temperature in Celsius read from a sensor",
+	}, sensorLabels)
+	TemperatureErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "temperature",
+		Name:      "errors",
+		Help:      "Number of errors reading a temperature sensor",
+	}, sensorLabels)
+	TemperatureSlope = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "temperature",
+		Name:      "slope",
+		Help:      "Fitted drift versus temperature slope in Hertz per Celsius",
+	}, pipelineLabels)
+	TemperatureIntercept = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "temperature",
+		Name:      "intercept",
+		Help:      "Fitted drift at zero Celsius in Hertz",
+	}, pipelineLabels)
+	TemperatureSamples = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "temperature",
+		Name:      "samples",
+		Help:      "Number of samples in the drift versus temperature model",
+	}, pipelineLabels)
+	TemperatureModelValid = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "temperature",
+		Name:      "model_valid",
+		Help:      "If the drift versus temperature model can be used for predictions",
+	}, pipelineLabels)
+	Holdover = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "holdover",
+		Help: "If the correction is being predicted from temperature because the beacon is not available",
+	}, pipelineLabels)
+	HoldoverPrediction = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "holdover",
+		Name:      "prediction",
+		Help:      "Drift in Hertz predicted from temperature",
+	}, pipelineLabels)
+	PSKFrames = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "psk",
+		Name:      "frames",
+		Help:      "Number of PSK beacon frames decoded with a valid CRC",
+	}, pipelineLabels)
+	PSKCRCErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "psk",
+		Name:      "crc_errors",
+		Help:      "Number of PSK beacon frames dropped by the CRC check",
+	}, pipelineLabels)
+	PSKMER = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "psk",
+		Name:      "mer",
+		Help:      "PSK beacon modulation error ratio in dB",
+	}, pipelineLabels)
+	BeaconDecoded = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "beacon",
+		Name:      "decoded",
+		Help:      "If a PSK beacon frame was decoded recently",
+	}, pipelineLabels)
+	CWConfidence = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "cw",
+		Name:      "confidence",
+		Help:      "CW beacon decode confidence from 0 to 1",
+	}, pipelineLabels)
+	CWWPM = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "cw",
+		Name:      "wpm",
+		Help:      "CW beacon speed in words per minute",
+	}, pipelineLabels)
+	CWCharacters = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "cw",
+		Name:      "characters",
+		Help:      "Number of characters decoded from the CW beacon",
+	}, pipelineLabels)
+	CWText = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "cw",
+		Name:      "text",
+		Help:      "Last text decoded from the CW beacon, in the text label",
+	}, textLabels)
+	BeaconVerified = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "beacon",
+		Name:      "verified",
+		Help:      "If the CW beacon text was decoded recently",
+	}, pipelineLabels)
+	ResidualError = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "residual",
+		Name:      "error",
+		Help:      "Beacon frequency error after the drift correction in Hertz",
+	}, pipelineLabels)
+	ResidualWidth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "residual",
+		Name:      "width",
+		Help:      "Beacon line width after the drift correction in Hertz",
+	}, pipelineLabels)
+	ResidualJitter = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "residual",
+		Name:      "jitter",
+		Help:      "Standard deviation of the beacon frequency error after the drift correction in Hertz",
+	}, pipelineLabels)
+	SignalsActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "signals",
+		Name:      "active",
+		Help:      "Number of signals currently on the air",
+	}, pipelineLabels)
+	SignalsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "signals",
+		Name:      "total",
+		Help:      "Number of signals seen since server started",
+	}, pipelineLabels)
+	SignalsNoiseFloor = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "signals",
+		Name:      "noise_floor",
+		Help:      "Full band noise floor in dBFS per detector bin",
+	}, pipelineLabels)
+	SignalsDroppedFrames = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "signals",
+		Name:      "dropped_frames",
+		Help:      "Number of spectrum frames skipped because the signal detector was busy",
+	}, pipelineLabels)
+	AlertsActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "alerts",
+		Name:      "active",
+		Help:      "1 while the alert rule is firing",
+	}, ruleLabels)
+	AlertsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "alerts",
+		Name:      "total",
+		Help:      "Number of alerts fired since server started",
+	}, ruleLabels)
+	WebhookErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "alerts",
+		Name:      "webhook_errors",
+		Help:      "Number of alert webhook deliveries that failed",
+	}, pipelineLabels)
+	ADCClipRate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "adc",
+		Name:      "clip_rate",
+		Help:      "Fraction of the raw samples with I or Q at the ADC limits",
+	}, pipelineLabels)
+	ADCClippedSamples = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "adc",
+		Name:      "clipped_samples",
+		Help:      "Number of raw samples with I or Q at the ADC limits",
+	}, pipelineLabels)
+	ADCRMS = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "adc",
+		Name:      "rms",
+		Help:      "RMS level of the raw samples in dBFS",
+	}, pipelineLabels)
+	ADCPeak = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "adc",
+		Name:      "peak",
+		Help:      "Peak level of the raw samples in dBFS",
+	}, pipelineLabels)
+	SourceGain = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "source",
+		Name:      "gain",
+		Help:      "Upstream tuner gain in dB",
+	}, pipelineLabels)
+	GainChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "source",
+		Name:      "gain_changes",
+		Help:      "Number of gain changes made by the automatic gain control",
+	}, pipelineLabels)
+	SpectrumDroppedFrames = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "spectrum",
+		Name:      "dropped_frames",
+		Help:      "Number of FFT frames skipped because the spectrum worker was busy",
+	}, pipelineLabels)
 )
 
 func GetHandler() http.Handler {
@@ -0,0 +1,77 @@
+package occupancy
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// Bucket accumulates the spectrum frames of a time interval.
+// Occupancy and Power hold sums over the frames so buckets can be merged by adding them.
+type Bucket struct {
+	Start           time.Time `json:"start"`
+	Duration        float64   `json:"duration"` // Seconds
+	CenterFrequency float64   `json:"centerFrequency"`
+	BinWidth        float64   `json:"binWidth"`
+	Frames          int       `json:"frames"`
+	Occupancy       []float64 `json:"occupancy"` // Sum of the occupied fraction of each bin
+	Power           []float64 `json:"power"`     // Sum of the linear power of each bin
+}
+
+func makeBucket(start time.Time, duration time.Duration, centerFrequency, binWidth float64, bins int) *Bucket {
+	return &Bucket{
+		Start:           start,
+		Duration:        duration.Seconds(),
+		CenterFrequency: centerFrequency,
+		BinWidth:        binWidth,
+		Occupancy:       make([]float64, bins),
+		Power:           make([]float64, bins),
+	}
+}
+
+// End returns the end of the bucket interval
+func (b *Bucket) End() time.Time {
+	return b.Start.Add(time.Duration(b.Duration * float64(time.Second)))
+}
+
+// compatible returns true when both buckets cover the same frequencies
+func (b *Bucket) compatible(o *Bucket) bool {
+	return b.CenterFrequency == o.CenterFrequency && b.BinWidth == o.BinWidth && len(b.Power) == len(o.Power)
+}
+
+func (b *Bucket) copy() *Bucket {
+	c := *b
+	c.Occupancy = append([]float64(nil), b.Occupancy...)
+	c.Power = append([]float64(nil), b.Power...)
+	return &c
+}
+
+// readBuckets reads a JSON lines file of buckets
+func readBuckets(filename string) ([]*Bucket, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buckets := make([]*Bucket, 0)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		b := &Bucket{}
+		err = json.Unmarshal(scanner.Bytes(), b)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+
+	return buckets, scanner.Err()
+}
@@ -0,0 +1,90 @@
+package occupancy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/racerxdl/qo100-dedrift/driftlog"
+	"net/http"
+	"strconv"
+)
+
+// heatmap builds the heatmap requested by the hours and mode query parameters
+func (r *Recorder) heatmap(req *http.Request) (Heatmap, int, error) {
+	hours, err := driftlog.ParseHours(req, r.cfg.MaxHours)
+	if err != nil {
+		return Heatmap{}, http.StatusBadRequest, err
+	}
+
+	mode := req.URL.Query().Get("mode")
+	if mode == "" {
+		mode = ModeTime
+	}
+
+	buckets, err := r.ReadLast(hours)
+	if err != nil {
+		r.log.Error("Error reading occupancy buckets: %s", err)
+		return Heatmap{}, http.StatusInternalServerError, fmt.Errorf("Internal Server Error")
+	}
+
+	h, err := MakeHeatmap(buckets, mode)
+	if err != nil {
+		return Heatmap{}, http.StatusBadRequest, err
+	}
+
+	return h, http.StatusOK, nil
+}
+
+// Handler serves the heatmap of the last N hours, specified by the hours query parameter, up to MaxHours.
+// The mode query parameter selects one row per bucket (time) or per hour of the day (hour).
+func (r *Recorder) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		h, status, err := r.heatmap(req)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		data, _ := json.Marshal(h)
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(200)
+		_, _ = w.Write(data)
+	}
+}
+
+// ImageHandler serves the heatmap as a PNG image. Besides the hours and mode query parameters, value selects
+// occupancy or power and width resamples the bins to the specified number of pixels.
+func (r *Recorder) ImageHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		h, status, err := r.heatmap(req)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		value := req.URL.Query().Get("value")
+		if value == "" {
+			value = ValueOccupancy
+		}
+
+		width := 0
+		if v := req.URL.Query().Get("width"); v != "" {
+			width, err = strconv.Atoi(v)
+			if err != nil || width <= 0 || width > 16384 {
+				http.Error(w, fmt.Sprintf("invalid width %q", v), http.StatusBadRequest)
+				return
+			}
+		}
+
+		buf := &bytes.Buffer{}
+		err = RenderPNG(buf, h, value, width, r.cfg.RowHeight)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		w.Header().Set("content-type", "image/png")
+		w.WriteHeader(200)
+		_, _ = w.Write(buf.Bytes())
+	}
+}
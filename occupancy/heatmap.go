@@ -0,0 +1,148 @@
+package occupancy
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	ModeTime = "time" // One row per bucket
+	ModeHour = "hour" // One row per hour of the day (UTC)
+)
+
+// Row is a line of the heatmap
+type Row struct {
+	Label     string    `json:"label"`
+	Time      time.Time `json:"time"` // Start of the first bucket in the row
+	Frames    int       `json:"frames"`
+	Occupancy []float32 `json:"occupancy"` // Fraction of the time each bin was above the detection threshold
+	Power     []float32 `json:"power"`     // Average power of each bin in dBFS
+}
+
+// Heatmap is the occupancy and average power of the full band over a period
+type Heatmap struct {
+	Mode            string    `json:"mode"`
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	CenterFrequency float64   `json:"centerFrequency"`
+	StartFrequency  float64   `json:"startFrequency"` // Frequency of the center of the first bin
+	BinWidth        float64   `json:"binWidth"`
+	Rows            []Row     `json:"rows"`
+}
+
+type accumulator struct {
+	label     string
+	time      time.Time
+	frames    int
+	occupancy []float64
+	power     []float64
+}
+
+func (a *accumulator) add(b *Bucket) {
+	if a.occupancy == nil {
+		a.occupancy = make([]float64, len(b.Occupancy))
+		a.power = make([]float64, len(b.Power))
+		a.time = b.Start
+	}
+
+	for i := range b.Occupancy {
+		a.occupancy[i] += b.Occupancy[i]
+		a.power[i] += b.Power[i]
+	}
+	a.frames += b.Frames
+}
+
+func (a *accumulator) row() Row {
+	r := Row{
+		Label:     a.label,
+		Time:      a.time,
+		Frames:    a.frames,
+		Occupancy: make([]float32, len(a.occupancy)),
+		Power:     make([]float32, len(a.power)),
+	}
+
+	if a.frames == 0 {
+		return r
+	}
+
+	for i := range a.occupancy {
+		r.Occupancy[i] = float32(a.occupancy[i] / float64(a.frames))
+		r.Power[i] = float32(10 * math.Log10(math.Max(a.power[i]/float64(a.frames), 1e-30)))
+	}
+
+	return r
+}
+
+// MakeHeatmap aggregates the buckets in rows according to the mode.
+// Only the buckets with the same frequency span of the most recent one are used, so the columns of all rows match.
+func MakeHeatmap(buckets []*Bucket, mode string) (Heatmap, error) {
+	if mode != ModeTime && mode != ModeHour {
+		return Heatmap{}, fmt.Errorf("invalid heatmap mode %q", mode)
+	}
+
+	h := Heatmap{
+		Mode: mode,
+		Rows: make([]Row, 0),
+	}
+
+	if len(buckets) == 0 {
+		return h, nil
+	}
+
+	sort.SliceStable(buckets, func(i, j int) bool { return buckets[i].Start.Before(buckets[j].Start) })
+
+	last := buckets[len(buckets)-1]
+	n := len(last.Power)
+	h.CenterFrequency = last.CenterFrequency
+	h.BinWidth = last.BinWidth
+	h.StartFrequency = last.CenterFrequency + (0.5-float64(n)/2)*last.BinWidth
+	h.End = last.End()
+
+	var rows []*accumulator
+
+	switch mode {
+	case ModeTime:
+		byStart := make(map[int64]*accumulator)
+		for _, b := range buckets {
+			if !b.compatible(last) {
+				continue
+			}
+			a, ok := byStart[b.Start.Unix()]
+			if !ok {
+				a = &accumulator{label: b.Start.UTC().Format(time.RFC3339)}
+				byStart[b.Start.Unix()] = a
+				rows = append(rows, a)
+			}
+			a.add(b)
+		}
+	case ModeHour:
+		rows = make([]*accumulator, 24)
+		for i := range rows {
+			rows[i] = &accumulator{label: fmt.Sprintf("%02d:00", i)}
+		}
+		for _, b := range buckets {
+			if b.compatible(last) {
+				rows[b.Start.UTC().Hour()].add(b)
+			}
+		}
+	}
+
+	for _, a := range rows {
+		if a.occupancy == nil { // Hours without data
+			a.occupancy = make([]float64, n)
+			a.power = make([]float64, n)
+		}
+		h.Rows = append(h.Rows, a.row())
+	}
+
+	for _, b := range buckets {
+		if b.compatible(last) {
+			h.Start = b.Start
+			break
+		}
+	}
+
+	return h, nil
+}
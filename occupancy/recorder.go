@@ -0,0 +1,295 @@
+package occupancy
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/quan-to/slog"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"github.com/racerxdl/qo100-dedrift/signals"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dayLayout = "20060102"
+
+// Recorder accumulates the occupancy and average power of the full band spectrum in time buckets.
+// Finished buckets are appended to daily files named <name>-<YYYYMMDD>.jsonl and the bucket in progress is
+// saved periodically to <name>-current.json, so it survives a restart.
+type Recorder struct {
+	sync.Mutex
+	name         string
+	directory    string
+	cfg          config.OccupancyConfig
+	log          *slog.Instance
+	pattern      *regexp.Regexp // Matches the daily files of this recorder only, not of pipelines named <name>-<suffix>
+	bucketSize   time.Duration
+	saveInterval time.Duration
+	current      *Bucket
+	lastSave     time.Time
+}
+
+func MakeRecorder(name string, cfg config.OccupancyConfig) (*Recorder, error) {
+	if cfg.BucketSize <= 0 {
+		return nil, fmt.Errorf("invalid occupancy bucket size %f", cfg.BucketSize)
+	}
+
+	err := os.MkdirAll(cfg.Directory, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{
+		name:         name,
+		directory:    cfg.Directory,
+		cfg:          cfg,
+		log:          slog.Scope("Occupancy " + name),
+		pattern:      regexp.MustCompile(fmt.Sprintf(`^%s-\d{8}\.jsonl$`, regexp.QuoteMeta(name))),
+		bucketSize:   time.Duration(cfg.BucketSize * float64(time.Second)),
+		saveInterval: time.Duration(cfg.SaveInterval * float64(time.Second)),
+	}
+
+	r.loadCurrent()
+
+	return r, nil
+}
+
+func (r *Recorder) filename(day string) string {
+	return filepath.Join(r.directory, fmt.Sprintf("%s-%s.jsonl", r.name, day))
+}
+
+func (r *Recorder) currentFilename() string {
+	return filepath.Join(r.directory, r.name+"-current.json")
+}
+
+// files returns the daily bucket files sorted by day
+func (r *Recorder) files() ([]string, error) {
+	entries, err := ioutil.ReadDir(r.directory)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0)
+	for _, e := range entries {
+		if !e.IsDir() && r.pattern.MatchString(e.Name()) {
+			files = append(files, filepath.Join(r.directory, e.Name()))
+		}
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func (r *Recorder) fileDayOf(filename string) string {
+	base := filepath.Base(filename)
+	return strings.TrimSuffix(strings.TrimPrefix(base, r.name+"-"), ".jsonl")
+}
+
+// loadCurrent restores the bucket in progress saved before a restart
+func (r *Recorder) loadCurrent() {
+	data, err := ioutil.ReadFile(r.currentFilename())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			r.log.Error("Error reading the current occupancy bucket: %s", err)
+		}
+		return
+	}
+
+	b := &Bucket{}
+	err = json.Unmarshal(data, b)
+	if err != nil {
+		r.log.Error("Error parsing the current occupancy bucket: %s", err)
+		return
+	}
+
+	r.log.Info("Restored occupancy bucket started at %s with %d frames", b.Start.Format(time.RFC3339), b.Frames)
+	r.current = b
+}
+
+// columns returns how many spectrum bins are summed in each bucket bin
+func (r *Recorder) columns(bins int) int {
+	if r.cfg.Bins <= 0 || bins <= r.cfg.Bins {
+		return 1
+	}
+	return bins / r.cfg.Bins
+}
+
+// Add accumulates a spectrum in the bucket of its time. It is meant to be called from the signal detector callback.
+func (r *Recorder) Add(spectrum signals.Spectrum) {
+	group := r.columns(len(spectrum.Bins))
+	bins := len(spectrum.Bins) / group
+	binWidth := spectrum.BinWidth * float64(group)
+	start := spectrum.Time.Truncate(r.bucketSize)
+
+	r.Lock()
+	defer r.Unlock()
+
+	next := makeBucket(start, r.bucketSize, spectrum.CenterFrequency, binWidth, bins)
+
+	if r.current != nil && (!r.current.Start.Equal(start) || !r.current.compatible(next)) {
+		r.finish()
+	}
+
+	if r.current == nil {
+		r.current = next
+	}
+
+	b := r.current
+	for i := 0; i < bins; i++ {
+		occupied, power := 0, 0.0
+		for _, v := range spectrum.Bins[i*group : (i+1)*group] {
+			if v >= spectrum.Threshold {
+				occupied++
+			}
+			power += v
+		}
+		b.Occupancy[i] += float64(occupied) / float64(group)
+		b.Power[i] += power / float64(group)
+	}
+	b.Frames++
+
+	if time.Since(r.lastSave) > r.saveInterval {
+		r.saveCurrent()
+	}
+}
+
+// saveCurrent writes the bucket in progress. It replaces the file atomically so a crash never leaves it truncated.
+func (r *Recorder) saveCurrent() {
+	r.lastSave = time.Now()
+
+	data, err := json.Marshal(r.current)
+	if err != nil {
+		r.log.Error("Error encoding the current occupancy bucket: %s", err)
+		return
+	}
+
+	tmp := r.currentFilename() + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err == nil {
+		err = os.Rename(tmp, r.currentFilename())
+	}
+
+	if err != nil {
+		r.log.Error("Error saving the current occupancy bucket: %s", err)
+	}
+}
+
+// finish appends the bucket in progress to its daily file
+func (r *Recorder) finish() {
+	b := r.current
+	r.current = nil
+
+	if b.Frames == 0 {
+		return
+	}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		r.log.Error("Error encoding occupancy bucket: %s", err)
+		return
+	}
+
+	filename := r.filename(b.Start.UTC().Format(dayLayout))
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		r.log.Error("Error opening %s: %s", filename, err)
+		return
+	}
+
+	_, err = f.Write(append(data, '\n'))
+	_ = f.Close()
+	if err != nil {
+		r.log.Error("Error writing occupancy bucket to %s: %s", filename, err)
+		return
+	}
+
+	r.log.Debug("Occupancy bucket started at %s finished with %d frames", b.Start.Format(time.RFC3339), b.Frames)
+
+	err = os.Remove(r.currentFilename())
+	if err != nil && !os.IsNotExist(err) {
+		r.log.Error("Error removing the current occupancy bucket: %s", err)
+	}
+
+	r.cleanup()
+}
+
+// cleanup removes the files older than MaxDays
+func (r *Recorder) cleanup() {
+	if r.cfg.MaxDays <= 0 {
+		return
+	}
+
+	files, err := r.files()
+	if err != nil {
+		r.log.Error("Error listing occupancy files: %s", err)
+		return
+	}
+
+	oldest := time.Now().UTC().AddDate(0, 0, -r.cfg.MaxDays).Format(dayLayout)
+	for _, f := range files {
+		if r.fileDayOf(f) < oldest {
+			r.log.Info("Removing old occupancy file %s", f)
+			err = os.Remove(f)
+			if err != nil {
+				r.log.Error("Error removing %s: %s", f, err)
+			}
+		}
+	}
+}
+
+// Read returns the buckets that end after the specified time, including the bucket in progress
+func (r *Recorder) Read(since time.Time) ([]*Bucket, error) {
+	files, err := r.files()
+	if err != nil {
+		return nil, err
+	}
+
+	sinceDay := since.UTC().Add(-r.bucketSize).Format(dayLayout)
+	buckets := make([]*Bucket, 0)
+
+	for _, f := range files {
+		if r.fileDayOf(f) < sinceDay {
+			continue
+		}
+
+		fileBuckets, err := readBuckets(f)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range fileBuckets {
+			if b.End().After(since) {
+				buckets = append(buckets, b)
+			}
+		}
+	}
+
+	r.Lock()
+	if r.current != nil && r.current.Frames > 0 {
+		buckets = append(buckets, r.current.copy())
+	}
+	r.Unlock()
+
+	return buckets, nil
+}
+
+// ReadLast returns the buckets of the last hours
+func (r *Recorder) ReadLast(hours float64) ([]*Bucket, error) {
+	return r.Read(time.Now().Add(-time.Duration(hours * float64(time.Hour))))
+}
+
+// Close saves the bucket in progress
+func (r *Recorder) Close() {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.current != nil && r.current.Frames > 0 {
+		r.saveCurrent()
+	}
+}
@@ -0,0 +1,46 @@
+package occupancy
+
+import (
+	"github.com/racerxdl/qo100-dedrift/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecorderFilesIgnoresOtherPipelines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qo100-occupancy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	names := []string{
+		"main-20261018.jsonl",
+		"main-20261019.jsonl",
+		"main-2-20261019.jsonl",
+		"main-current.json",
+		"main-old.jsonl",
+	}
+	for _, f := range names {
+		err = ioutil.WriteFile(filepath.Join(dir, f), nil, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := MakeRecorder("main", config.OccupancyConfig{Directory: dir, BucketSize: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := r.files()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{filepath.Join(dir, names[0]), filepath.Join(dir, names[1])}
+	if len(files) != len(expected) || files[0] != expected[0] || files[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+}
@@ -0,0 +1,122 @@
+package occupancy
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+const (
+	ValueOccupancy = "occupancy"
+	ValuePower     = "power"
+)
+
+// palette is the color scale of the heatmap, from the lowest to the highest value
+var palette = []color.RGBA{
+	{0, 0, 4, 255},
+	{40, 11, 84, 255},
+	{101, 21, 110, 255},
+	{159, 42, 99, 255},
+	{212, 72, 66, 255},
+	{245, 125, 21, 255},
+	{250, 193, 39, 255},
+	{252, 255, 164, 255},
+}
+
+// colorOf interpolates the palette. v is in the 0..1 range.
+func colorOf(v float64) color.RGBA {
+	v = math.Max(0, math.Min(1, v)) * float64(len(palette)-1)
+	i := int(v)
+	if i >= len(palette)-1 {
+		return palette[len(palette)-1]
+	}
+
+	f := v - float64(i)
+	a, b := palette[i], palette[i+1]
+	mix := func(x, y uint8) uint8 { return uint8(float64(x) + (float64(y)-float64(x))*f) }
+
+	return color.RGBA{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: 255}
+}
+
+// powerRange returns the lowest and highest average power of the rows with data
+func powerRange(h Heatmap) (float64, float64) {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, r := range h.Rows {
+		if r.Frames == 0 {
+			continue
+		}
+		for _, v := range r.Power {
+			min = math.Min(min, float64(v))
+			max = math.Max(max, float64(v))
+		}
+	}
+
+	if max <= min {
+		return min, min + 1
+	}
+
+	return min, max
+}
+
+// RenderPNG draws the heatmap with one pixel column per bin, resampled to width pixels when width is positive,
+// and rowHeight pixels per row. Rows without data are left transparent.
+func RenderPNG(w io.Writer, h Heatmap, value string, width, rowHeight int) error {
+	if value != ValueOccupancy && value != ValuePower {
+		return fmt.Errorf("invalid heatmap value %q", value)
+	}
+
+	if len(h.Rows) == 0 {
+		return fmt.Errorf("no occupancy data")
+	}
+
+	bins := len(h.Rows[0].Power)
+	if width <= 0 {
+		width = bins
+	}
+	if rowHeight <= 0 {
+		rowHeight = 1
+	}
+
+	min, max := powerRange(h)
+	img := image.NewRGBA(image.Rect(0, 0, width, len(h.Rows)*rowHeight))
+
+	for y, r := range h.Rows {
+		if r.Frames == 0 {
+			continue
+		}
+
+		values := r.Occupancy
+		if value == ValuePower {
+			values = r.Power
+		}
+
+		for x := 0; x < width; x++ {
+			// Average the bins covered by the pixel
+			first := x * bins / width
+			last := (x + 1) * bins / width
+			if last <= first {
+				last = first + 1
+			}
+
+			v := 0.0
+			for _, b := range values[first:last] {
+				v += float64(b)
+			}
+			v /= float64(last - first)
+
+			if value == ValuePower {
+				v = (v - min) / (max - min)
+			}
+
+			c := colorOf(v)
+			for i := 0; i < rowHeight; i++ {
+				img.SetRGBA(x, y*rowHeight+i, c)
+			}
+		}
+	}
+
+	return png.Encode(w, img)
+}
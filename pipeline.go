@@ -0,0 +1,812 @@
+package main
+
+import (
+	"encoding/binary"
+	"github.com/quan-to/slog"
+	"github.com/racerxdl/go.fifo"
+	"github.com/racerxdl/qo100-dedrift/alerts"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"github.com/racerxdl/qo100-dedrift/cw"
+	"github.com/racerxdl/qo100-dedrift/dedrift"
+	"github.com/racerxdl/qo100-dedrift/driftlog"
+	"github.com/racerxdl/qo100-dedrift/metrics"
+	"github.com/racerxdl/qo100-dedrift/occupancy"
+	"github.com/racerxdl/qo100-dedrift/psk"
+	"github.com/racerxdl/qo100-dedrift/rigctl"
+	"github.com/racerxdl/qo100-dedrift/rtltcp"
+	"github.com/racerxdl/qo100-dedrift/signals"
+	"github.com/racerxdl/qo100-dedrift/stability"
+	"github.com/racerxdl/qo100-dedrift/web"
+	"os"
+	"sync"
+	"time"
+)
+
+const correctionPublishInterval = 100 * time.Millisecond
+
+// Pipeline connects a RTLTCP source through a Dedrifter to its own RTLTCP server and web namespace
+type Pipeline struct {
+	name string
+	cfg  config.PipelineConfig
+	log  *slog.Instance
+
+	client    *rtltcp.Client
+	server    *rtltcp.Server
+	dedrifter *dedrift.Dedrifter
+	namespace *web.Namespace
+	hardware  *HardwareCorrector
+	rigctl    *rigctl.Server
+	rig       *PipelineRig
+	uplink    *UplinkCorrector
+	driftLog  *driftlog.Logger
+	thermal   *TemperatureCompensator
+	gain      *GainController
+	gainTable rtltcp.GainTable
+	signals   *signals.Detector
+	occupancy *occupancy.Recorder
+	alerts    *alerts.Manager
+	writer    *FileWriter
+
+	// lock guards the center frequency, beacon offset and gain changed by the rtl_tcp clients and rigctl
+	lock     sync.Mutex
+	tuneLock sync.Mutex
+
+	sampleFifo              *fifo.Queue
+	dspRunning              bool
+	dspDone                 chan bool
+	lastShiftReport         time.Time
+	beaconAbsoluteFrequency float64
+	lastRetunes             int
+	lastPSKStats            psk.Stats
+	lastCWStatus            cw.Status
+	lastSignals             int
+	lastDroppedSignalFrames int
+	lastDroppedFFTFrames    int
+	lastWebhookErrors       int
+	currentGain             float64
+	lastCorrectionPublish   time.Time
+	lastStateSave           time.Time
+	lastDriftLog            time.Time
+	stopCorrection          chan bool
+}
+
+func MakePipeline(cfg config.PipelineConfig, ws *web.Server) *Pipeline {
+	p := &Pipeline{
+		name:            cfg.Name,
+		cfg:             cfg,
+		log:             slog.Scope("Pipeline " + cfg.Name),
+		writer:          MakeFileWriter(cfg.Name),
+		sampleFifo:      fifo.NewQueue(),
+		dspDone:         make(chan bool, 1),
+		stopCorrection:  make(chan bool, 1),
+		lastShiftReport: time.Now(),
+	}
+
+	p.beaconAbsoluteFrequency = cfg.Processing.BeaconFrequency
+	p.log.Info("Beacon absolute frequency: %.0f Hz (offset %.0f Hz)", p.beaconAbsoluteFrequency, cfg.Processing.BeaconOffset)
+
+	var err error
+	p.dedrifter, err = dedrift.MakeDedrifter(cfg.Processing, cfg.Source.SampleRate)
+	if err != nil {
+		p.log.Fatal("Error creating dedrifter: %s", err)
+	}
+	p.dedrifter.SetFFTSettings(cfg.WebSettings)
+
+	if cfg.Processing.State.Enable {
+		p.loadState()
+	}
+
+	p.namespace = ws.AddNamespace(cfg.Name, pc.Server.MaxWebConnections, cfg.WebSettings, web.FrequencySettings{
+		LNBFrequency:      cfg.Source.LNBFrequency,
+		UpconverterOffset: cfg.Source.UpconverterOffset,
+		BeaconFrequency:   cfg.Processing.BeaconFrequency,
+	})
+	p.dedrifter.SetOnFFT(func(segFFT, fullFFT []float32) {
+		if fullFFT != nil {
+			p.namespace.BroadcastFFT(web.MessageTypeMainFFT, fullFFT)
+		}
+		if segFFT != nil {
+			p.namespace.BroadcastFFT(web.MessageTypeSegFFT, segFFT)
+		}
+	})
+
+	if decoder := p.dedrifter.PSKDecoder(); decoder != nil {
+		decoder.SetOnFrame(func(frame psk.Frame) {
+			p.log.Debug("Beacon frame %d decoded (MER %.1f dB): %s", frame.Number, frame.MER, frame.Text)
+			p.namespace.BroadcastJSON(web.MessageTypeBeaconFrame, frame)
+		})
+		p.namespace.HandleFunc("beacon.json", decoder.Handler())
+	}
+
+	if decoder := p.dedrifter.CWDecoder(); decoder != nil {
+		decoder.SetOnCharacter(func(status cw.Status) {
+			p.namespace.BroadcastJSON(web.MessageTypeCWText, status)
+		})
+		p.namespace.HandleFunc("cw.json", decoder.Handler())
+	}
+
+	if cfg.Signals.Enable {
+		var err error
+		p.signals, err = signals.MakeDetector(cfg.Name, cfg.Signals, float64(cfg.Source.SampleRate))
+		if err != nil {
+			p.log.Fatal("Error creating signal detector: %s", err)
+		}
+		p.signals.SetOnActivity(func(activity signals.Activity) {
+			p.namespace.BroadcastJSON(web.MessageTypeSignals, activity)
+		})
+		p.namespace.HandleFunc("signals.json", p.signals.Handler())
+	}
+
+	if cfg.Occupancy.Enable {
+		if p.signals == nil {
+			p.log.Fatal("Occupancy statistics need the signal detector to be enabled")
+		}
+
+		var err error
+		p.occupancy, err = occupancy.MakeRecorder(cfg.Name, cfg.Occupancy)
+		if err != nil {
+			p.log.Fatal("Error creating occupancy recorder: %s", err)
+		}
+		p.signals.SetOnSpectrum(p.occupancy.Add)
+		p.namespace.HandleFunc("occupancy.json", p.occupancy.Handler())
+		p.namespace.HandleFunc("occupancy.png", p.occupancy.ImageHandler())
+	}
+
+	if cfg.Alerts.Enable {
+		p.alerts = alerts.MakeManager(cfg.Name, cfg.Alerts)
+		p.alerts.SetOnAlert(func(alert alerts.Alert) {
+			if alert.State == alerts.StateFiring {
+				metrics.AlertsActive.WithLabelValues(p.name, alert.Rule).Set(1)
+				metrics.AlertsTotal.WithLabelValues(p.name, alert.Rule).Inc()
+			} else {
+				metrics.AlertsActive.WithLabelValues(p.name, alert.Rule).Set(0)
+			}
+			p.namespace.BroadcastJSON(web.MessageTypeAlert, alert)
+		})
+		p.namespace.HandleFunc("alerts.json", p.alerts.Handler())
+	}
+
+	if cfg.DriftLog.Enable {
+		var err error
+		p.driftLog, err = driftlog.MakeLogger(cfg.Name, cfg.DriftLog)
+		if err != nil {
+			p.log.Fatal("Error creating drift log: %s", err)
+		}
+		p.namespace.HandleFunc("drift.json", p.driftLog.Handler(cfg.DriftLog.MaxHours))
+		p.namespace.HandleFunc("stability.json", stability.Handler(p.driftLog, cfg.DriftLog.MaxHours, cfg.DriftLog.Taus))
+	}
+
+	return p
+}
+
+func (p *Pipeline) Start() error {
+	p.writer.Start()
+
+	p.client = rtltcp.MakeClient(p.name)
+	err := p.client.Connect(p.cfg.Source.Address)
+	if err != nil {
+		return err
+	}
+
+	p.dedrifter.ResetSampleClock()
+
+	metrics.MaxConnections.WithLabelValues(p.name).Add(float64(p.cfg.MaxRTLConnections))
+	metrics.ServerCenterFrequency.WithLabelValues(p.name).Set(p.rfFrequency(p.cfg.Source.CenterFrequency))
+	metrics.ServerSampleRate.WithLabelValues(p.name).Set(float64(p.cfg.Source.SampleRate))
+	metrics.SegmentSampleRate.WithLabelValues(p.name).Set(float64(p.dedrifter.GetSegmentSampleRate()))
+	metrics.SegmentCenterFrequency.WithLabelValues(p.name).Set(p.beaconAbsoluteFrequency)
+
+	if p.cfg.Processing.Resampler.Enable {
+		metrics.ResamplerPPM.WithLabelValues(p.name).Set(p.cfg.Processing.Resampler.PPM)
+	}
+
+	p.dedrifter.Spectrum().Start()
+
+	if residual := p.dedrifter.Residual(); residual != nil {
+		residual.Start()
+	}
+
+	if p.signals != nil {
+		p.signals.SetCenterFrequency(p.rfFrequency(p.cfg.Source.CenterFrequency))
+		p.signals.Start()
+	}
+
+	if p.alerts != nil {
+		p.alerts.Start()
+	}
+
+	if p.cfg.Source.HardwareCorrection.Enable {
+		p.log.Info("Hardware correction enabled in %s mode", p.cfg.Source.HardwareCorrection.Mode)
+		p.hardware = MakeHardwareCorrector(p.name, p.cfg.Source.HardwareCorrection, p.cfg.Source.CenterFrequency, p.client, p.dedrifter)
+	}
+
+	if p.cfg.Source.Temperature.Enable {
+		p.thermal, err = MakeTemperatureCompensator(p.name, p.cfg.Source.Temperature, p.dedrifter, p.hardware, p.writer)
+		if err != nil {
+			p.client.Stop()
+			return err
+		}
+		p.thermal.Start()
+	}
+
+	_ = p.client.SetSampleRate(p.cfg.Source.SampleRate)
+	_ = p.client.SetCenterFrequency(p.cfg.Source.CenterFrequency)
+	p.client.SetOnSamples(func(data []complex64) {
+		p.sampleFifo.Add(data)
+	})
+
+	dongleInfo := p.client.GetDongleInfo()
+	p.gainTable = rtltcp.MakeGainTable(dongleInfo)
+	if !p.gainTable.Valid() {
+		p.log.Warn("The gains of the %s tuner (%d reported) are not known, it will pick the gain closest to the requested", rtltcp.TunerTypeToName[dongleInfo.TunerType], dongleInfo.TunerGainCount)
+	}
+
+	if p.cfg.Source.AutoGain.Enable {
+		p.gain = MakeGainController(p.name, p.cfg.Source.AutoGain, float64(p.cfg.Source.Gain), p.gainTable, p.client)
+		err = p.gain.Start()
+		if err != nil {
+			p.client.Stop()
+			return err
+		}
+		p.setCurrentGain(p.gain.Gain())
+	} else {
+		_ = p.setGain(float64(p.cfg.Source.Gain))
+	}
+	p.publishGain()
+
+	p.server = rtltcp.MakeRTLTCPServer(p.cfg.RTLTCPAddress, p.name)
+	p.server.SetDongleInfo(p.client.GetDongleInfo())
+	p.server.SetOnCommand(p.onCommand)
+
+	err = p.server.Start()
+	if err != nil {
+		p.client.Stop()
+		return err
+	}
+
+	if p.cfg.Correction.Subscribe != "" {
+		p.log.Info("Using the correction published by %s", p.cfg.Correction.Subscribe)
+		go p.correctionLoop()
+	}
+
+	if p.cfg.Rigctl.Enable {
+		p.rig = MakePipelineRig(p, p.cfg.Rigctl)
+		p.rigctl = rigctl.MakeServer(p.cfg.Rigctl.Address, p.rig)
+		err = p.rigctl.Start()
+		if err != nil {
+			p.server.Stop()
+			p.client.Stop()
+			return err
+		}
+	}
+
+	if p.cfg.Uplink.Enable {
+		p.uplink = MakeUplinkCorrector(p.name, p.cfg.Uplink, p, p.rig)
+		p.uplink.Start()
+	}
+
+	p.dspRunning = true
+	go p.dsp()
+
+	return nil
+}
+
+func (p *Pipeline) correctionLoop() {
+	ratio := p.cfg.Correction.FrequencyRatio
+	if ratio == 0 {
+		ratio = 1
+	}
+
+	corrections := correctionBus.Subscribe(p.cfg.Correction.Subscribe)
+
+	for {
+		select {
+		case c := <-corrections:
+			p.dedrifter.SetExternalCorrection(c.Drift*float32(ratio), c.Locked)
+		case <-p.stopCorrection:
+			return
+		}
+	}
+}
+
+// GetStatus returns the dedrifter status with the drift corrected by the upstream tuner included in Drift
+func (p *Pipeline) GetStatus() dedrift.Status {
+	status := p.dedrifter.GetStatus()
+	status.Drift = totalDrift(status, p.hardware)
+	return status
+}
+
+func (p *Pipeline) publishCorrection() {
+	status := p.GetStatus()
+
+	correctionBus.Publish(dedrift.Correction{
+		Source:    p.name,
+		Drift:     status.Drift,
+		Frequency: p.beaconAbsoluteFrequency,
+		Locked:    status.Locked,
+		Time:      time.Now(),
+	})
+}
+
+func (p *Pipeline) Stop() {
+	if p.cfg.Correction.Subscribe != "" {
+		p.stopCorrection <- true
+	}
+
+	if p.dspRunning {
+		p.dspRunning = false
+		<-p.dspDone
+	}
+
+	p.dedrifter.Spectrum().Stop()
+
+	if residual := p.dedrifter.Residual(); residual != nil {
+		residual.Stop()
+	}
+
+	if p.thermal != nil {
+		p.thermal.Stop()
+	}
+
+	// The DSP loop is done, so this flushes the last state save and drift log records
+	p.writer.Stop()
+
+	if p.driftLog != nil {
+		p.driftLog.Close()
+	}
+
+	if p.signals != nil {
+		p.signals.Stop()
+	}
+
+	if p.occupancy != nil {
+		p.occupancy.Close()
+	}
+
+	if p.alerts != nil {
+		p.alerts.Stop()
+	}
+
+	if p.uplink != nil {
+		p.uplink.Stop()
+	}
+
+	if p.rigctl != nil {
+		p.rigctl.Stop()
+	}
+
+	if p.server != nil {
+		p.server.Stop()
+	}
+
+	if p.client != nil {
+		p.client.Stop()
+	}
+}
+
+func (p *Pipeline) onCommand(sessionId string, cmd rtltcp.Command) bool {
+	if cmd.Type == rtltcp.SetSampleRate {
+		sampleRate := binary.BigEndian.Uint32(cmd.Param[:])
+		if sampleRate != p.cfg.Source.SampleRate {
+			p.log.Error("Client asked for %d as sampleRate, but we cannot change it! Current: %d", sampleRate, p.cfg.Source.SampleRate)
+			p.log.Error("Closing connection with %s", sessionId)
+			return false
+		}
+		return true
+	}
+
+	if p.gain != nil && (cmd.Type == rtltcp.SetGain || cmd.Type == rtltcp.SetGainMode || cmd.Type == rtltcp.SetTunerGainByIndex) {
+		p.log.Warn("Ignoring command %s because the automatic gain control is enabled", rtltcp.CommandTypeToName[cmd.Type])
+		return true
+	}
+
+	if p.cfg.AllowControl {
+		if cmd.Type == rtltcp.SetFrequency {
+			_ = p.Tune(binary.BigEndian.Uint32(cmd.Param[:]))
+		} else {
+			_ = p.client.SendCommand(cmd)
+			p.trackGain(cmd)
+		}
+	} else {
+		p.log.Warn("Ignoring command %s because AllowControl is false", rtltcp.CommandTypeToName[cmd.Type])
+	}
+
+	return true
+}
+
+// Tune changes the upstream center frequency
+func (p *Pipeline) Tune(frequency uint32) error {
+	p.tuneLock.Lock()
+	defer p.tuneLock.Unlock()
+
+	var err error
+	if p.hardware != nil {
+		err = p.hardware.Tune(frequency)
+	} else {
+		err = p.client.SetCenterFrequency(frequency)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	p.OnChangeFrequency(frequency)
+
+	return nil
+}
+
+// setGain selects the tuner gain closest to gain, by its index when the tuner gains are known
+func (p *Pipeline) setGain(gain float64) error {
+	if !p.gainTable.Valid() {
+		p.setCurrentGain(gain)
+		return p.client.SetGain(uint32(gain * 10))
+	}
+
+	index, nearest := p.gainTable.Nearest(gain)
+	if nearest != gain {
+		p.log.Info("Using %.1f dB, the %s gain closest to %.1f dB", nearest, rtltcp.TunerTypeToName[p.gainTable.Tuner], gain)
+	}
+
+	err := p.client.SetGainMode(true)
+	if err != nil {
+		return err
+	}
+
+	p.setCurrentGain(nearest)
+	return p.client.SetTunerGainByIndex(uint32(index))
+}
+
+// trackGain follows the gain commands forwarded from the clients
+func (p *Pipeline) trackGain(cmd rtltcp.Command) {
+	value := binary.BigEndian.Uint32(cmd.Param[:])
+
+	var gain float64
+
+	switch cmd.Type {
+	case rtltcp.SetGain:
+		gain = float64(int32(value)) / 10
+		if p.gainTable.Valid() {
+			_, gain = p.gainTable.Nearest(gain)
+		}
+	case rtltcp.SetTunerGainByIndex:
+		if int(value) >= len(p.gainTable.Gains) {
+			return
+		}
+		gain = p.gainTable.Gains[value]
+	default:
+		return
+	}
+
+	p.setCurrentGain(gain)
+	p.publishGain()
+}
+
+func (p *Pipeline) setCurrentGain(gain float64) {
+	p.lock.Lock()
+	p.currentGain = gain
+	p.lock.Unlock()
+}
+
+func (p *Pipeline) getCurrentGain() float64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.currentGain
+}
+
+// publishGain updates the gain shown in the web settings and metrics
+func (p *Pipeline) publishGain() {
+	gain := p.getCurrentGain()
+	p.namespace.SetGainSettings(web.GainSettings{
+		Tuner: rtltcp.TunerTypeToName[p.gainTable.Tuner],
+		Gains: p.gainTable.Gains,
+		Gain:  gain,
+		Index: p.gainTable.Index(gain),
+		Auto:  p.gain != nil,
+	})
+	metrics.SourceGain.WithLabelValues(p.name).Set(gain)
+}
+
+// flushSampleFifo drops the blocks waiting for the DSP loop. The sample clock measurement is restarted, since the
+// dropped samples never reach its meter and would show as a slow sample clock.
+func (p *Pipeline) flushSampleFifo() int {
+	flushed := 0
+	for p.sampleFifo.Len() > 0 {
+		p.sampleFifo.Next()
+		flushed++
+	}
+
+	p.dedrifter.ResetSampleClock()
+
+	return flushed
+}
+
+// rfFrequency converts a source frequency to the absolute RF frequency
+func (p *Pipeline) rfFrequency(ifFrequency uint32) float64 {
+	return p.cfg.Source.ToRF(float64(ifFrequency))
+}
+
+// centerFrequency returns the current nominal center frequency of the source
+func (p *Pipeline) centerFrequency() uint32 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.cfg.Source.CenterFrequency
+}
+
+func (p *Pipeline) OnChangeFrequency(newFrequency uint32) {
+	p.lock.Lock()
+	oldFrequency := p.cfg.Source.CenterFrequency
+	beaconOffset := float32(p.cfg.Source.ToIF(p.beaconAbsoluteFrequency) - float64(newFrequency))
+	p.cfg.Processing.BeaconOffset = beaconOffset
+	p.cfg.Source.CenterFrequency = newFrequency
+	p.lock.Unlock()
+
+	p.log.Info("Changed center frequency from %.0f Hz to %.0f Hz. Recalculating.", p.rfFrequency(oldFrequency), p.rfFrequency(newFrequency))
+
+	if p.signals != nil {
+		p.signals.SetCenterFrequency(p.rfFrequency(newFrequency))
+	}
+
+	// Samples that were already received were captured with the old center frequency
+	flushed := p.flushSampleFifo()
+	p.log.Debug("Flushed %d sample blocks", flushed)
+
+	p.dedrifter.Retune(beaconOffset)
+	p.log.Debug("New Beacon Offset: %f Hz", beaconOffset)
+	metrics.ServerCenterFrequency.WithLabelValues(p.name).Set(p.rfFrequency(newFrequency))
+}
+
+func (p *Pipeline) updateMetrics() {
+	status := p.GetStatus()
+
+	metrics.LockOffset.WithLabelValues(p.name).Set(float64(status.Drift))
+	metrics.LockDetector.WithLabelValues(p.name).Set(float64(status.LockDetector))
+	metrics.SegmentCenterFrequency.WithLabelValues(p.name).Set(p.beaconAbsoluteFrequency + float64(status.Drift))
+	metrics.LoopBandwidth.WithLabelValues(p.name).Set(float64(status.LoopBandwidth))
+	metrics.LoopGear.WithLabelValues(p.name).Set(float64(status.Gear))
+	metrics.BeaconPower.WithLabelValues(p.name).Set(float64(status.BeaconPower))
+	metrics.NoiseFloor.WithLabelValues(p.name).Set(float64(status.NoiseFloor))
+	metrics.BeaconSNR.WithLabelValues(p.name).Set(float64(status.BeaconSNR))
+	metrics.BeaconCN0.WithLabelValues(p.name).Set(float64(status.BeaconCN0))
+	metrics.AGCGain.WithLabelValues(p.name).Set(float64(status.AGCGain))
+	metrics.Retunes.WithLabelValues(p.name).Add(float64(status.Retunes - p.lastRetunes))
+	p.lastRetunes = status.Retunes
+
+	levels := p.client.ReadLevels()
+	if levels.Samples > 0 {
+		metrics.ADCClipRate.WithLabelValues(p.name).Set(levels.ClipRate)
+		metrics.ADCClippedSamples.WithLabelValues(p.name).Add(float64(levels.Clipped))
+		metrics.ADCRMS.WithLabelValues(p.name).Set(levels.RMS)
+		metrics.ADCPeak.WithLabelValues(p.name).Set(levels.Peak)
+	}
+
+	if p.gain != nil {
+		p.gain.Update(levels)
+		if gain := p.gain.Gain(); gain != p.getCurrentGain() {
+			p.setCurrentGain(gain)
+			p.publishGain()
+		}
+	}
+
+	if decoder := p.dedrifter.PSKDecoder(); decoder != nil {
+		stats := decoder.GetStats()
+		metrics.PSKFrames.WithLabelValues(p.name).Add(float64(stats.Frames - p.lastPSKStats.Frames))
+		metrics.PSKCRCErrors.WithLabelValues(p.name).Add(float64(stats.CRCErrors - p.lastPSKStats.CRCErrors))
+		metrics.PSKMER.WithLabelValues(p.name).Set(float64(stats.MER))
+		metrics.BeaconDecoded.WithLabelValues(p.name).Set(boolToFloat(status.BeaconDecoded))
+		p.lastPSKStats = stats
+	}
+
+	if decoder := p.dedrifter.CWDecoder(); decoder != nil {
+		cwStatus := decoder.GetStatus()
+		metrics.CWConfidence.WithLabelValues(p.name).Set(float64(cwStatus.Confidence))
+		metrics.CWWPM.WithLabelValues(p.name).Set(float64(cwStatus.WPM))
+		metrics.CWCharacters.WithLabelValues(p.name).Add(float64(cwStatus.Characters - p.lastCWStatus.Characters))
+		metrics.BeaconVerified.WithLabelValues(p.name).Set(boolToFloat(status.BeaconVerified))
+		if cwStatus.Text != p.lastCWStatus.Text {
+			metrics.CWText.DeleteLabelValues(p.name, p.lastCWStatus.Text)
+			metrics.CWText.WithLabelValues(p.name, cwStatus.Text).Set(1)
+		}
+		p.lastCWStatus = cwStatus
+	}
+
+	if status.ResidualMeasured {
+		metrics.ResidualError.WithLabelValues(p.name).Set(float64(status.ResidualError))
+		metrics.ResidualWidth.WithLabelValues(p.name).Set(float64(status.ResidualWidth))
+		metrics.ResidualJitter.WithLabelValues(p.name).Set(float64(status.ResidualJitter))
+	}
+
+	fftDropped := p.dedrifter.Spectrum().GetDroppedFrames()
+	metrics.SpectrumDroppedFrames.WithLabelValues(p.name).Add(float64(fftDropped - p.lastDroppedFFTFrames))
+	p.lastDroppedFFTFrames = fftDropped
+
+	if p.signals != nil {
+		activity := p.signals.GetActivity()
+		total, dropped := p.signals.GetCounters()
+		metrics.SignalsActive.WithLabelValues(p.name).Set(float64(len(activity.Signals)))
+		metrics.SignalsNoiseFloor.WithLabelValues(p.name).Set(float64(activity.NoiseFloor))
+		metrics.SignalsTotal.WithLabelValues(p.name).Add(float64(total - p.lastSignals))
+		metrics.SignalsDroppedFrames.WithLabelValues(p.name).Add(float64(dropped - p.lastDroppedSignalFrames))
+		p.lastSignals = total
+		p.lastDroppedSignalFrames = dropped
+	}
+
+	if p.alerts != nil {
+		p.evaluateAlerts(status)
+	}
+
+	if status.HoldingCorrection {
+		metrics.CorrectionHold.WithLabelValues(p.name).Set(1)
+	} else {
+		metrics.CorrectionHold.WithLabelValues(p.name).Set(0)
+	}
+
+	if status.External {
+		metrics.ExternalCorrection.WithLabelValues(p.name).Set(1)
+	} else {
+		metrics.ExternalCorrection.WithLabelValues(p.name).Set(0)
+	}
+
+	if status.SampleClockMeasured {
+		metrics.SampleClockPPM.WithLabelValues(p.name).Set(status.SampleClockPPM)
+	}
+
+	if p.cfg.Processing.Resampler.Enable {
+		metrics.ResamplerPPM.WithLabelValues(p.name).Set(status.ResamplerPPM)
+	}
+}
+
+func (p *Pipeline) evaluateAlerts(status dedrift.Status) {
+	in := alerts.Input{
+		Time:            time.Now(),
+		Locked:          status.Locked,
+		BeaconFrequency: p.beaconAbsoluteFrequency,
+		BeaconBandwidth: p.cfg.Processing.Beacon.Bandwidth,
+		BeaconPower:     float64(status.BeaconPower),
+		NoiseFloor:      float64(status.NoiseFloor),
+	}
+
+	if p.signals != nil {
+		activity := p.signals.GetActivity()
+		if !activity.Time.IsZero() {
+			in.PassbandMeasured = true
+			in.PassbandNoise = float64(activity.NoiseFloor)
+			for _, s := range activity.Signals {
+				in.Carriers = append(in.Carriers, alerts.Carrier{
+					Frequency: s.Frequency,
+					Bandwidth: s.Bandwidth,
+					Power:     float64(s.Power),
+				})
+			}
+		}
+	}
+
+	p.alerts.Evaluate(in)
+
+	webhookErrors := p.alerts.GetWebhookErrors()
+	metrics.WebhookErrors.WithLabelValues(p.name).Add(float64(webhookErrors - p.lastWebhookErrors))
+	p.lastWebhookErrors = webhookErrors
+}
+
+func (p *Pipeline) dsp() {
+	p.log.Info("Starting DSP Loop")
+
+	for p.dspRunning {
+		for p.sampleFifo.Len() == 0 {
+			time.Sleep(time.Millisecond * 5)
+			if !p.dspRunning {
+				break
+			}
+		}
+
+		if !p.dspRunning {
+			break
+		}
+
+		data := p.dedrifter.Process(p.sampleFifo.Next().([]complex64))
+
+		if p.hardware != nil {
+			p.hardware.Update()
+		}
+
+		if p.cfg.Correction.Publish && time.Since(p.lastCorrectionPublish) > correctionPublishInterval {
+			p.publishCorrection()
+			p.lastCorrectionPublish = time.Now()
+		}
+
+		if p.cfg.Processing.State.Enable && time.Since(p.lastStateSave) > time.Duration(p.cfg.Processing.State.Interval*float64(time.Second)) {
+			p.saveState()
+			p.lastStateSave = time.Now()
+		}
+
+		if p.driftLog != nil && time.Since(p.lastDriftLog) > time.Duration(p.cfg.DriftLog.Interval*float64(time.Second)) {
+			p.logDrift()
+			p.lastDriftLog = time.Now()
+		}
+
+		if time.Since(p.lastShiftReport) > time.Second {
+			if p.thermal != nil {
+				p.thermal.Update()
+			}
+			p.updateMetrics()
+			p.lastShiftReport = time.Now()
+		}
+
+		if len(data) > 0 {
+			if p.signals != nil {
+				p.signals.Work(data)
+			}
+			p.server.ComplexBroadcast(data)
+		}
+	}
+
+	if p.cfg.Processing.State.Enable {
+		p.saveState()
+	}
+
+	p.dspDone <- true
+}
+
+// driftPPM returns the drift relative to the LNB LO, or to the beacon frequency when the LO is not known
+func (p *Pipeline) driftPPM(drift float32) float64 {
+	reference := p.cfg.Source.LNBFrequency
+	if reference == 0 {
+		reference = p.beaconAbsoluteFrequency
+	}
+
+	return float64(drift) / reference * 1e6
+}
+
+func (p *Pipeline) logDrift() {
+	status := p.GetStatus()
+
+	record := driftlog.Record{
+		Time:   time.Now(),
+		Drift:  float64(status.Drift),
+		PPM:    p.driftPPM(status.Drift),
+		Locked: status.Locked,
+		SNR:    float64(status.BeaconSNR),
+	}
+
+	if p.thermal != nil {
+		if t, ok := p.thermal.Temperature(); ok {
+			record.Temperature = &t
+		}
+	}
+
+	p.writer.Write("drift log", func() error {
+		return p.driftLog.Log(record)
+	})
+}
+
+// loadState seeds the dedrifter from the state file if it is recent enough
+func (p *Pipeline) loadState() {
+	stateCfg := p.cfg.Processing.State
+
+	state, err := dedrift.LoadState(stateCfg.File)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			p.log.Error("Error loading state from %s: %s", stateCfg.File, err)
+		}
+		return
+	}
+
+	age := time.Since(state.Time)
+	if age > time.Duration(stateCfg.MaxAge*float64(time.Second)) {
+		p.log.Info("Ignoring state from %s saved %s ago", stateCfg.File, age)
+		return
+	}
+
+	p.dedrifter.Seed(state)
+}
+
+// saveState queues the write of the current loop state to the state file.
+// The drift includes the hardware correction, since the upstream starts from the nominal frequency after a restart.
+func (p *Pipeline) saveState() {
+	state := p.dedrifter.GetState()
+	state.Drift = p.GetStatus().Drift
+
+	filename := p.cfg.Processing.State.File
+	p.writer.Write("state to "+filename, func() error {
+		return dedrift.SaveState(filename, state)
+	})
+}
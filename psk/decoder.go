@@ -0,0 +1,310 @@
+package psk
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/racerxdl/qo100-dedrift/channel"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"github.com/racerxdl/segdsp/dsp"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	samplesPerSymbol      = 10
+	syncGainMu            = 0.05
+	syncRelativeLimit     = 0.005
+	merAlpha              = 0.01
+	minPrintableRatio     = 0.9
+	defaultFrameHistory   = 20
+	crcLength             = 2
+	maxSyncWordLength     = 64
+	symbolAmplitudeWarmup = 16
+)
+
+// Frame is a frame decoded from the PSK beacon
+type Frame struct {
+	Time     time.Time `json:"time"`
+	Number   int       `json:"number"`
+	Data     []byte    `json:"-"`
+	Text     string    `json:"text,omitempty"` // Set when the frame is a text bulletin
+	MER      float32   `json:"mer"`            // MER in dB over the frame symbols
+	Inverted bool      `json:"inverted"`
+}
+
+// Stats are the decoder counters
+type Stats struct {
+	Frames    int       `json:"frames"`
+	CRCErrors int       `json:"crcErrors"`
+	Syncs     int       `json:"syncs"`
+	MER       float32   `json:"mer"` // Averaged MER in dB
+	LastFrame time.Time `json:"lastFrame"`
+}
+
+type OnFrame func(frame Frame)
+
+// Decoder demodulates the BPSK beacon from the carrier locked segment stream.
+// It filters and decimates the stream, recovers the symbol timing, slices the symbols and decodes the frames
+// that follow the sync word and pass the CRC check.
+//
+// The default settings follow the AMSAT Phase 3 telemetry block format used by the 400 bps BPSK beacon of AO-40 and
+// reused by the QO-100 narrowband beacon: the 32 bit sync vector 0x3915ED30, followed by a 512 byte block and its
+// CRC-16 CCITT sent big endian.
+type Decoder struct {
+	cfg        config.PSKDecoderConfig
+	decimator  *channel.Decimator
+	symbolSync *SymbolSync
+	framer     *Framer
+	onFrame    OnFrame
+
+	lastBit     byte
+	amplitude   float64
+	symbolCount int
+	merSignal   float64
+	merError    float64
+	frameSignal float64
+	frameError  float64
+
+	lock      sync.Mutex
+	stats     Stats
+	frames    []Frame
+	maxFrames int
+}
+
+// ParseSyncWord parses a hexadecimal sync word and returns its value and length in bits
+func ParseSyncWord(s string) (uint64, int, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if len(data) == 0 || len(data)*8 > maxSyncWordLength {
+		return 0, 0, fmt.Errorf("sync word should have between 1 and %d bytes", maxSyncWordLength/8)
+	}
+
+	v := uint64(0)
+	for _, b := range data {
+		v = v<<8 | uint64(b)
+	}
+
+	return v, len(data) * 8, nil
+}
+
+func MakeDecoder(cfg config.PSKDecoderConfig, sampleRate float32) (*Decoder, error) {
+	syncWord, syncBits, err := ParseSyncWord(cfg.SyncWord)
+	if err != nil {
+		return nil, err
+	}
+
+	decimation := int(float64(sampleRate) / (cfg.SymbolRate * samplesPerSymbol))
+	if decimation < 1 {
+		decimation = 1
+	}
+
+	sps := float64(sampleRate) / float64(decimation) / cfg.SymbolRate
+
+	maxFrames := cfg.FrameHistory
+	if maxFrames <= 0 {
+		maxFrames = defaultFrameHistory
+	}
+
+	return &Decoder{
+		cfg:        cfg,
+		decimator:  channel.MakeDecimator(decimation, dsp.MakeLowPass(1, float64(sampleRate), cfg.SymbolRate*0.6, cfg.SymbolRate*0.4)),
+		symbolSync: MakeSymbolSync(sps, syncGainMu, syncRelativeLimit),
+		framer:     MakeFramer(syncWord, syncBits, cfg.MaxSyncErrors, cfg.FrameLength+crcLength),
+		frames:     make([]Frame, 0),
+		maxFrames:  maxFrames,
+	}, nil
+}
+
+// SetOnFrame sets the callback called for each valid frame. It runs in the goroutine that calls Work.
+func (d *Decoder) SetOnFrame(cb OnFrame) {
+	d.onFrame = cb
+}
+
+// Work processes samples from the Costas Loop output, where the beacon carrier is at DC and the symbols are on the I axis
+func (d *Decoder) Work(samples []complex64) {
+	decimated := d.decimator.Work(samples)
+	if len(decimated) == 0 {
+		return
+	}
+
+	for _, s := range d.symbolSync.Work(decimated) {
+		d.pushSymbol(s)
+	}
+}
+
+func (d *Decoder) pushSymbol(s complex64) {
+	i := float64(real(s))
+	q := float64(imag(s))
+
+	d.amplitude += merAlpha * (math.Abs(i) - d.amplitude)
+	d.symbolCount++
+
+	ideal := d.amplitude
+	bit := byte(1)
+	if i < 0 {
+		ideal = -ideal
+		bit = 0
+	}
+
+	signal := ideal * ideal
+	noise := (i-ideal)*(i-ideal) + q*q
+
+	if d.symbolCount > symbolAmplitudeWarmup {
+		d.merSignal += merAlpha * (signal - d.merSignal)
+		d.merError += merAlpha * (noise - d.merError)
+	}
+
+	if d.cfg.Differential {
+		bit, d.lastBit = bit^d.lastBit, bit
+	}
+
+	if d.framer.inFrame { // Frame symbol
+		d.frameSignal += signal
+		d.frameError += noise
+	}
+
+	frame, ok := d.framer.PushBit(bit)
+
+	if !ok && d.framer.inFrame && d.framer.bitCount == 0 { // Sync word found
+		d.frameSignal = 0
+		d.frameError = 0
+	}
+
+	d.lock.Lock()
+	d.stats.Syncs = d.framer.GetSyncCount()
+	if d.merError > 0 {
+		d.stats.MER = merDB(d.merSignal, d.merError)
+	}
+	d.lock.Unlock()
+
+	if ok {
+		d.handleFrame(frame)
+	}
+}
+
+func merDB(signal, noise float64) float32 {
+	if noise <= 0 {
+		return 0
+	}
+	return float32(10 * math.Log10(signal/noise))
+}
+
+// frameText returns the printable text of the frame, or false if the frame is not text
+func frameText(data []byte) (string, bool) {
+	printable := 0
+	b := strings.Builder{}
+
+	for _, c := range data {
+		switch {
+		case c == '\r' || c == '\n':
+			printable++
+			b.WriteByte('\n')
+		case c >= 0x20 && c < 0x7F:
+			printable++
+			b.WriteByte(c)
+		case c == 0:
+			printable++
+		default:
+			b.WriteByte('.')
+		}
+	}
+
+	if float64(printable) < minPrintableRatio*float64(len(data)) {
+		return "", false
+	}
+
+	return strings.TrimSpace(b.String()), true
+}
+
+func (d *Decoder) handleFrame(data []byte) {
+	d.lock.Lock()
+
+	if crc16(data) != 0 {
+		d.stats.CRCErrors++
+		d.lock.Unlock()
+		return
+	}
+
+	d.stats.Frames++
+	d.stats.LastFrame = time.Now()
+
+	payload := make([]byte, len(data)-crcLength)
+	copy(payload, data)
+
+	frame := Frame{
+		Time:     d.stats.LastFrame,
+		Number:   d.stats.Frames,
+		Data:     payload,
+		MER:      merDB(d.frameSignal, d.frameError),
+		Inverted: d.framer.IsInverted(),
+	}
+
+	if text, ok := frameText(payload); ok {
+		frame.Text = text
+	}
+
+	d.frames = append(d.frames, frame)
+	if len(d.frames) > d.maxFrames {
+		d.frames = d.frames[len(d.frames)-d.maxFrames:]
+	}
+
+	d.lock.Unlock()
+
+	if d.onFrame != nil {
+		d.onFrame(frame)
+	}
+}
+
+// Reset drops the partial frame and the symbol history, like after a retune
+func (d *Decoder) Reset() {
+	d.framer.Reset()
+	d.decimator.Reset()
+	d.symbolSync.Reset()
+}
+
+// GetStats returns the decoder counters. It is safe to be called from any goroutine.
+func (d *Decoder) GetStats() Stats {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.stats
+}
+
+// GetFrames returns the last decoded frames. It is safe to be called from any goroutine.
+func (d *Decoder) GetFrames() []Frame {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	frames := make([]Frame, len(d.frames))
+	copy(frames, d.frames)
+	return frames
+}
+
+// Decoded returns true if a frame was decoded in the last validFor
+func (d *Decoder) Decoded(validFor time.Duration) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return !d.stats.LastFrame.IsZero() && time.Since(d.stats.LastFrame) < validFor
+}
+
+// Handler serves the decoder counters and the last decoded frames
+func (d *Decoder) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, _ := json.Marshal(struct {
+			Stats  Stats   `json:"stats"`
+			Frames []Frame `json:"frames"`
+		}{
+			Stats:  d.GetStats(),
+			Frames: d.GetFrames(),
+		})
+
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(200)
+		_, _ = w.Write(data)
+	}
+}
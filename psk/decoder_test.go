@@ -0,0 +1,176 @@
+package psk
+
+import (
+	"github.com/racerxdl/qo100-dedrift/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSampleRate = 24000
+
+func TestCRC16(t *testing.T) {
+	data := []byte("123456789")
+	if crc := crc16(data); crc != 0x29B1 {
+		t.Fatalf("expected CRC 0x29B1, got 0x%04X", crc)
+	}
+
+	data = append(data, 0x29, 0xB1)
+	if crc := crc16(data); crc != 0 {
+		t.Errorf("expected the CRC over the data and its CRC to be zero, got 0x%04X", crc)
+	}
+}
+
+// testFrame returns a frame of length bytes filled with text, like a bulletin, followed by its CRC.
+// The symbol timing is only recovered on transitions, so the frame is not padded with zeros.
+func testFrame(text string, length int) []byte {
+	frame := make([]byte, length+crcLength)
+	copy(frame, strings.Repeat(text, length/len(text)+1))
+	crc := crc16(frame[:length])
+	frame[length] = byte(crc >> 8)
+	frame[length+1] = byte(crc)
+	return frame
+}
+
+func bytesToBits(data []byte) []byte {
+	bits := make([]byte, 0, len(data)*8)
+	for _, b := range data {
+		for i := 7; i >= 0; i-- {
+			bits = append(bits, (b>>uint(i))&1)
+		}
+	}
+	return bits
+}
+
+// modulate returns the baseband BPSK samples of the bits, like the Costas Loop output with the carrier at DC
+func modulate(bits []byte, symbolRate float64, amplitude float32) []complex64 {
+	sps := testSampleRate / symbolRate
+	samples := make([]complex64, int(float64(len(bits))*sps))
+	for i := range samples {
+		v := amplitude
+		if bits[int(float64(i)/sps)] == 0 {
+			v = -v
+		}
+		samples[i] = complex(v, 0)
+	}
+	return samples
+}
+
+// transmission returns a preamble, the sync word and each frame, with a gap between the frames
+func transmission(cfg config.PSKDecoderConfig, frames ...[]byte) []byte {
+	syncWord, syncBits, _ := ParseSyncWord(cfg.SyncWord)
+
+	var bits []byte
+	for _, frame := range frames {
+		for i := 0; i < 64; i++ {
+			bits = append(bits, byte(i&1))
+		}
+		for i := syncBits - 1; i >= 0; i-- {
+			bits = append(bits, byte(syncWord>>uint(i))&1)
+		}
+		bits = append(bits, bytesToBits(frame)...)
+	}
+
+	for i := 0; i < 64; i++ {
+		bits = append(bits, byte(i&1))
+	}
+
+	return bits
+}
+
+func runDecoder(t *testing.T, cfg config.PSKDecoderConfig, samples []complex64) (*Decoder, []Frame) {
+	d, err := MakeDecoder(cfg, testSampleRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var frames []Frame
+	d.SetOnFrame(func(frame Frame) {
+		frames = append(frames, frame)
+	})
+
+	// Feed in blocks like the dedrifter does
+	for i := 0; i < len(samples); i += 1000 {
+		end := i + 1000
+		if end > len(samples) {
+			end = len(samples)
+		}
+		d.Work(samples[i:end])
+	}
+
+	return d, frames
+}
+
+func TestDecoderFrames(t *testing.T) {
+	cfg := config.DefaultConfig.Processing.PSKDecoder
+	text := "QO-100 BPSK BEACON TEST "
+	expected := strings.TrimSpace(string(testFrame(text, cfg.FrameLength)[:cfg.FrameLength]))
+
+	good := testFrame(text, cfg.FrameLength)
+	bad := testFrame(text, cfg.FrameLength)
+	bad[10] ^= 0x04
+
+	samples := modulate(transmission(cfg, good, bad, good), cfg.SymbolRate, 0.5)
+	d, frames := runDecoder(t, cfg, samples)
+
+	stats := d.GetStats()
+	if stats.Frames != 2 || len(frames) != 2 {
+		t.Fatalf("expected 2 valid frames, got %d (%d callbacks)", stats.Frames, len(frames))
+	}
+	if stats.CRCErrors != 1 {
+		t.Errorf("expected 1 CRC error, got %d", stats.CRCErrors)
+	}
+	if stats.Syncs != 3 {
+		t.Errorf("expected 3 syncs, got %d", stats.Syncs)
+	}
+
+	for _, frame := range frames {
+		if frame.Text != expected {
+			t.Errorf("expected the frame text %q, got %q", expected, frame.Text)
+		}
+		if len(frame.Data) != cfg.FrameLength {
+			t.Errorf("expected %d bytes without the CRC, got %d", cfg.FrameLength, len(frame.Data))
+		}
+		if frame.Inverted {
+			t.Errorf("expected a frame that is not inverted")
+		}
+		if frame.MER < 20 {
+			t.Errorf("expected a clean signal to have a high MER, got %f dB", frame.MER)
+		}
+	}
+
+	if !d.Decoded(time.Minute) {
+		t.Errorf("expected the decoder to report a recent frame")
+	}
+}
+
+func TestDecoderInverted(t *testing.T) {
+	cfg := config.DefaultConfig.Processing.PSKDecoder
+	text := "INVERTED "
+	expected := strings.TrimSpace(string(testFrame(text, cfg.FrameLength)[:cfg.FrameLength]))
+
+	// The Costas Loop can lock 180 degrees off, which inverts every symbol
+	samples := modulate(transmission(cfg, testFrame(text, cfg.FrameLength)), cfg.SymbolRate, -0.5)
+	_, frames := runDecoder(t, cfg, samples)
+
+	if len(frames) != 1 {
+		t.Fatalf("expected 1 frame, got %d", len(frames))
+	}
+	if !frames[0].Inverted {
+		t.Errorf("expected the frame to be reported as inverted")
+	}
+	if frames[0].Text != expected {
+		t.Errorf("expected the frame text %q, got %q", expected, frames[0].Text)
+	}
+}
+
+func TestFrameText(t *testing.T) {
+	if _, ok := frameText([]byte{0x01, 0x02, 0x80, 0xFF}); ok {
+		t.Errorf("expected binary data not to be text")
+	}
+
+	text, ok := frameText([]byte("HELLO\rWORLD\x00\x00"))
+	if !ok || text != "HELLO\nWORLD" {
+		t.Errorf("expected the text %q, got %q (%v)", "HELLO\nWORLD", text, ok)
+	}
+}
@@ -0,0 +1,98 @@
+package psk
+
+import (
+	"math/bits"
+)
+
+// crc16 computes the CRC-16 CCITT (polynomial 0x1021, initial value 0xFFFF).
+// Running it over the data followed by its big endian CRC results in zero.
+func crc16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// Framer finds the sync word in the bit stream and collects the frame that follows it.
+// Since BPSK has a 180 degrees ambiguity, an inverted sync word is also accepted and inverts the frame bits.
+type Framer struct {
+	syncWord      uint64
+	syncMask      uint64
+	maxSyncErrors int
+	frameBits     int
+
+	shift     uint64
+	inFrame   bool
+	inverted  bool
+	frame     []byte
+	bitCount  int
+	syncCount int
+}
+
+// MakeFramer creates a framer for frames of frameLength bytes (including the CRC) after a syncBits long sync word
+func MakeFramer(syncWord uint64, syncBits, maxSyncErrors, frameLength int) *Framer {
+	return &Framer{
+		syncWord:      syncWord,
+		syncMask:      (uint64(1) << uint(syncBits)) - 1,
+		maxSyncErrors: maxSyncErrors,
+		frameBits:     frameLength * 8,
+		frame:         make([]byte, frameLength),
+	}
+}
+
+// PushBit feeds a bit and returns the frame when it is complete. The returned slice is reused by the next frame.
+func (f *Framer) PushBit(bit byte) ([]byte, bool) {
+	if f.inFrame {
+		if f.inverted {
+			bit ^= 1
+		}
+
+		idx := f.bitCount / 8
+		f.frame[idx] = f.frame[idx]<<1 | bit
+		f.bitCount++
+
+		if f.bitCount == f.frameBits {
+			f.inFrame = false
+			f.shift = 0
+			return f.frame, true
+		}
+
+		return nil, false
+	}
+
+	f.shift = (f.shift<<1 | uint64(bit)) & f.syncMask
+
+	errors := bits.OnesCount64(f.shift ^ f.syncWord)
+	inverted := bits.OnesCount64(^f.shift&f.syncMask ^ f.syncWord)
+
+	if errors <= f.maxSyncErrors || inverted <= f.maxSyncErrors {
+		f.inFrame = true
+		f.inverted = inverted < errors
+		f.bitCount = 0
+		f.syncCount++
+	}
+
+	return nil, false
+}
+
+// GetSyncCount returns how many times the sync word was found
+func (f *Framer) GetSyncCount() int {
+	return f.syncCount
+}
+
+func (f *Framer) IsInverted() bool {
+	return f.inverted
+}
+
+func (f *Framer) Reset() {
+	f.shift = 0
+	f.inFrame = false
+}
@@ -0,0 +1,83 @@
+package psk
+
+import "math"
+
+// SymbolSync recovers the symbol timing of a BPSK stream with a Gardner timing error detector.
+// It is used instead of segdsp ComplexClockRecovery, which does not handle fractional sample positions.
+type SymbolSync struct {
+	omega      float64 // Samples per symbol
+	omegaMid   float64
+	omegaLimit float64
+	gainMu     float64
+	gainOmega  float64
+
+	buffer  []complex64
+	next    float64 // Position of the next symbol in buffer
+	last    complex64
+	power   float64
+	symbols []complex64
+}
+
+// MakeSymbolSync creates a symbol synchronizer for sps samples per symbol.
+// The symbol rate can deviate up to relativeLimit from the nominal.
+func MakeSymbolSync(sps, gainMu, relativeLimit float64) *SymbolSync {
+	return &SymbolSync{
+		omega:      sps,
+		omegaMid:   sps,
+		omegaLimit: sps * relativeLimit,
+		gainMu:     gainMu,
+		gainOmega:  gainMu * gainMu / 4,
+		next:       math.Ceil(sps),
+		power:      1,
+	}
+}
+
+func (s *SymbolSync) interpolate(position float64) complex64 {
+	i := int(position)
+	mu := float32(position - float64(i))
+	return s.buffer[i]*complex(1-mu, 0) + s.buffer[i+1]*complex(mu, 0)
+}
+
+// Work returns the symbols found in samples. The returned slice is reused by the next call.
+func (s *SymbolSync) Work(samples []complex64) []complex64 {
+	s.buffer = append(s.buffer, samples...)
+	s.symbols = s.symbols[:0]
+
+	for int(s.next)+1 < len(s.buffer) {
+		current := s.interpolate(s.next)
+		middle := s.interpolate(s.next - s.omega/2)
+
+		diff := current - s.last
+		e := float64(real(diff)*real(middle) + imag(diff)*imag(middle))
+
+		p := float64(real(current)*real(current) + imag(current)*imag(current))
+		s.power += 0.01 * (p - s.power)
+		if s.power > 0 {
+			e /= s.power
+		}
+		e = math.Max(-1, math.Min(1, e))
+
+		s.omega -= s.gainOmega * s.omegaMid * e
+		s.omega = s.omegaMid + math.Max(-s.omegaLimit, math.Min(s.omegaLimit, s.omega-s.omegaMid))
+		s.next += s.omega - s.gainMu*s.omegaMid*e
+
+		s.last = current
+		s.symbols = append(s.symbols, current)
+	}
+
+	// Keep enough history to interpolate the middle of the next symbol
+	drop := int(s.next-s.omegaMid-s.omegaLimit) - 1
+	if drop > 0 {
+		s.buffer = s.buffer[:copy(s.buffer, s.buffer[drop:])]
+		s.next -= float64(drop)
+	}
+
+	return s.symbols
+}
+
+// Reset drops the buffered samples keeping the current symbol rate estimate
+func (s *SymbolSync) Reset() {
+	s.buffer = s.buffer[:0]
+	s.next = math.Ceil(s.omega)
+	s.last = 0
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"sync"
+)
+
+const (
+	RigctlTargetSource  = "source"
+	RigctlTargetVirtual = "virtual"
+)
+
+// PipelineRig exposes a Pipeline as a rig to the rigctl server.
+// Since the served samples are already dedrifted, the dial frequency is the absolute RF frequency plus the
+// FrequencyOffset, without the drift measured on the beacon.
+type PipelineRig struct {
+	sync.Mutex
+	pipeline         *Pipeline
+	cfg              config.RigctlConfig
+	virtualFrequency uint32
+	mode             string
+	passband         int
+}
+
+func MakePipelineRig(pipeline *Pipeline, cfg config.RigctlConfig) *PipelineRig {
+	return &PipelineRig{
+		pipeline:         pipeline,
+		cfg:              cfg,
+		virtualFrequency: pipeline.centerFrequency(),
+		mode:             cfg.Mode,
+		passband:         cfg.Passband,
+	}
+}
+
+// NominalFrequency returns the dial frequency, which is the center of the dedrifted stream
+func (r *PipelineRig) NominalFrequency() float64 {
+	r.Lock()
+	defer r.Unlock()
+
+	frequency := r.pipeline.centerFrequency()
+	if r.cfg.Target == RigctlTargetVirtual {
+		frequency = r.virtualFrequency
+	}
+
+	return r.pipeline.rfFrequency(frequency) + r.cfg.FrequencyOffset
+}
+
+func (r *PipelineRig) GetFrequency() float64 {
+	return r.NominalFrequency()
+}
+
+func (r *PipelineRig) SetFrequency(frequency float64) error {
+	r.Lock()
+	defer r.Unlock()
+
+	ifFrequency := r.pipeline.cfg.Source.ToIF(frequency - r.cfg.FrequencyOffset)
+	if ifFrequency <= 0 {
+		return fmt.Errorf("frequency %f is below the source band", frequency)
+	}
+
+	switch r.cfg.Target {
+	case RigctlTargetVirtual:
+		center := float64(r.pipeline.centerFrequency())
+		halfBand := float64(r.pipeline.cfg.Source.SampleRate) / 2
+		if ifFrequency < center-halfBand || ifFrequency > center+halfBand {
+			return fmt.Errorf("frequency %f is outside the received band", frequency)
+		}
+		r.virtualFrequency = uint32(ifFrequency)
+		return nil
+	case RigctlTargetSource:
+		if !r.pipeline.cfg.AllowControl {
+			return fmt.Errorf("AllowControl is false")
+		}
+		return r.pipeline.Tune(uint32(ifFrequency))
+	}
+
+	return fmt.Errorf("invalid rigctl target %q", r.cfg.Target)
+}
+
+func (r *PipelineRig) GetMode() (string, int) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.mode, r.passband
+}
+
+func (r *PipelineRig) SetMode(mode string, passband int) error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.mode = mode
+	if passband > 0 {
+		r.passband = passband
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package rigctl
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const clientIOTimeout = 2 * time.Second
+
+// Client is a client for rigctld compatible servers
+type Client struct {
+	sync.Mutex
+	address string
+	conn    net.Conn
+	reader  *bufio.Reader
+}
+
+func MakeClient(address string) *Client {
+	return &Client{
+		address: address,
+	}
+}
+
+func (c *Client) Connect() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.conn != nil {
+		return nil
+	}
+
+	conn, err := net.DialTimeout("tcp", c.address, clientIOTimeout)
+	if err != nil {
+		return err
+	}
+
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+
+	return nil
+}
+
+func (c *Client) IsConnected() bool {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.conn != nil
+}
+
+func (c *Client) Close() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.close()
+}
+
+func (c *Client) close() {
+	if c.conn != nil {
+		_ = c.conn.Close()
+		c.conn = nil
+		c.reader = nil
+	}
+}
+
+// command sends a command and returns the first line of the response. Connection errors close the connection.
+func (c *Client) command(cmd string) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.conn == nil {
+		return "", fmt.Errorf("not connected")
+	}
+
+	_ = c.conn.SetDeadline(time.Now().Add(clientIOTimeout))
+
+	_, err := c.conn.Write([]byte(cmd + "\n"))
+	if err != nil {
+		c.close()
+		return "", err
+	}
+
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		c.close()
+		return "", err
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+func parseReport(line string) error {
+	if !strings.HasPrefix(line, "RPRT ") {
+		return fmt.Errorf("unexpected response %q", line)
+	}
+
+	code, err := strconv.Atoi(strings.TrimPrefix(line, "RPRT "))
+	if err != nil {
+		return fmt.Errorf("unexpected response %q", line)
+	}
+
+	if code != RigOK {
+		return fmt.Errorf("rig returned error %d", code)
+	}
+
+	return nil
+}
+
+func (c *Client) SetFrequency(frequency float64) error {
+	line, err := c.command(fmt.Sprintf("F %d", int64(frequency)))
+	if err != nil {
+		return err
+	}
+
+	return parseReport(line)
+}
+
+func (c *Client) GetFrequency() (float64, error) {
+	line, err := c.command("f")
+	if err != nil {
+		return 0, err
+	}
+
+	if strings.HasPrefix(line, "RPRT ") {
+		return 0, parseReport(line)
+	}
+
+	return strconv.ParseFloat(line, 64)
+}
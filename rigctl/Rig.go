@@ -0,0 +1,9 @@
+package rigctl
+
+// Rig is the radio controlled by the rigctl Server. Frequencies are in Hertz.
+type Rig interface {
+	GetFrequency() float64
+	SetFrequency(frequency float64) error
+	GetMode() (mode string, passband int)
+	SetMode(mode string, passband int) error
+}
@@ -0,0 +1,223 @@
+package rigctl
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/quan-to/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hamlib error codes
+const (
+	RigOK      = 0
+	RigEInval  = -1
+	RigEIO     = -6
+	RigENAvail = -11
+)
+
+const clientTimeout = 5 * time.Minute
+
+var log = slog.Scope("Rigctl Server")
+
+// Server is a rigctld compatible TCP server
+type Server struct {
+	address  string
+	rig      Rig
+	running  bool
+	listener net.Listener
+	connLock sync.Mutex
+	conns    map[net.Conn]bool
+}
+
+func MakeServer(address string, rig Rig) *Server {
+	return &Server{
+		address: address,
+		rig:     rig,
+		conns:   map[net.Conn]bool{},
+	}
+}
+
+func (s *Server) Start() error {
+	if s.running {
+		return fmt.Errorf("already running")
+	}
+
+	l, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return err
+	}
+
+	s.listener = l
+	s.running = true
+	log.Info("Listening on %s", s.address)
+	go s.loop()
+
+	return nil
+}
+
+// Addr returns the address the server is listening on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *Server) Stop() {
+	if s.running {
+		s.running = false
+		_ = s.listener.Close()
+		s.connLock.Lock()
+		for c := range s.conns {
+			_ = c.Close()
+		}
+		s.connLock.Unlock()
+	}
+}
+
+func (s *Server) loop() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if strings.Contains(err.Error(), "use of closed") {
+				return
+			}
+			log.Error("Error accepting: %s", err)
+			continue
+		}
+
+		go s.handleConnection(conn)
+	}
+}
+
+func (s *Server) handleConnection(conn net.Conn) {
+	log.Info("Received connection from %s", conn.RemoteAddr())
+	s.connLock.Lock()
+	s.conns[conn] = true
+	s.connLock.Unlock()
+
+	reader := bufio.NewReader(conn)
+
+	// Stop closes the connections, which ends the loop
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(clientTimeout))
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+
+		response, quit := s.handleCommand(strings.TrimSpace(line))
+		if quit {
+			break
+		}
+
+		_, err = conn.Write([]byte(response))
+		if err != nil {
+			break
+		}
+	}
+
+	s.connLock.Lock()
+	delete(s.conns, conn)
+	s.connLock.Unlock()
+	_ = conn.Close()
+	log.Info("Connection from %s closed", conn.RemoteAddr())
+}
+
+func report(code int) string {
+	return fmt.Sprintf("RPRT %d\n", code)
+}
+
+// handleCommand processes a single command line and returns the response and if the connection should be closed
+func (s *Server) handleCommand(line string) (string, bool) {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return "", false
+	}
+
+	cmd := args[0]
+	args = args[1:]
+
+	// Short commands can be sent without spaces, like F14074000
+	if len(cmd) > 1 && cmd[0] != '\\' {
+		args = append([]string{cmd[1:]}, args...)
+		cmd = cmd[:1]
+	}
+
+	switch cmd {
+	case "f", "\\get_freq":
+		return fmt.Sprintf("%d\n", int64(s.rig.GetFrequency())), false
+	case "F", "\\set_freq":
+		if len(args) < 1 {
+			return report(RigEInval), false
+		}
+		frequency, err := strconv.ParseFloat(args[0], 64)
+		if err != nil {
+			return report(RigEInval), false
+		}
+		err = s.rig.SetFrequency(frequency)
+		if err != nil {
+			log.Error("Error setting frequency to %f: %s", frequency, err)
+			return report(RigEIO), false
+		}
+		return report(RigOK), false
+	case "m", "\\get_mode":
+		mode, passband := s.rig.GetMode()
+		return fmt.Sprintf("%s\n%d\n", mode, passband), false
+	case "M", "\\set_mode":
+		if len(args) < 1 {
+			return report(RigEInval), false
+		}
+		passband := 0
+		if len(args) > 1 {
+			passband, _ = strconv.Atoi(args[1])
+		}
+		err := s.rig.SetMode(strings.ToUpper(args[0]), passband)
+		if err != nil {
+			return report(RigEInval), false
+		}
+		return report(RigOK), false
+	case "v", "\\get_vfo":
+		return "VFOA\n", false
+	case "t", "\\get_ptt":
+		return "0\n", false
+	case "\\chk_vfo":
+		return "0\n", false
+	case "\\dump_state":
+		return dumpState(), false
+	case "q", "Q", "\\quit":
+		return "", true
+	}
+
+	return report(RigENAvail), false
+}
+
+func dumpState() string {
+	lines := []string{
+		"0", // Protocol version
+		"1", // Rig model (dummy)
+		"2", // ITU region
+		"0.000000 100000000000.000000 0x1ff -1 -1 0x3 0x0", // RX range
+		"0 0 0 0 0 0 0", // End of RX ranges
+		"0 0 0 0 0 0 0", // End of TX ranges
+		"0x1ff 1",       // Tuning steps
+		"0 0",           // End of tuning steps
+		"0x1ff 0",       // Filters
+		"0 0",           // End of filters
+		"0",             // Max RIT
+		"0",             // Max XIT
+		"0",             // Max IF Shift
+		"0",             // Announces
+		"",              // Preamp
+		"",              // Attenuator
+		"0x0",           // Get Functions
+		"0x0",           // Set Functions
+		"0x0",           // Get Levels
+		"0x0",           // Set Levels
+		"0x0",           // Get Parameters
+		"0x0",           // Set Parameters
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
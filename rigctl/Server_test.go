@@ -0,0 +1,156 @@
+package rigctl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type pipeRig struct {
+	frequency float64
+	mode      string
+	passband  int
+	setError  error
+}
+
+func (r *pipeRig) GetFrequency() float64 {
+	return r.frequency
+}
+
+func (r *pipeRig) SetFrequency(frequency float64) error {
+	if r.setError != nil {
+		return r.setError
+	}
+	r.frequency = frequency
+	return nil
+}
+
+func (r *pipeRig) GetMode() (string, int) {
+	return r.mode, r.passband
+}
+
+func (r *pipeRig) SetMode(mode string, passband int) error {
+	if mode != "USB" && mode != "LSB" {
+		return fmt.Errorf("invalid mode %s", mode)
+	}
+	r.mode = mode
+	r.passband = passband
+	return nil
+}
+
+// startPipe runs handleConnection on one end of a pipe and returns the other end
+func startPipe(rig Rig) (net.Conn, *bufio.Reader, chan bool) {
+	server, client := net.Pipe()
+	s := MakeServer("pipe", rig)
+	done := make(chan bool)
+	go func() {
+		s.handleConnection(server)
+		done <- true
+	}()
+
+	_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+	return client, bufio.NewReader(client), done
+}
+
+func send(t *testing.T, conn net.Conn, reader *bufio.Reader, command string, lines int) []string {
+	_, err := conn.Write([]byte(command + "\n"))
+	if err != nil {
+		t.Fatalf("error sending %q: %s", command, err)
+	}
+
+	response := make([]string, lines)
+	for i := range response {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("error reading response to %q: %s", command, err)
+		}
+		response[i] = strings.TrimSuffix(line, "\n")
+	}
+
+	return response
+}
+
+func TestServerCommands(t *testing.T) {
+	rig := &pipeRig{frequency: 10489750000, mode: "USB", passband: 2700}
+	conn, reader, done := startPipe(rig)
+
+	tests := []struct {
+		command  string
+		expected []string
+	}{
+		{"f", []string{"10489750000"}},
+		{"\\get_freq", []string{"10489750000"}},
+		{"F 10489800000", []string{"RPRT 0"}},
+		{"f", []string{"10489800000"}},
+		{"F10489900000", []string{"RPRT 0"}},
+		{"\\get_freq", []string{"10489900000"}},
+		{"\\set_freq 10489950000", []string{"RPRT 0"}},
+		{"f", []string{"10489950000"}},
+		{"F", []string{"RPRT -1"}},
+		{"F abc", []string{"RPRT -1"}},
+		{"m", []string{"USB", "2700"}},
+		{"M lsb 2400", []string{"RPRT 0"}},
+		{"\\get_mode", []string{"LSB", "2400"}},
+		{"M CW", []string{"RPRT -1"}},
+		{"v", []string{"VFOA"}},
+		{"t", []string{"0"}},
+		{"\\chk_vfo", []string{"0"}},
+		{"X", []string{"RPRT -11"}},
+		{"\\unknown", []string{"RPRT -11"}},
+	}
+
+	for _, test := range tests {
+		response := send(t, conn, reader, test.command, len(test.expected))
+		for i := range test.expected {
+			if response[i] != test.expected[i] {
+				t.Errorf("%q: expected line %d to be %q, got %q", test.command, i, test.expected[i], response[i])
+			}
+		}
+	}
+
+	rig.setError = fmt.Errorf("device unplugged")
+	response := send(t, conn, reader, "F 10489750000", 1)
+	if response[0] != "RPRT -6" {
+		t.Errorf("expected RPRT -6 when the rig fails, got %q", response[0])
+	}
+
+	_, _ = conn.Write([]byte("q\n"))
+	_, err := reader.ReadString('\n')
+	if err != io.EOF {
+		t.Errorf("expected the connection to be closed after q, got %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handleConnection did not return after q")
+	}
+}
+
+func TestServerDumpState(t *testing.T) {
+	conn, reader, done := startPipe(&pipeRig{})
+	defer func() {
+		_ = conn.Close()
+		<-done
+	}()
+
+	expected := strings.Split(strings.TrimSuffix(dumpState(), "\n"), "\n")
+	response := send(t, conn, reader, "\\dump_state", len(expected))
+
+	if response[0] != "0" {
+		t.Errorf("expected protocol version 0, got %q", response[0])
+	}
+	if !strings.HasPrefix(response[3], "0.000000 100000000000.000000") {
+		t.Errorf("expected the RX range to cover every frequency, got %q", response[3])
+	}
+
+	// The state must be followed by the next response, so clients reading it line by line stay in sync
+	response = send(t, conn, reader, "f", 1)
+	if response[0] != "0" {
+		t.Errorf("expected the frequency after the state, got %q", response[0])
+	}
+}
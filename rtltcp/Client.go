@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/quan-to/slog"
 	"github.com/racerxdl/qo100-dedrift/metrics"
 	"net"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -26,9 +28,16 @@ type Client struct {
 
 	samplesBuffer    []byte
 	samplesBufferPos int
+
+	levelsLock sync.Mutex
+	levels     levelAccumulator
+
+	bytesIn  prometheus.Counter
+	bytesOut prometheus.Counter
 }
 
-func MakeClient() *Client {
+// MakeClient creates a new RTLTCP Client. The name is used as the pipeline label of its metrics.
+func MakeClient(name string) *Client {
 	return &Client{
 		stopChan: make(chan bool),
 		running:  false,
@@ -38,6 +47,8 @@ func MakeClient() *Client {
 		},
 		samplesBufferPos: 0,
 		samplesBuffer:    make([]byte, 16384),
+		bytesIn:          metrics.BytesIn.WithLabelValues(name),
+		bytesOut:         metrics.BytesOut.WithLabelValues(name),
 	}
 }
 
@@ -61,6 +72,37 @@ func (client *Client) SetGain(gain uint32) error {
 	return client.SendCommand(cmd)
 }
 
+// SetGainMode selects manual (true) or automatic (false) tuner gain
+func (client *Client) SetGainMode(manual bool) error {
+	var mode uint32
+	if manual {
+		mode = 1
+	}
+
+	buff := make([]byte, 4)
+	binary.BigEndian.PutUint32(buff, mode)
+
+	cmd := Command{
+		Type:  SetGainMode,
+		Param: [4]byte{buff[0], buff[1], buff[2], buff[3]},
+	}
+
+	return client.SendCommand(cmd)
+}
+
+// SetTunerGainByIndex selects one of the TunerGainCount gains supported by the tuner, lowest first
+func (client *Client) SetTunerGainByIndex(index uint32) error {
+	buff := make([]byte, 4)
+	binary.BigEndian.PutUint32(buff, index)
+
+	cmd := Command{
+		Type:  SetTunerGainByIndex,
+		Param: [4]byte{buff[0], buff[1], buff[2], buff[3]},
+	}
+
+	return client.SendCommand(cmd)
+}
+
 func (client *Client) SetSampleRate(sampleRate uint32) error {
 	buff := make([]byte, 4)
 	binary.BigEndian.PutUint32(buff, sampleRate)
@@ -93,7 +135,7 @@ func (client *Client) SendCommand(cmd Command) error {
 	}
 
 	n, err := client.conn.Write(buffer.Bytes())
-	metrics.BytesOut.Add(float64(n))
+	client.bytesOut.Add(float64(n))
 	return err
 }
 
@@ -143,7 +185,7 @@ func (client *Client) handshake() error {
 		return fmt.Errorf("not received enough bytes for handshake")
 	}
 
-	metrics.BytesIn.Add(float64(n))
+	client.bytesIn.Add(float64(n))
 	b := bytes.NewReader(buffer)
 	err = binary.Read(b, binary.BigEndian, &client.dongleInfo)
 	if err != nil {
@@ -186,22 +228,56 @@ func (client *Client) loop() {
 			client.handleData(client.samplesBuffer)
 			client.samplesBufferPos = 0
 		}
-		metrics.BytesIn.Add(float64(n))
+		client.bytesIn.Add(float64(n))
 	}
 
 	_ = client.conn.Close()
 }
 
+// ReadLevels returns the statistics of the samples received since the last call
+func (client *Client) ReadLevels() Levels {
+	client.levelsLock.Lock()
+	defer client.levelsLock.Unlock()
+
+	l := client.levels.levels()
+	client.levels = levelAccumulator{}
+
+	return l
+}
+
 func (client *Client) handleData(data []byte) {
-	if client.cb != nil {
-		iq := make([]complex64, len(data)/2)
+	iq := make([]complex64, len(data)/2)
 
-		for i := range iq {
-			rv := (float32(data[i*2]) - 128) / 127
-			iv := (float32(data[i*2+1]) - 128) / 127
-			iq[i] = complex(rv, iv)
+	clipped := 0
+	power, peak := 0.0, 0.0
+
+	for i := range iq {
+		ri, ii := data[i*2], data[i*2+1]
+		if ri == 0 || ri == 255 || ii == 0 || ii == 255 {
+			clipped++
+		}
+
+		rv := (float32(ri) - 128) / 127
+		iv := (float32(ii) - 128) / 127
+		iq[i] = complex(rv, iv)
+
+		p := float64(rv*rv + iv*iv)
+		power += p
+		if p > peak {
+			peak = p
 		}
+	}
+
+	client.levelsLock.Lock()
+	client.levels.samples += len(iq)
+	client.levels.clipped += clipped
+	client.levels.power += power
+	if peak > client.levels.peak {
+		client.levels.peak = peak
+	}
+	client.levelsLock.Unlock()
 
+	if client.cb != nil {
 		client.cb(iq)
 	}
 }
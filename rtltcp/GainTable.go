@@ -0,0 +1,76 @@
+package rtltcp
+
+import "math"
+
+// r82xxGains is shared by the R820T and R828D tuners
+var r82xxGains = []int{
+	0, 9, 14, 27, 37, 77, 87, 125, 144, 157, 166, 197, 207, 229, 254, 280, 297, 328, 338, 364, 372, 386, 402, 421, 434,
+	439, 445, 480, 496,
+}
+
+// tunerGains are the gains supported by each tuner in tenths of dB, lowest first, in the same order librtlsdr
+// uses for SetTunerGainByIndex
+var tunerGains = map[TunerType][]int{
+	RtlsdrTunerE4000:  {-10, 15, 40, 65, 90, 115, 140, 165, 190, 215, 240, 290, 340, 420},
+	RtlsdrTunerFc0012: {-99, -40, 71, 179, 192},
+	RtlsdrTunerFc0013: {
+		-99, -73, -65, -63, -60, -58, -54, 58, 61, 63, 65, 67, 68, 70, 71, 179, 181, 182, 184, 186, 188, 191, 197,
+	},
+	RtlsdrTunerFc2580: {0},
+	RtlsdrTunerR820t:  r82xxGains,
+	RtlsdrTunerR828d:  r82xxGains,
+}
+
+// GainTable maps gains in dB to the steps supported by the tuner of a dongle
+type GainTable struct {
+	Tuner TunerType
+	Gains []float64 // dB, lowest first
+}
+
+// MakeGainTable returns the gain table of the dongle tuner. The table is empty when the tuner is unknown or when the
+// number of gains reported by the dongle does not match the known table, as the indexes would not match.
+func MakeGainTable(info DongleInfo) GainTable {
+	t := GainTable{
+		Tuner: info.TunerType,
+		Gains: make([]float64, 0),
+	}
+
+	gains := tunerGains[info.TunerType]
+	if info.TunerGainCount != 0 && int(info.TunerGainCount) != len(gains) {
+		return t
+	}
+
+	for _, g := range gains {
+		t.Gains = append(t.Gains, float64(g)/10)
+	}
+
+	return t
+}
+
+// Valid returns true when the tuner gains are known
+func (t GainTable) Valid() bool {
+	return len(t.Gains) > 0
+}
+
+// Nearest returns the index and value of the supported gain closest to gain. The table must be valid.
+func (t GainTable) Nearest(gain float64) (int, float64) {
+	best := 0
+	for i, g := range t.Gains {
+		if math.Abs(g-gain) < math.Abs(t.Gains[best]-gain) {
+			best = i
+		}
+	}
+
+	return best, t.Gains[best]
+}
+
+// Index returns the index of the gain in the table, or -1 when it is not supported
+func (t GainTable) Index(gain float64) int {
+	for i, g := range t.Gains {
+		if math.Abs(g-gain) < 0.05 {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,60 @@
+package rtltcp
+
+import (
+	"testing"
+)
+
+func TestMakeGainTable(t *testing.T) {
+	table := MakeGainTable(DongleInfo{TunerType: RtlsdrTunerR820t, TunerGainCount: 29})
+	if !table.Valid() || len(table.Gains) != 29 {
+		t.Fatalf("expected 29 gains, got %v", table.Gains)
+	}
+	if table.Gains[0] != 0 || table.Gains[28] != 49.6 {
+		t.Errorf("expected gains from 0 to 49.6 dB, got %f to %f", table.Gains[0], table.Gains[28])
+	}
+
+	// The indexes would not match the dongle
+	table = MakeGainTable(DongleInfo{TunerType: RtlsdrTunerR820t, TunerGainCount: 10})
+	if table.Valid() {
+		t.Errorf("expected a table with another gain count to be invalid")
+	}
+
+	table = MakeGainTable(DongleInfo{TunerType: RtlsdrTunerUnknown})
+	if table.Valid() {
+		t.Errorf("expected the table of an unknown tuner to be invalid")
+	}
+}
+
+func TestGainTableNearest(t *testing.T) {
+	table := MakeGainTable(DongleInfo{TunerType: RtlsdrTunerE4000})
+
+	cases := []struct {
+		gain  float64
+		index int
+		value float64
+	}{
+		{-50, 0, -1},
+		{17, 7, 16.5},
+		{20, 8, 19},
+		{20.5, 9, 21.5},
+		{100, 13, 42},
+	}
+
+	for _, c := range cases {
+		index, value := table.Nearest(c.gain)
+		if index != c.index || value != c.value {
+			t.Errorf("nearest of %f: expected %d (%f dB), got %d (%f dB)", c.gain, c.index, c.value, index, value)
+		}
+	}
+}
+
+func TestGainTableIndex(t *testing.T) {
+	table := MakeGainTable(DongleInfo{TunerType: RtlsdrTunerR820t})
+
+	if i := table.Index(19.7); i != 11 {
+		t.Errorf("expected index 11 for 19.7 dB, got %d", i)
+	}
+	if i := table.Index(20); i != -1 {
+		t.Errorf("expected 20 dB to be unsupported, got index %d", i)
+	}
+}
@@ -0,0 +1,36 @@
+package rtltcp
+
+import "math"
+
+// Levels are the statistics of the raw 8 bit samples received since they were last read
+type Levels struct {
+	Samples  int
+	Clipped  int     // Samples with I or Q at 0 or 255
+	ClipRate float64 // Fraction of the samples that clipped
+	RMS      float64 // dBFS
+	Peak     float64 // dBFS
+}
+
+type levelAccumulator struct {
+	samples int
+	clipped int
+	power   float64
+	peak    float64
+}
+
+func (a *levelAccumulator) levels() Levels {
+	l := Levels{
+		Samples: a.samples,
+		Clipped: a.clipped,
+		RMS:     math.Inf(-1),
+		Peak:    math.Inf(-1),
+	}
+
+	if a.samples > 0 {
+		l.ClipRate = float64(a.clipped) / float64(a.samples)
+		l.RMS = 10 * math.Log10(math.Max(a.power/float64(a.samples), 1e-20))
+		l.Peak = 10 * math.Log10(math.Max(a.peak, 1e-20))
+	}
+
+	return l
+}
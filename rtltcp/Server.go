@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/quan-to/slog"
 	"github.com/racerxdl/go.fifo"
 	"github.com/racerxdl/qo100-dedrift/metrics"
@@ -37,9 +38,15 @@ type Server struct {
 	onCommandCb    OnCommand
 	onConnectCb    OnConnect
 	bufferFifo     *fifo.Queue
+
+	bytesIn          prometheus.Counter
+	bytesOut         prometheus.Counter
+	connectionsGauge prometheus.Gauge
+	totalConnections prometheus.Counter
 }
 
-func MakeRTLTCPServer(address string) *Server {
+// MakeRTLTCPServer creates a new RTLTCP Server listening at address. The name is used as the pipeline label of its metrics.
+func MakeRTLTCPServer(address, name string) *Server {
 	return &Server{
 		address:        address,
 		connections:    make([]*Session, 0),
@@ -50,7 +57,11 @@ func MakeRTLTCPServer(address string) *Server {
 			TunerType:      RtlsdrTunerR820t,
 			TunerGainCount: 0,
 		},
-		bufferFifo: fifo.NewQueue(),
+		bufferFifo:       fifo.NewQueue(),
+		bytesIn:          metrics.BytesIn.WithLabelValues(name),
+		bytesOut:         metrics.BytesOut.WithLabelValues(name),
+		connectionsGauge: metrics.Connections.WithLabelValues(name),
+		totalConnections: metrics.TotalConnections.WithLabelValues(name),
 	}
 }
 
@@ -135,7 +146,7 @@ func (server *Server) broadcast(data []byte) {
 
 		for _, v := range server.connections {
 			n, _ := v.conn.Write(payload)
-			metrics.BytesOut.Add(float64(n))
+			server.bytesOut.Add(float64(n))
 		}
 	}
 	server.connectionLock.Unlock()
@@ -229,8 +240,8 @@ func (server *Server) handleRequest(conn net.Conn) {
 		server.onConnectCb(session.id, session.conn.RemoteAddr().String())
 	}
 
-	metrics.TotalConnections.Inc()
-	metrics.Connections.Inc()
+	server.totalConnections.Inc()
+	server.connectionsGauge.Inc()
 
 	for running {
 		_ = conn.SetReadDeadline(time.Now().Add(defaultReadTimeout))
@@ -267,7 +278,7 @@ func (server *Server) handleRequest(conn net.Conn) {
 				continue
 			}
 			server.handlePacket(session, cmd)
-			metrics.BytesIn.Add(float64(n))
+			server.bytesIn.Add(float64(n))
 		}
 	}
 	server.connectionLock.Lock()
@@ -280,6 +291,6 @@ func (server *Server) handleRequest(conn net.Conn) {
 	server.connectionLock.Unlock()
 	_ = conn.Close()
 
-	metrics.Connections.Dec()
+	server.connectionsGauge.Dec()
 	clog.Info("Connection closed.")
 }
@@ -0,0 +1,447 @@
+package signals
+
+import (
+	"encoding/json"
+	"github.com/quan-to/slog"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"github.com/racerxdl/qo100-dedrift/dedrift"
+	"github.com/racerxdl/segdsp/dsp"
+	"github.com/racerxdl/segdsp/dsp/fft"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	EventStart = "start"
+	EventEnd   = "end"
+)
+
+// occupiedPowerRatio is the fraction of the signal power inside its reported bandwidth
+const occupiedPowerRatio = 0.99
+
+// Event is a signal start or end
+type Event struct {
+	Time   time.Time `json:"time"`
+	Event  string    `json:"event"`
+	Signal Signal    `json:"signal"`
+}
+
+// Activity is the list of active signals
+type Activity struct {
+	Time       time.Time `json:"time"`
+	NoiseFloor float32   `json:"noiseFloor"` // dBFS per bin
+	BinWidth   float64   `json:"binWidth"`
+	Signals    []Signal  `json:"signals"`
+}
+
+// Spectrum is an averaged power spectrum of the full band
+type Spectrum struct {
+	Time            time.Time
+	CenterFrequency float64
+	BinWidth        float64
+	NoiseFloor      float64   // Linear power per bin
+	Threshold       float64   // Linear power above which a bin is occupied
+	Bins            []float64 // Linear power per bin, lowest frequency first
+}
+
+type OnActivity func(activity Activity)
+
+type OnSpectrum func(spectrum Spectrum)
+
+type detection struct {
+	offset    float64
+	bandwidth float64
+	power     float64
+	snr       float64
+	flatness  float64
+}
+
+// Detector finds the carriers and occupied channels above the noise floor of the dedrifted full band.
+// Its spectrum is computed on a dedrift.FrameWorker, so the detector never delays the IQ output. It does not reuse
+// the full band FFT of the web interface, which is averaged in dB at the web settings resolution and frame rate.
+type Detector struct {
+	cfg        config.SignalsConfig
+	log        *slog.Instance
+	sampleRate float64
+
+	frameInterval time.Duration
+	lastFrame     time.Time
+	worker        *dedrift.FrameWorker
+	onActivity    OnActivity
+	onSpectrum    OnSpectrum
+	eventLog      *os.File
+
+	window   []float64
+	fftFrame []complex64
+	psd      []float64
+	averaged int
+
+	lock            sync.Mutex
+	centerFrequency float64
+	signals         []*Signal
+	nextID          int
+	activity        Activity
+	totalSignals    int
+}
+
+func MakeDetector(name string, cfg config.SignalsConfig, sampleRate float64) (*Detector, error) {
+	fftSize := 1
+	for fftSize < cfg.FFTSize {
+		fftSize <<= 1
+	}
+
+	averaging := cfg.Averaging
+	if averaging < 1 {
+		averaging = 1
+	}
+
+	d := &Detector{
+		cfg:           cfg,
+		log:           slog.Scope("Signals " + name),
+		sampleRate:    sampleRate,
+		frameInterval: time.Duration(cfg.Interval / float64(averaging) * float64(time.Second)),
+		window:        dsp.HammingWindow(fftSize),
+		fftFrame:      make([]complex64, fftSize),
+		psd:           make([]float64, fftSize),
+		signals:       make([]*Signal, 0),
+		activity:      Activity{Signals: make([]Signal, 0)},
+	}
+
+	d.cfg.Averaging = averaging
+	d.worker = dedrift.MakeFrameWorker(fftSize, d.process)
+
+	if cfg.EventLog != "" {
+		f, err := os.OpenFile(cfg.EventLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		d.eventLog = f
+	}
+
+	return d, nil
+}
+
+// SetCenterFrequency sets the absolute frequency of the center of the full band
+func (d *Detector) SetCenterFrequency(frequency float64) {
+	d.lock.Lock()
+	d.centerFrequency = frequency
+	d.lock.Unlock()
+}
+
+// SetOnActivity sets the callback called with the active signals after each detection. It runs in the detector goroutine.
+func (d *Detector) SetOnActivity(cb OnActivity) {
+	d.onActivity = cb
+}
+
+// SetOnSpectrum sets the callback called with each averaged spectrum. It runs in the detector goroutine and must not
+// keep the bins.
+func (d *Detector) SetOnSpectrum(cb OnSpectrum) {
+	d.onSpectrum = cb
+}
+
+func (d *Detector) Start() {
+	d.worker.Start()
+}
+
+func (d *Detector) Stop() {
+	d.worker.Stop()
+	if d.eventLog != nil {
+		_ = d.eventLog.Close()
+	}
+}
+
+// Work copies the last corrected full band samples to the worker when a frame is due. It never blocks.
+func (d *Detector) Work(samples []complex64) {
+	due := time.Since(d.lastFrame) >= d.frameInterval
+	if due {
+		d.lastFrame = time.Now()
+	}
+
+	d.worker.Work(samples, due)
+}
+
+func (d *Detector) process(frame []complex64) {
+	// The history is not full yet
+	if len(frame) < len(d.fftFrame) {
+		return
+	}
+
+	frame = frame[len(frame)-len(d.fftFrame):]
+
+	for i, v := range frame {
+		w := float32(d.window[i])
+		d.fftFrame[i] = complex(real(v)*w, imag(v)*w)
+	}
+
+	n := len(d.psd)
+	norm := 1 / float64(n*n)
+	for i, v := range fft.FFT(d.fftFrame) {
+		o := (i + n/2) % n
+		d.psd[o] += float64(real(v)*real(v)+imag(v)*imag(v)) * norm
+	}
+
+	d.averaged++
+	if d.averaged < d.cfg.Averaging {
+		return
+	}
+
+	bins := make([]float64, n)
+	for i, v := range d.psd {
+		bins[i] = v / float64(d.averaged)
+		d.psd[i] = 0
+	}
+	d.averaged = 0
+
+	d.update(bins)
+}
+
+// detect finds the runs of bins above the threshold
+func (d *Detector) detect(bins []float64) ([]detection, float64, float64) {
+	n := len(bins)
+	binWidth := d.sampleRate / float64(n)
+	first := int(float64(n) * d.cfg.EdgeExclusion)
+	last := n - first
+
+	sorted := make([]float64, last-first)
+	copy(sorted, bins[first:last])
+	sort.Float64s(sorted)
+	noise := sorted[len(sorted)/2]
+	threshold := noise * math.Pow(10, d.cfg.Threshold/10)
+	maxGap := int(d.cfg.MergeGap / binWidth)
+
+	detections := make([]detection, 0)
+
+	for i := first; i < last; i++ {
+		if bins[i] < threshold {
+			continue
+		}
+
+		// Extend the run allowing gaps up to maxGap bins
+		end := i
+		for j := i + 1; j < last && j-end <= maxGap+1; j++ {
+			if bins[j] >= threshold {
+				end = j
+			}
+		}
+
+		power, weighted, peak := 0.0, 0.0, 0.0
+		for j := i; j <= end; j++ {
+			p := math.Max(bins[j]-noise, 0)
+			power += p
+			weighted += p * float64(j)
+			peak = math.Max(peak, bins[j])
+		}
+
+		center := float64(i+end) / 2
+		if power > 0 {
+			center = weighted / power
+		}
+
+		bandwidth := occupiedBandwidth(bins[i:end+1], noise) * binWidth
+		if bandwidth >= d.cfg.MinBandwidth {
+			detections = append(detections, detection{
+				offset:    (center - float64(n/2)) * binWidth,
+				bandwidth: bandwidth,
+				power:     power,
+				snr:       peak / noise,
+				flatness:  spectralFlatness(bins[i : end+1]),
+			})
+		}
+
+		i = end
+	}
+
+	return detections, noise, threshold
+}
+
+// occupiedBandwidth returns how many bins hold the occupiedPowerRatio of the power above the noise
+func occupiedBandwidth(bins []float64, noise float64) float64 {
+	total := 0.0
+	for _, v := range bins {
+		total += math.Max(v-noise, 0)
+	}
+
+	// Remove the tails on both sides
+	tail := total * (1 - occupiedPowerRatio) / 2
+	lower, upper := 0.0, float64(len(bins))
+
+	acc := 0.0
+	for i, v := range bins {
+		p := math.Max(v-noise, 0)
+		if acc+p > tail {
+			lower = float64(i) + (tail-acc)/p
+			break
+		}
+		acc += p
+	}
+
+	acc = 0
+	for i := len(bins) - 1; i >= 0; i-- {
+		p := math.Max(bins[i]-noise, 0)
+		if acc+p > tail {
+			upper = float64(i+1) - (tail-acc)/p
+			break
+		}
+		acc += p
+	}
+
+	return math.Max(upper-lower, 0)
+}
+
+func (d *Detector) classify(bandwidth, flatness float64) string {
+	switch {
+	case bandwidth <= d.cfg.CWMaxBandwidth:
+		return ModeCW
+	case bandwidth <= d.cfg.SSBMaxBandwidth && flatness < d.cfg.DigitalFlatness:
+		return ModeSSB
+	default:
+		return ModeDigital
+	}
+}
+
+func toDB(v float64) float32 {
+	return float32(10 * math.Log10(math.Max(v, 1e-30)))
+}
+
+// update matches the detections with the tracked signals and publishes the activity
+func (d *Detector) update(bins []float64) {
+	detections, noise, threshold := d.detect(bins)
+	now := time.Now()
+	binWidth := d.sampleRate / float64(len(bins))
+	holdTime := time.Duration(d.cfg.HoldTime * float64(time.Second))
+
+	events := make([]Event, 0)
+
+	d.lock.Lock()
+
+	matched := make(map[*Signal]bool)
+
+	for _, det := range detections {
+		frequency := d.centerFrequency + det.offset
+
+		var signal *Signal
+		for _, s := range d.signals {
+			tolerance := math.Max(math.Max(s.Bandwidth, det.bandwidth)/2, 2*binWidth)
+			if !matched[s] && math.Abs(s.Frequency-frequency) <= tolerance {
+				signal = s
+				break
+			}
+		}
+
+		if signal == nil {
+			d.nextID++
+			signal = &Signal{ID: d.nextID, FirstSeen: now}
+			d.signals = append(d.signals, signal)
+		}
+
+		matched[signal] = true
+		signal.Frequency = frequency
+		signal.Bandwidth = det.bandwidth
+		signal.Power = toDB(det.power)
+		signal.SNR = toDB(det.snr)
+		signal.Flatness = float32(det.flatness)
+		signal.Mode = d.classify(det.bandwidth, det.flatness)
+		signal.LastSeen = now
+		signal.detections++
+
+		if !signal.active && signal.detections >= d.cfg.MinDetections {
+			signal.active = true
+			d.totalSignals++
+			events = append(events, Event{Time: now, Event: EventStart, Signal: *signal})
+		}
+	}
+
+	signals := d.signals[:0]
+	active := make([]Signal, 0)
+	for _, s := range d.signals {
+		if !matched[s] && now.Sub(s.LastSeen) > holdTime {
+			if s.active {
+				events = append(events, Event{Time: now, Event: EventEnd, Signal: *s})
+			}
+			continue
+		}
+		signals = append(signals, s)
+		if s.active {
+			active = append(active, *s)
+		}
+	}
+	d.signals = signals
+
+	sort.Slice(active, func(i, j int) bool { return active[i].Frequency < active[j].Frequency })
+
+	d.activity = Activity{
+		Time:       now,
+		NoiseFloor: toDB(noise),
+		BinWidth:   binWidth,
+		Signals:    active,
+	}
+	activity := d.activity
+	centerFrequency := d.centerFrequency
+
+	d.lock.Unlock()
+
+	if d.onSpectrum != nil {
+		d.onSpectrum(Spectrum{
+			Time:            now,
+			CenterFrequency: centerFrequency,
+			BinWidth:        binWidth,
+			NoiseFloor:      noise,
+			Threshold:       threshold,
+			Bins:            bins,
+		})
+	}
+
+	for _, e := range events {
+		d.logEvent(e)
+	}
+
+	if d.onActivity != nil {
+		d.onActivity(activity)
+	}
+}
+
+func (d *Detector) logEvent(e Event) {
+	s := e.Signal
+	if e.Event == EventStart {
+		d.log.Info("Signal %d started at %.3f kHz (%s, %.0f Hz, %.1f dBFS)", s.ID, s.Frequency/1e3, s.Mode, s.Bandwidth, s.Power)
+	} else {
+		d.log.Info("Signal %d at %.3f kHz ended after %s", s.ID, s.Frequency/1e3, s.Duration().Round(time.Second))
+	}
+
+	if d.eventLog != nil {
+		data, _ := json.Marshal(e)
+		_, err := d.eventLog.Write(append(data, '\n'))
+		if err != nil {
+			d.log.Error("Error writing signal event: %s", err)
+		}
+	}
+}
+
+// GetActivity returns the last list of active signals. It is safe to be called from any goroutine.
+func (d *Detector) GetActivity() Activity {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.activity
+}
+
+// GetCounters returns the number of signals seen and the number of frames skipped because the detector was busy
+func (d *Detector) GetCounters() (signals, dropped int) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.totalSignals, d.worker.GetDroppedFrames()
+}
+
+// Handler serves the active signals
+func (d *Detector) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, _ := json.Marshal(d.GetActivity())
+
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(200)
+		_, _ = w.Write(data)
+	}
+}
@@ -0,0 +1,202 @@
+package signals
+
+import (
+	"github.com/racerxdl/qo100-dedrift/config"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+const (
+	testSampleRate = 409600
+	testFFTSize    = 4096 // 100 Hz bins
+	testNoise      = 1e-9
+	testCenter     = 10489500000
+)
+
+// testSpectrum returns a flat noise floor with a carrier, a voice like and a flat digital signal when present is set
+func testSpectrum(present bool) []float64 {
+	bins := make([]float64, testFFTSize)
+	for i := range bins {
+		bins[i] = testNoise
+	}
+
+	if !present {
+		return bins
+	}
+
+	// Carrier at +100 kHz
+	bins[testFFTSize/2+1000] = testNoise * 1e4
+
+	// Voice at -50 kHz, 2.5 kHz wide, the power falls 3 dB every 100 Hz
+	for i := 0; i < 25; i++ {
+		bins[testFFTSize/2-500+i] = testNoise * 1e4 * math.Pow(10, -0.3*float64(i))
+	}
+
+	// Digital at +20 kHz, 5 kHz wide with a flat top
+	for i := 0; i < 50; i++ {
+		bins[testFFTSize/2+200+i] = testNoise * 1e3
+	}
+
+	return bins
+}
+
+func makeTestDetector(t *testing.T) *Detector {
+	cfg := config.DefaultConfig.Server.Signals
+	cfg.FFTSize = testFFTSize
+	cfg.HoldTime = 0.1
+
+	d, err := MakeDetector("test", cfg, testSampleRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetCenterFrequency(testCenter)
+
+	return d
+}
+
+func TestDetectorDetection(t *testing.T) {
+	d := makeTestDetector(t)
+
+	detections, noise, threshold := d.detect(testSpectrum(true))
+	if math.Abs(noise-testNoise) > testNoise*1e-6 {
+		t.Errorf("expected the noise floor at %g, got %g", testNoise, noise)
+	}
+	if expected := testNoise * math.Pow(10, d.cfg.Threshold/10); math.Abs(threshold-expected) > expected*1e-6 {
+		t.Errorf("expected the threshold at %g, got %g", expected, threshold)
+	}
+	if len(detections) != 3 {
+		t.Fatalf("expected 3 detections, got %d: %+v", len(detections), detections)
+	}
+
+	d.update(testSpectrum(true))
+	d.update(testSpectrum(true))
+
+	signals := d.GetActivity().Signals
+	if len(signals) != 3 {
+		t.Fatalf("expected 3 signals, got %d", len(signals))
+	}
+
+	expected := []struct {
+		frequency float64
+		mode      string
+	}{
+		{testCenter - 50e3, ModeSSB},
+		{testCenter + 20e3, ModeDigital},
+		{testCenter + 100e3, ModeCW},
+	}
+
+	for i, e := range expected {
+		s := signals[i]
+		// The voice signal center is weighted by its power, so it is close to its strong edge
+		if math.Abs(s.Frequency-e.frequency) > 3e3 {
+			t.Errorf("expected a signal at %.0f Hz, got %.0f Hz", e.frequency, s.Frequency)
+		}
+		if s.Mode != e.mode {
+			t.Errorf("expected the signal at %.0f Hz to be %s, got %s (%.0f Hz wide, flatness %.2f)", e.frequency, e.mode, s.Mode, s.Bandwidth, s.Flatness)
+		}
+	}
+
+	if math.Abs(signals[1].Bandwidth-5e3) > 200 {
+		t.Errorf("expected the digital signal to be 5 kHz wide, got %.0f Hz", signals[1].Bandwidth)
+	}
+}
+
+func TestDetectorHysteresis(t *testing.T) {
+	d := makeTestDetector(t)
+
+	// A signal needs MinDetections spectra to start
+	d.update(testSpectrum(true))
+	if n := len(d.GetActivity().Signals); n != 0 {
+		t.Errorf("expected no signals after a single detection, got %d", n)
+	}
+
+	d.update(testSpectrum(true))
+	if n := len(d.GetActivity().Signals); n != 3 {
+		t.Errorf("expected 3 signals after %d detections, got %d", d.cfg.MinDetections, n)
+	}
+
+	ids := map[int]bool{}
+	for _, s := range d.GetActivity().Signals {
+		ids[s.ID] = true
+	}
+
+	// A signal missing for less than HoldTime keeps its ID
+	d.update(testSpectrum(false))
+	if n := len(d.GetActivity().Signals); n != 3 {
+		t.Errorf("expected the signals to be held, got %d", n)
+	}
+
+	d.update(testSpectrum(true))
+	for _, s := range d.GetActivity().Signals {
+		if !ids[s.ID] {
+			t.Errorf("expected the signal at %.0f Hz to keep its ID, got %d", s.Frequency, s.ID)
+		}
+	}
+
+	// A signal missing for longer than HoldTime ends
+	time.Sleep(time.Duration(d.cfg.HoldTime*float64(time.Second)) + 50*time.Millisecond)
+	d.update(testSpectrum(false))
+	if n := len(d.GetActivity().Signals); n != 0 {
+		t.Errorf("expected the signals to end after the hold time, got %d", n)
+	}
+
+	if total, _ := d.GetCounters(); total != 3 {
+		t.Errorf("expected 3 signals counted, got %d", total)
+	}
+}
+
+func TestDetectorWorker(t *testing.T) {
+	cfg := config.DefaultConfig.Server.Signals
+	cfg.FFTSize = testFFTSize
+	cfg.Interval = 0
+	cfg.Threshold = 15 // No noise peaks over the threshold
+
+	d, err := MakeDetector("test", cfg, testSampleRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetCenterFrequency(testCenter)
+
+	activities := make(chan Activity, 16)
+	d.SetOnActivity(func(activity Activity) {
+		select {
+		case activities <- activity:
+		default:
+		}
+	})
+
+	d.Start()
+	defer d.Stop()
+
+	// A carrier 10 kHz above the center with a little noise, in blocks smaller than the FFT
+	r := rand.New(rand.NewSource(1))
+	block := make([]complex64, testFFTSize/4)
+	n := 0
+	timeout := time.After(5 * time.Second)
+	for {
+		for i := range block {
+			s, c := math.Sincos(2 * math.Pi * 10e3 * float64(n) / testSampleRate)
+			block[i] = complex(float32(0.5*c+r.NormFloat64()*1e-3), float32(0.5*s+r.NormFloat64()*1e-3))
+			n++
+		}
+		d.Work(block)
+
+		select {
+		case activity := <-activities:
+			if len(activity.Signals) > 0 {
+				if len(activity.Signals) != 1 {
+					t.Fatalf("expected only the carrier, got %+v", activity.Signals)
+				}
+				if f := activity.Signals[0].Frequency; math.Abs(f-(testCenter+10e3)) > 200 {
+					t.Errorf("expected the carrier at %.0f Hz, got %.0f Hz", testCenter+10e3, f)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("expected the carrier to be detected")
+		default:
+		}
+	}
+}
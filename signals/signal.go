@@ -0,0 +1,52 @@
+package signals
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	ModeCW      = "cw"
+	ModeSSB     = "ssb"
+	ModeDigital = "digital"
+)
+
+// Signal is a signal found in the dedrifted spectrum
+type Signal struct {
+	ID        int       `json:"id"`
+	Frequency float64   `json:"frequency"` // Absolute center frequency in Hertz
+	Bandwidth float64   `json:"bandwidth"` // Occupied bandwidth in Hertz
+	Power     float32   `json:"power"`     // Integrated power in dBFS
+	SNR       float32   `json:"snr"`       // Peak bin above the noise floor in dB
+	Flatness  float32   `json:"flatness"`  // Spectral flatness of the occupied bins, from 0 to 1
+	Mode      string    `json:"mode"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+
+	detections int
+	active     bool
+}
+
+// Duration returns for how long the signal has been seen
+func (s Signal) Duration() time.Duration {
+	return s.LastSeen.Sub(s.FirstSeen)
+}
+
+// spectralFlatness is the ratio between the geometric and the arithmetic mean of the bins power.
+// Digital modes have a flat top and are close to 1, voice is much lower.
+func spectralFlatness(bins []float64) float64 {
+	if len(bins) == 0 {
+		return 0
+	}
+
+	logSum := 0.0
+	sum := 0.0
+	for _, v := range bins {
+		v = math.Max(v, 1e-30)
+		logSum += math.Log(v)
+		sum += v
+	}
+
+	n := float64(len(bins))
+	return math.Exp(logSum/n) / (sum / n)
+}
@@ -0,0 +1,64 @@
+package stability
+
+import "math"
+
+// ADEV computes the non-overlapping Allan deviation of the fractional frequency series y for tau = m * tau0.
+// It returns the deviation and the number of differences used.
+func ADEV(y []float64, m int) (float64, int) {
+	if m < 1 {
+		return math.NaN(), 0
+	}
+
+	blocks := len(y) / m
+	if blocks < 2 {
+		return math.NaN(), 0
+	}
+
+	averages := make([]float64, blocks)
+	for i := 0; i < blocks; i++ {
+		sum := 0.0
+		for _, v := range y[i*m : (i+1)*m] {
+			sum += v
+		}
+		averages[i] = sum / float64(m)
+	}
+
+	sum := 0.0
+	for i := 0; i < blocks-1; i++ {
+		d := averages[i+1] - averages[i]
+		sum += d * d
+	}
+
+	n := blocks - 1
+
+	return math.Sqrt(sum / (2 * float64(n))), n
+}
+
+// OADEV computes the overlapping Allan deviation of the fractional frequency series y for tau = m * tau0.
+// It returns the deviation and the number of terms used.
+func OADEV(y []float64, m int) (float64, int) {
+	if m < 1 {
+		return math.NaN(), 0
+	}
+
+	// Phase data normalized by tau0: x[i+1] - x[i] = y[i]
+	x := make([]float64, len(y)+1)
+	for i, v := range y {
+		x[i+1] = x[i] + v
+	}
+
+	n := len(x) - 2*m
+	if n < 1 {
+		return math.NaN(), 0
+	}
+
+	sum := 0.0
+	for i := 0; i < n; i++ {
+		d := x[i+2*m] - 2*x[i+m] + x[i]
+		sum += d * d
+	}
+
+	fm := float64(m)
+
+	return math.Sqrt(sum / (2 * fm * fm * float64(n))), n
+}
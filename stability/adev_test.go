@@ -0,0 +1,89 @@
+package stability
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDeviationAlternating(t *testing.T) {
+	y := make([]float64, 100)
+	for i := range y {
+		y[i] = 1
+		if i%2 == 1 {
+			y[i] = -1
+		}
+	}
+
+	for name, deviation := range map[string]func([]float64, int) (float64, int){"ADEV": ADEV, "OADEV": OADEV} {
+		if d, _ := deviation(y, 1); math.Abs(d-math.Sqrt2) > 1e-12 {
+			t.Errorf("%s at m=1: expected %f, got %f", name, math.Sqrt2, d)
+		}
+		if d, _ := deviation(y, 2); d != 0 {
+			t.Errorf("%s at m=2: expected 0, got %f", name, d)
+		}
+	}
+}
+
+func TestDeviationLinearDrift(t *testing.T) {
+	// A linear frequency drift gives a deviation of rate * tau / sqrt(2)
+	rate := 1e-9
+	y := make([]float64, 1000)
+	for i := range y {
+		y[i] = rate * float64(i)
+	}
+
+	for _, m := range []int{1, 10, 100} {
+		expected := rate * float64(m) / math.Sqrt2
+
+		adev, _ := ADEV(y, m)
+		if math.Abs(adev-expected) > expected*1e-9 {
+			t.Errorf("ADEV at m=%d: expected %e, got %e", m, expected, adev)
+		}
+
+		oadev, _ := OADEV(y, m)
+		if math.Abs(oadev-expected) > expected*1e-9 {
+			t.Errorf("OADEV at m=%d: expected %e, got %e", m, expected, oadev)
+		}
+	}
+}
+
+func TestDeviationWhiteNoise(t *testing.T) {
+	// White frequency noise falls with the square root of tau
+	r := rand.New(rand.NewSource(1))
+	y := make([]float64, 100000)
+	for i := range y {
+		y[i] = r.NormFloat64()
+	}
+
+	oadev1, _ := OADEV(y, 1)
+	oadev100, _ := OADEV(y, 100)
+	if ratio := oadev1 / oadev100; math.Abs(ratio-10) > 1 {
+		t.Errorf("expected OADEV to fall 10 times from m=1 to m=100, got %f", ratio)
+	}
+
+	adev1, _ := ADEV(y, 1)
+	if math.Abs(adev1-1) > 0.02 {
+		t.Errorf("expected ADEV of unit white noise to be 1 at m=1, got %f", adev1)
+	}
+}
+
+func TestDeviationTerms(t *testing.T) {
+	y := make([]float64, 10)
+
+	if _, n := ADEV(y, 3); n != 2 {
+		t.Errorf("expected 2 ADEV differences, got %d", n)
+	}
+	if _, n := OADEV(y, 3); n != 5 {
+		t.Errorf("expected 5 OADEV terms, got %d", n)
+	}
+
+	for _, m := range []int{0, 6} {
+		if d, n := ADEV(y, m); n != 0 || !math.IsNaN(d) {
+			t.Errorf("expected no ADEV at m=%d, got %f with %d differences", m, d, n)
+		}
+		if d, n := OADEV(y, m); n != 0 || !math.IsNaN(d) {
+			t.Errorf("expected no OADEV at m=%d, got %f with %d terms", m, d, n)
+		}
+	}
+}
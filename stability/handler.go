@@ -0,0 +1,49 @@
+package stability
+
+import (
+	"encoding/json"
+	"github.com/quan-to/slog"
+	"github.com/racerxdl/qo100-dedrift/driftlog"
+	"net/http"
+)
+
+var log = slog.Scope("Stability")
+
+// Handler serves the stability report of the last N hours of the drift log.
+// The hours and tau (comma separated seconds) query parameters override the defaults.
+func Handler(logger *driftlog.Logger, maxHours float64, taus []float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hours, err := driftlog.ParseHours(r, maxHours)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		reportTaus := taus
+		if v := r.URL.Query().Get("tau"); v != "" {
+			reportTaus, err = ParseTaus(v)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		records, err := logger.ReadLast(hours)
+		if err != nil {
+			log.Error("Error reading drift log: %s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		report, err := Analyze(records, reportTaus)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		data, _ := json.Marshal(report)
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(200)
+		_, _ = w.Write(data)
+	}
+}
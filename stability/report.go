@@ -0,0 +1,225 @@
+package stability
+
+import (
+	"fmt"
+	"github.com/racerxdl/qo100-dedrift/driftlog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Point is the Allan deviation at a single tau
+type Point struct {
+	Tau   float64 `json:"tau"` // Seconds
+	ADEV  float64 `json:"adev"`
+	OADEV float64 `json:"oadev"`
+	N     int     `json:"n"` // Number of overlapping terms
+}
+
+// Report is the stability analysis of a drift series
+type Report struct {
+	Samples       int       `json:"samples"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	Tau0          float64   `json:"tau0"`          // Median sample interval in seconds
+	Segments      int       `json:"segments"`      // Number of contiguous runs of locked records
+	DriftRate     float64   `json:"driftRate"`     // Linear drift rate in Hertz per second
+	DriftRatePPM  float64   `json:"driftRatePPM"`  // Linear drift rate in ppm per hour
+	PeakToPeak    float64   `json:"peakToPeak"`    // Peak-to-peak wander in Hertz
+	PeakToPeakPPM float64   `json:"peakToPeakPPM"` // Peak-to-peak wander in ppm
+	Points        []Point   `json:"points"`
+}
+
+// ParseTaus parses a comma separated list of tau values in seconds
+func ParseTaus(s string) ([]float64, error) {
+	taus := make([]float64, 0)
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		tau, err := strconv.ParseFloat(v, 64)
+		if err != nil || tau <= 0 {
+			return nil, fmt.Errorf("invalid tau %q", v)
+		}
+
+		taus = append(taus, tau)
+	}
+
+	return taus, nil
+}
+
+func medianInterval(records []driftlog.Record) float64 {
+	intervals := make([]float64, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		intervals[i-1] = records[i].Time.Sub(records[i-1].Time).Seconds()
+	}
+
+	sort.Float64s(intervals)
+
+	return intervals[len(intervals)/2]
+}
+
+// maxGapRatio is the interval between records, in sample intervals, above which the series is split in segments
+const maxGapRatio = 1.5
+
+// segment is a contiguous run of locked records
+type segment struct {
+	t  []float64 // Seconds since the report start
+	hz []float64 // Drift in Hertz
+	y  []float64 // Fractional frequency
+}
+
+// splitSegments splits the records wherever the interval to the previous record exceeds maxGapRatio * tau0,
+// like after an unlock or a restart
+func splitSegments(records []driftlog.Record, start time.Time, tau0 float64) []segment {
+	segments := make([]segment, 0)
+	var current *segment
+
+	for i, r := range records {
+		if i == 0 || r.Time.Sub(records[i-1].Time).Seconds() > maxGapRatio*tau0 {
+			segments = append(segments, segment{})
+			current = &segments[len(segments)-1]
+		}
+
+		current.t = append(current.t, r.Time.Sub(start).Seconds())
+		current.hz = append(current.hz, r.Drift)
+		current.y = append(current.y, r.PPM*1e-6)
+	}
+
+	return segments
+}
+
+func mean(v []float64) float64 {
+	sum := 0.0
+	for _, x := range v {
+		sum += x
+	}
+
+	return sum / float64(len(v))
+}
+
+// pooledSlope returns the least squares slope of y over t common to all segments, each with its own intercept
+func pooledSlope(t, y [][]float64) float64 {
+	var stt, sty float64
+
+	for s := range t {
+		mt := mean(t[s])
+		my := mean(y[s])
+		for i := range t[s] {
+			stt += (t[s][i] - mt) * (t[s][i] - mt)
+			sty += (t[s][i] - mt) * (y[s][i] - my)
+		}
+	}
+
+	if stt == 0 {
+		return 0
+	}
+
+	return sty / stt
+}
+
+// pooledDeviation computes a deviation in each segment and combines them weighted by their number of terms
+func pooledDeviation(segments []segment, m int, deviation func(y []float64, m int) (float64, int)) (float64, int) {
+	sum := 0.0
+	n := 0
+
+	for _, s := range segments {
+		dev, terms := deviation(s.y, m)
+		if terms == 0 {
+			continue
+		}
+
+		sum += dev * dev * float64(terms)
+		n += terms
+	}
+
+	if n == 0 {
+		return math.NaN(), 0
+	}
+
+	return math.Sqrt(sum / float64(n)), n
+}
+
+// Analyze computes the Allan deviation at the specified taus, the drift rate and the peak-to-peak wander.
+// Only locked records are used and they are assumed to be evenly spaced by the median sample interval.
+// The records are split in segments at gaps longer than the sample interval, like unlocks or restarts, and the
+// deviations and the drift rate are computed in each segment and pooled, so they never span a gap.
+// Taus that are not a multiple of the sample interval are rounded to the nearest one.
+func Analyze(records []driftlog.Record, taus []float64) (Report, error) {
+	locked := make([]driftlog.Record, 0, len(records))
+	for _, r := range records {
+		if r.Locked {
+			locked = append(locked, r)
+		}
+	}
+
+	report := Report{
+		Samples: len(locked),
+		Points:  make([]Point, 0),
+	}
+
+	if len(locked) < 3 {
+		return report, fmt.Errorf("not enough locked samples (%d)", len(locked))
+	}
+
+	report.Start = locked[0].Time
+	report.End = locked[len(locked)-1].Time
+	report.Tau0 = medianInterval(locked)
+
+	if report.Tau0 <= 0 {
+		return report, fmt.Errorf("invalid sample interval")
+	}
+
+	segments := splitSegments(locked, report.Start, report.Tau0)
+	report.Segments = len(segments)
+
+	t := make([][]float64, len(segments))
+	hz := make([][]float64, len(segments))
+	y := make([][]float64, len(segments))
+	for i, s := range segments {
+		t[i] = s.t
+		hz[i] = s.hz
+		y[i] = s.y
+	}
+
+	minHz, maxHz := math.Inf(1), math.Inf(-1)
+	minPPM, maxPPM := math.Inf(1), math.Inf(-1)
+
+	for _, r := range locked {
+		minHz = math.Min(minHz, r.Drift)
+		maxHz = math.Max(maxHz, r.Drift)
+		minPPM = math.Min(minPPM, r.PPM)
+		maxPPM = math.Max(maxPPM, r.PPM)
+	}
+
+	report.DriftRate = pooledSlope(t, hz)
+	report.DriftRatePPM = pooledSlope(t, y) * 1e6 * 3600
+	report.PeakToPeak = maxHz - minHz
+	report.PeakToPeakPPM = maxPPM - minPPM
+
+	for _, tau := range taus {
+		m := int(math.Round(tau / report.Tau0))
+		if m < 1 {
+			m = 1
+		}
+
+		adev, _ := pooledDeviation(segments, m, ADEV)
+		oadev, n := pooledDeviation(segments, m, OADEV)
+		if n == 0 {
+			continue
+		}
+
+		report.Points = append(report.Points, Point{
+			Tau:   float64(m) * report.Tau0,
+			ADEV:  adev,
+			OADEV: oadev,
+			N:     n,
+		})
+	}
+
+	return report, nil
+}
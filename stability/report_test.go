@@ -0,0 +1,93 @@
+package stability
+
+import (
+	"github.com/racerxdl/qo100-dedrift/driftlog"
+	"math"
+	"testing"
+	"time"
+)
+
+// makeRecords returns n locked records spaced by one second with a constant drift
+func makeRecords(start time.Time, n int, drift, ppm float64) []driftlog.Record {
+	records := make([]driftlog.Record, n)
+	for i := range records {
+		records[i] = driftlog.Record{
+			Time:   start.Add(time.Duration(i) * time.Second),
+			Drift:  drift,
+			PPM:    ppm,
+			Locked: true,
+		}
+	}
+
+	return records
+}
+
+func TestAnalyzeSplitsAtGaps(t *testing.T) {
+	start := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	// A restart one hour later with another drift. Stitching both runs would show a step and a drift rate.
+	records := makeRecords(start, 100, 100, 0.01)
+	records = append(records, makeRecords(start.Add(time.Hour), 100, 500, 0.05)...)
+
+	report, err := Analyze(records, []float64{1, 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Segments != 2 {
+		t.Errorf("expected 2 segments, got %d", report.Segments)
+	}
+	if report.Tau0 != 1 {
+		t.Errorf("expected tau0 of 1 second, got %f", report.Tau0)
+	}
+	if report.DriftRate != 0 {
+		t.Errorf("expected no drift rate, got %f Hz/s", report.DriftRate)
+	}
+	if report.PeakToPeak != 400 {
+		t.Errorf("expected 400 Hz peak-to-peak, got %f", report.PeakToPeak)
+	}
+
+	if len(report.Points) != 2 {
+		t.Fatalf("expected 2 points, got %d", len(report.Points))
+	}
+
+	for _, p := range report.Points {
+		if p.ADEV > 1e-15 || p.OADEV > 1e-15 {
+			t.Errorf("expected no deviation at tau %f, got ADEV %e and OADEV %e", p.Tau, p.ADEV, p.OADEV)
+		}
+	}
+
+	// Both segments contribute 100 + 1 - 2m terms
+	if report.Points[1].N != 2*(101-20) {
+		t.Errorf("expected %d terms at tau 10, got %d", 2*(101-20), report.Points[1].N)
+	}
+}
+
+func TestAnalyzeSkipsUnlocked(t *testing.T) {
+	start := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	records := makeRecords(start, 60, 0, 0)
+	for i := 20; i < 30; i++ {
+		records[i].Locked = false
+		records[i].Drift = 1000
+	}
+
+	// A slope within each locked run
+	for i := range records {
+		if records[i].Locked {
+			records[i].Drift = float64(i) * 0.5
+		}
+	}
+
+	report, err := Analyze(records, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Samples != 50 || report.Segments != 2 {
+		t.Errorf("expected 50 samples in 2 segments, got %d in %d", report.Samples, report.Segments)
+	}
+	if math.Abs(report.DriftRate-0.5) > 1e-9 {
+		t.Errorf("expected 0.5 Hz/s drift rate, got %f", report.DriftRate)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"github.com/quan-to/slog"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"github.com/racerxdl/qo100-dedrift/dedrift"
+	"github.com/racerxdl/qo100-dedrift/metrics"
+	"github.com/racerxdl/qo100-dedrift/temperature"
+	"os"
+	"sync"
+	"time"
+)
+
+// TemperatureCompensator samples the temperature inputs, learns how the drift follows the temperature while locked
+// and predicts the correction during holdover when the beacon is not available.
+type TemperatureCompensator struct {
+	sync.Mutex
+	name      string
+	cfg       config.TemperatureConfig
+	log       *slog.Instance
+	sensors   []temperature.Sensor
+	model     *temperature.Model
+	dedrifter *dedrift.Dedrifter
+	hardware  *HardwareCorrector
+	writer    *FileWriter
+	readings  map[string]float64
+	stopChan  chan bool
+
+	lastModelSample time.Time
+	unlockedSince   time.Time
+	holdover        bool
+}
+
+func MakeTemperatureCompensator(name string, cfg config.TemperatureConfig, dedrifter *dedrift.Dedrifter, hardware *HardwareCorrector, writer *FileWriter) (*TemperatureCompensator, error) {
+	tc := &TemperatureCompensator{
+		name:      name,
+		cfg:       cfg,
+		log:       slog.Scope("Temperature " + name),
+		sensors:   make([]temperature.Sensor, len(cfg.Sensors)),
+		model:     temperature.MakeModel(cfg.ModelSamples, cfg.MinSamples, cfg.MinSpan),
+		dedrifter: dedrifter,
+		hardware:  hardware,
+		writer:    writer,
+		readings:  map[string]float64{},
+		stopChan:  make(chan bool, 1),
+	}
+
+	for i, v := range cfg.Sensors {
+		s, err := temperature.MakeSensor(v)
+		if err != nil {
+			return nil, err
+		}
+		tc.sensors[i] = s
+	}
+
+	if cfg.ModelFile != "" {
+		err := tc.model.Load(cfg.ModelFile)
+		if err != nil && !os.IsNotExist(err) {
+			tc.log.Error("Error loading temperature model from %s: %s", cfg.ModelFile, err)
+		}
+	}
+
+	tc.updateModelMetrics()
+
+	return tc, nil
+}
+
+func (tc *TemperatureCompensator) Start() {
+	go tc.loop()
+}
+
+func (tc *TemperatureCompensator) Stop() {
+	tc.stopChan <- true
+}
+
+func (tc *TemperatureCompensator) loop() {
+	interval := time.Duration(tc.cfg.Interval * float64(time.Second))
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	tc.sample()
+
+	for {
+		select {
+		case <-ticker.C:
+			tc.sample()
+		case <-tc.stopChan:
+			return
+		}
+	}
+}
+
+func (tc *TemperatureCompensator) sample() {
+	for _, s := range tc.sensors {
+		v, err := s.Read()
+		if err != nil {
+			tc.log.Error("Error reading sensor %s: %s", s.GetName(), err)
+			metrics.TemperatureErrors.WithLabelValues(tc.name, s.GetName()).Inc()
+			tc.Lock()
+			delete(tc.readings, s.GetName())
+			tc.Unlock()
+			continue
+		}
+
+		metrics.Temperature.WithLabelValues(tc.name, s.GetName()).Set(v)
+		tc.Lock()
+		tc.readings[s.GetName()] = v
+		tc.Unlock()
+	}
+}
+
+// Temperature returns the last reading of the model input. It is the first sensor unless ModelInput is set.
+func (tc *TemperatureCompensator) Temperature() (float64, bool) {
+	input := tc.cfg.ModelInput
+	if input == "" && len(tc.sensors) > 0 {
+		input = tc.sensors[0].GetName()
+	}
+
+	tc.Lock()
+	defer tc.Unlock()
+
+	v, ok := tc.readings[input]
+	return v, ok
+}
+
+// hardwareCorrection returns the drift already corrected by the upstream tuner
+func (tc *TemperatureCompensator) hardwareCorrection() float64 {
+	if tc.hardware != nil {
+		return tc.hardware.Correction()
+	}
+	return 0
+}
+
+func (tc *TemperatureCompensator) updateModelMetrics() {
+	slope, intercept, samples, valid := tc.model.GetFit()
+	metrics.TemperatureSlope.WithLabelValues(tc.name).Set(slope)
+	metrics.TemperatureIntercept.WithLabelValues(tc.name).Set(intercept)
+	metrics.TemperatureSamples.WithLabelValues(tc.name).Set(float64(samples))
+	metrics.TemperatureModelValid.WithLabelValues(tc.name).Set(boolToFloat(valid))
+}
+
+// Update feeds the model while locked and drives the holdover correction while unlocked.
+// It should be called from the same goroutine as the HardwareCorrector Update.
+func (tc *TemperatureCompensator) Update() {
+	status := tc.dedrifter.GetStatus()
+	t, ok := tc.Temperature()
+
+	if status.External || status.HoldingCorrection {
+		return
+	}
+
+	if status.Locked {
+		tc.unlockedSince = time.Time{}
+		if tc.holdover {
+			tc.log.Info("Beacon is back. Leaving holdover.")
+			tc.setHoldover(0, false)
+		}
+
+		if ok && time.Since(tc.lastModelSample) > time.Duration(tc.cfg.ModelInterval*float64(time.Second)) {
+			tc.model.Add(t, float64(totalDrift(status, tc.hardware)))
+			tc.lastModelSample = time.Now()
+			tc.updateModelMetrics()
+			tc.saveModel()
+		}
+		return
+	}
+
+	if tc.unlockedSince.IsZero() {
+		tc.unlockedSince = time.Now()
+	}
+
+	if !tc.cfg.Holdover || !ok || time.Since(tc.unlockedSince) < time.Duration(tc.cfg.HoldoverDelay*float64(time.Second)) {
+		return
+	}
+
+	predicted, valid := tc.model.Predict(t)
+	if !valid {
+		return
+	}
+
+	if !tc.holdover {
+		tc.log.Warn("Beacon lost for %s. Predicting %f Hz drift from %f C.", time.Since(tc.unlockedSince), predicted, t)
+	}
+
+	tc.setHoldover(predicted, true)
+}
+
+func (tc *TemperatureCompensator) setHoldover(predicted float64, active bool) {
+	tc.holdover = active
+	tc.dedrifter.SetHoldover(float32(predicted-tc.hardwareCorrection()), active)
+	metrics.Holdover.WithLabelValues(tc.name).Set(boolToFloat(active))
+	metrics.HoldoverPrediction.WithLabelValues(tc.name).Set(predicted)
+}
+
+// saveModel queues the write of the model file, the samples are copied when the write runs
+func (tc *TemperatureCompensator) saveModel() {
+	if tc.cfg.ModelFile == "" {
+		return
+	}
+
+	tc.writer.Write("temperature model to "+tc.cfg.ModelFile, func() error {
+		return tc.model.Save(tc.cfg.ModelFile)
+	})
+}
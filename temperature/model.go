@@ -0,0 +1,150 @@
+package temperature
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"sync"
+)
+
+// Sample is a drift measurement at a temperature
+type Sample struct {
+	Temperature float64 `json:"temperature"`
+	Drift       float64 `json:"drift"`
+}
+
+// Model fits drift = Intercept + Slope * temperature over the last samples by least squares
+type Model struct {
+	sync.Mutex
+	samples    []Sample
+	maxSamples int
+	minSamples int
+	minSpan    float64
+
+	valid     bool
+	slope     float64
+	intercept float64
+}
+
+func MakeModel(maxSamples, minSamples int, minSpan float64) *Model {
+	return &Model{
+		samples:    make([]Sample, 0),
+		maxSamples: maxSamples,
+		minSamples: minSamples,
+		minSpan:    minSpan,
+	}
+}
+
+// Add adds a sample and refits the model
+func (m *Model) Add(temperature, drift float64) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.samples = append(m.samples, Sample{Temperature: temperature, Drift: drift})
+	if m.maxSamples > 0 && len(m.samples) > m.maxSamples {
+		m.samples = m.samples[len(m.samples)-m.maxSamples:]
+	}
+
+	m.fit()
+}
+
+func (m *Model) fit() {
+	m.valid = false
+
+	if len(m.samples) < m.minSamples || len(m.samples) < 2 {
+		return
+	}
+
+	var st, sd, stt, std float64
+	minT, maxT := math.Inf(1), math.Inf(-1)
+
+	for _, s := range m.samples {
+		st += s.Temperature
+		sd += s.Drift
+		stt += s.Temperature * s.Temperature
+		std += s.Temperature * s.Drift
+		minT = math.Min(minT, s.Temperature)
+		maxT = math.Max(maxT, s.Temperature)
+	}
+
+	// Without enough temperature excursion the slope is only noise
+	if maxT-minT < m.minSpan {
+		return
+	}
+
+	n := float64(len(m.samples))
+	den := n*stt - st*st
+	if den == 0 {
+		return
+	}
+
+	m.slope = (n*std - st*sd) / den
+	m.intercept = (sd - m.slope*st) / n
+	m.valid = true
+}
+
+// Predict returns the drift expected at the temperature and if the model is valid
+func (m *Model) Predict(temperature float64) (float64, bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	if !m.valid {
+		return 0, false
+	}
+
+	return m.intercept + m.slope*temperature, true
+}
+
+// GetFit returns the slope (Hertz per Celsius), intercept (Hertz), number of samples and if the model is valid
+func (m *Model) GetFit() (float64, float64, int, bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.slope, m.intercept, len(m.samples), m.valid
+}
+
+// Load reads the samples saved by Save and refits the model
+func (m *Model) Load(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var samples []Sample
+	err = json.Unmarshal(data, &samples)
+	if err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.samples = samples
+	if m.maxSamples > 0 && len(m.samples) > m.maxSamples {
+		m.samples = m.samples[len(m.samples)-m.maxSamples:]
+	}
+
+	m.fit()
+
+	return nil
+}
+
+// Save writes the samples to filename through a temporary file
+func (m *Model) Save(filename string) error {
+	m.Lock()
+	data, err := json.Marshal(m.samples)
+	m.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	tmp := filename + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filename)
+}
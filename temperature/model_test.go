@@ -0,0 +1,87 @@
+package temperature
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModelFit(t *testing.T) {
+	m := MakeModel(0, 3, 2)
+
+	m.Add(20, -400)
+	m.Add(21, -450)
+	if _, valid := m.Predict(20); valid {
+		t.Errorf("expected the model to be invalid under MinSamples")
+	}
+
+	m.Add(21.5, -475)
+	if _, valid := m.Predict(20); valid {
+		t.Errorf("expected the model to be invalid under MinSpan")
+	}
+
+	m.Add(22, -500)
+	slope, intercept, samples, valid := m.GetFit()
+	if !valid || samples != 4 {
+		t.Fatalf("expected a valid fit of 4 samples, got %v with %d", valid, samples)
+	}
+	if math.Abs(slope+50) > 1e-9 || math.Abs(intercept-600) > 1e-9 {
+		t.Errorf("expected drift = 600 - 50 * temperature, got %f + %f * temperature", intercept, slope)
+	}
+
+	predicted, _ := m.Predict(30)
+	if math.Abs(predicted+900) > 1e-9 {
+		t.Errorf("expected -900 Hz at 30 C, got %f", predicted)
+	}
+}
+
+func TestModelMaxSamples(t *testing.T) {
+	m := MakeModel(3, 2, 0)
+
+	// An old sample off the line, dropped by the next ones
+	m.Add(0, 1000)
+	for _, v := range []float64{10, 11, 12} {
+		m.Add(v, 2*v)
+	}
+
+	slope, intercept, samples, _ := m.GetFit()
+	if samples != 3 || math.Abs(slope-2) > 1e-9 || math.Abs(intercept) > 1e-9 {
+		t.Errorf("expected drift = 2 * temperature over 3 samples, got %f + %f * temperature over %d", intercept, slope, samples)
+	}
+}
+
+func TestModelSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qo100-model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "model.json")
+
+	m := MakeModel(0, 2, 1)
+	m.Add(15, 100)
+	m.Add(25, 300)
+
+	err = m.Save(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := MakeModel(0, 2, 1)
+	err = loaded.Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	predicted, valid := loaded.Predict(20)
+	if !valid || math.Abs(predicted-200) > 1e-9 {
+		t.Errorf("expected the loaded model to predict 200 Hz at 20 C, got %f (%v)", predicted, valid)
+	}
+
+	if _, err := os.Stat(filename + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected the temporary file to be renamed")
+	}
+}
@@ -0,0 +1,150 @@
+package temperature
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SensorTypeFile = "file"
+	SensorTypeHTTP = "http"
+)
+
+const httpTimeout = 5 * time.Second
+
+// Sensor is a temperature input
+type Sensor interface {
+	GetName() string
+	// Read returns the temperature in Celsius
+	Read() (float64, error)
+}
+
+// MakeSensor creates the sensor described by cfg
+func MakeSensor(cfg config.TemperatureSensorConfig) (Sensor, error) {
+	scale := cfg.Scale
+	if scale == 0 {
+		scale = 1
+	}
+
+	switch cfg.Type {
+	case SensorTypeFile:
+		return &FileSensor{name: cfg.Name, path: cfg.Path, scale: scale, offset: cfg.Offset}, nil
+	case SensorTypeHTTP:
+		return &HTTPSensor{
+			name:   cfg.Name,
+			url:    cfg.Path,
+			field:  cfg.Field,
+			scale:  scale,
+			offset: cfg.Offset,
+			client: &http.Client{Timeout: httpTimeout},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("invalid temperature sensor type %q", cfg.Type)
+}
+
+// FileSensor reads a sysfs file with a single number (like hwmon temp*_input) or a 1-wire w1_slave file
+type FileSensor struct {
+	name   string
+	path   string
+	scale  float64
+	offset float64
+}
+
+func (s *FileSensor) GetName() string {
+	return s.name
+}
+
+func (s *FileSensor) Read() (float64, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return 0, err
+	}
+
+	content := strings.TrimSpace(string(data))
+
+	// w1_slave: "xx xx ... : crc=xx YES\nxx xx ... t=23125"
+	if strings.Contains(content, "crc=") {
+		if !strings.Contains(content, "YES") {
+			return 0, fmt.Errorf("1-wire CRC check failed for %s", s.path)
+		}
+	}
+
+	if idx := strings.LastIndex(content, "t="); idx >= 0 {
+		content = content[idx+2:]
+	}
+
+	v, err := strconv.ParseFloat(strings.TrimSpace(content), 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return v*s.scale + s.offset, nil
+}
+
+// HTTPSensor reads a number from a JSON document. The field is a dot separated path like "sensors.outdoor.temperature".
+type HTTPSensor struct {
+	name   string
+	url    string
+	field  string
+	scale  float64
+	offset float64
+	client *http.Client
+}
+
+func (s *HTTPSensor) GetName() string {
+	return s.name
+}
+
+func (s *HTTPSensor) Read() (float64, error) {
+	res, err := s.client.Get(s.url)
+	if err != nil {
+		return 0, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("got status %d from %s", res.StatusCode, s.url)
+	}
+
+	var doc interface{}
+	err = json.NewDecoder(res.Body).Decode(&doc)
+	if err != nil {
+		return 0, err
+	}
+
+	if s.field != "" {
+		for _, key := range strings.Split(s.field, ".") {
+			obj, ok := doc.(map[string]interface{})
+			if !ok {
+				return 0, fmt.Errorf("field %q not found in %s", s.field, s.url)
+			}
+			doc, ok = obj[key]
+			if !ok {
+				return 0, fmt.Errorf("field %q not found in %s", s.field, s.url)
+			}
+		}
+	}
+
+	var v float64
+	switch t := doc.(type) {
+	case float64:
+		v = t
+	case string:
+		v, err = strconv.ParseFloat(t, 64)
+		if err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("field %q of %s is not a number", s.field, s.url)
+	}
+
+	return v*s.scale + s.offset, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"github.com/quan-to/slog"
+	"github.com/racerxdl/qo100-dedrift/config"
+	"github.com/racerxdl/qo100-dedrift/dedrift"
+	"github.com/racerxdl/qo100-dedrift/metrics"
+	"github.com/racerxdl/qo100-dedrift/rigctl"
+	"math"
+	"time"
+)
+
+// statusSource provides the lock status and the total drift measured by a pipeline
+type statusSource interface {
+	GetStatus() dedrift.Status
+}
+
+// UplinkCorrector drives a transmitter through rigctld so the uplink follows the measured beacon drift
+type UplinkCorrector struct {
+	name          string
+	cfg           config.UplinkConfig
+	log           *slog.Instance
+	client        *rigctl.Client
+	source        statusSource
+	rig           *PipelineRig
+	lastFrequency float64
+	stopChan      chan bool
+}
+
+func MakeUplinkCorrector(name string, cfg config.UplinkConfig, source statusSource, rig *PipelineRig) *UplinkCorrector {
+	return &UplinkCorrector{
+		name:     name,
+		cfg:      cfg,
+		log:      slog.Scope("Uplink " + name),
+		client:   rigctl.MakeClient(cfg.Address),
+		source:   source,
+		rig:      rig,
+		stopChan: make(chan bool, 1),
+	}
+}
+
+func (u *UplinkCorrector) Start() {
+	u.log.Info("Driving transmitter at %s", u.cfg.Address)
+	go u.loop()
+}
+
+func (u *UplinkCorrector) Stop() {
+	u.stopChan <- true
+}
+
+func (u *UplinkCorrector) loop() {
+	interval := time.Duration(u.cfg.MinInterval * float64(time.Second))
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer u.client.Close()
+
+	for {
+		select {
+		case <-ticker.C:
+			u.update()
+		case <-u.stopChan:
+			return
+		}
+	}
+}
+
+// nominalFrequency returns the uplink frequency without drift correction
+func (u *UplinkCorrector) nominalFrequency() float64 {
+	if u.cfg.FollowRigctl && u.rig != nil {
+		return u.rig.NominalFrequency() - u.cfg.TransponderOffset
+	}
+
+	return u.cfg.Frequency
+}
+
+// correction returns the uplink correction in Hertz and if it was clamped
+func (u *UplinkCorrector) correction(drift float32) (float64, bool) {
+	correction := u.cfg.DriftRatio * float64(drift)
+
+	if u.cfg.MaxCorrection > 0 && math.Abs(correction) > u.cfg.MaxCorrection {
+		return math.Copysign(u.cfg.MaxCorrection, correction), true
+	}
+
+	return correction, false
+}
+
+func (u *UplinkCorrector) update() {
+	status := u.source.GetStatus()
+	if !status.Locked && !status.HoldingCorrection {
+		// Keep the last correction until we're locked again
+		return
+	}
+
+	correction, clamped := u.correction(status.Drift)
+	frequency := math.Round(u.nominalFrequency() + correction)
+
+	if clamped {
+		metrics.UplinkClamped.WithLabelValues(u.name).Set(1)
+	} else {
+		metrics.UplinkClamped.WithLabelValues(u.name).Set(0)
+	}
+
+	if u.lastFrequency != 0 && math.Abs(frequency-u.lastFrequency) < u.cfg.MinStep {
+		return
+	}
+
+	if !u.client.IsConnected() {
+		err := u.client.Connect()
+		if err != nil {
+			u.log.Error("Error connecting to %s: %s", u.cfg.Address, err)
+			metrics.UplinkConnected.WithLabelValues(u.name).Set(0)
+			metrics.UplinkErrors.WithLabelValues(u.name).Inc()
+			return
+		}
+		u.log.Info("Connected to %s", u.cfg.Address)
+		metrics.UplinkConnected.WithLabelValues(u.name).Set(1)
+	}
+
+	err := u.client.SetFrequency(frequency)
+	if err != nil {
+		u.log.Error("Error setting uplink frequency to %f Hz: %s", frequency, err)
+		metrics.UplinkConnected.WithLabelValues(u.name).Set(boolToFloat(u.client.IsConnected()))
+		metrics.UplinkErrors.WithLabelValues(u.name).Inc()
+		return
+	}
+
+	u.log.Debug("Uplink frequency set to %f Hz (correction %f Hz)", frequency, correction)
+	u.lastFrequency = frequency
+	metrics.UplinkFrequency.WithLabelValues(u.name).Set(frequency)
+	metrics.UplinkCorrection.WithLabelValues(u.name).Set(correction)
+	metrics.UplinkUpdates.WithLabelValues(u.name).Inc()
+}
+
+func boolToFloat(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"github.com/racerxdl/qo100-dedrift/config"
+	"github.com/racerxdl/qo100-dedrift/dedrift"
+	"github.com/racerxdl/qo100-dedrift/rigctl"
+	"math"
+	"sync"
+	"testing"
+)
+
+// testRig records the frequencies set through the stand-in rigctld
+type testRig struct {
+	sync.Mutex
+	frequencies []float64
+}
+
+func (r *testRig) GetFrequency() float64 {
+	r.Lock()
+	defer r.Unlock()
+	if len(r.frequencies) == 0 {
+		return 0
+	}
+	return r.frequencies[len(r.frequencies)-1]
+}
+
+func (r *testRig) SetFrequency(frequency float64) error {
+	r.Lock()
+	r.frequencies = append(r.frequencies, frequency)
+	r.Unlock()
+	return nil
+}
+
+func (r *testRig) GetMode() (string, int) {
+	return "USB", 2700
+}
+
+func (r *testRig) SetMode(mode string, passband int) error {
+	return nil
+}
+
+func (r *testRig) count() int {
+	r.Lock()
+	defer r.Unlock()
+	return len(r.frequencies)
+}
+
+type testStatus struct {
+	status dedrift.Status
+}
+
+func (s *testStatus) GetStatus() dedrift.Status {
+	return s.status
+}
+
+func startTestRigctld(t *testing.T) (*rigctl.Server, *testRig) {
+	rig := &testRig{}
+	server := rigctl.MakeServer("127.0.0.1:0", rig)
+	err := server.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return server, rig
+}
+
+func TestUplinkCorrector(t *testing.T) {
+	server, rig := startTestRigctld(t)
+	defer server.Stop()
+
+	cfg := config.DefaultConfig.Server.Uplink
+	cfg.Address = server.Addr().String()
+
+	source := &testStatus{status: dedrift.Status{Locked: true, Drift: 1000}}
+	u := MakeUplinkCorrector("test", cfg, source, nil)
+	defer u.client.Close()
+
+	u.update()
+	if rig.count() != 1 {
+		t.Fatalf("expected 1 frequency set, got %d", rig.count())
+	}
+
+	expected := math.Round(cfg.Frequency + 1000*cfg.DriftRatio)
+	if rig.GetFrequency() != expected {
+		t.Errorf("expected %f Hz, got %f Hz", expected, rig.GetFrequency())
+	}
+
+	// Changes under MinStep are not sent
+	source.status.Drift = 1010
+	u.update()
+	if rig.count() != 1 {
+		t.Errorf("expected the change under MinStep to be skipped, got %d frequencies", rig.count())
+	}
+
+	// The correction is clamped to MaxCorrection
+	source.status.Drift = -1e6
+	u.update()
+	expected = cfg.Frequency - cfg.MaxCorrection
+	if rig.GetFrequency() != expected {
+		t.Errorf("expected clamped %f Hz, got %f Hz", expected, rig.GetFrequency())
+	}
+
+	// The last correction is kept while unlocked
+	source.status = dedrift.Status{Drift: 2000}
+	u.update()
+	if rig.count() != 2 {
+		t.Errorf("expected no update while unlocked, got %d frequencies", rig.count())
+	}
+}
+
+func TestUplinkCorrectorReconnect(t *testing.T) {
+	server, rig := startTestRigctld(t)
+	address := server.Addr().String()
+
+	cfg := config.DefaultConfig.Server.Uplink
+	cfg.Address = address
+
+	source := &testStatus{status: dedrift.Status{Locked: true, Drift: 100}}
+	u := MakeUplinkCorrector("test", cfg, source, nil)
+	defer u.client.Close()
+
+	u.update()
+	if !u.client.IsConnected() || rig.count() != 1 {
+		t.Fatalf("expected a connected client and 1 frequency, got %v and %d", u.client.IsConnected(), rig.count())
+	}
+
+	server.Stop()
+
+	source.status.Drift = 500
+	u.update()
+	if u.client.IsConnected() {
+		t.Errorf("expected the client to disconnect after the server stopped")
+	}
+
+	server = rigctl.MakeServer(address, rig)
+	err := server.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+
+	u.update()
+	if rig.count() != 2 {
+		t.Errorf("expected the client to reconnect and set the frequency, got %d frequencies", rig.count())
+	}
+}
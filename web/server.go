@@ -7,12 +7,14 @@ import (
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/quan-to/slog"
 	"github.com/racerxdl/qo100-dedrift/config"
 	"github.com/racerxdl/qo100-dedrift/metrics"
 	"mime"
 	"net"
 	"net/http"
+	"net/url"
 	"path"
 	"strings"
 	"sync"
@@ -20,8 +22,12 @@ import (
 )
 
 const (
-	MessageTypeMainFFT uint8 = iota
-	MessageTypeSegFFT        = iota
+	MessageTypeMainFFT     uint8 = iota
+	MessageTypeSegFFT            = iota
+	MessageTypeBeaconFrame       = iota
+	MessageTypeCWText            = iota
+	MessageTypeSignals           = iota
+	MessageTypeAlert             = iota
 )
 
 const (
@@ -39,21 +45,53 @@ type wsClient struct {
 	lastKeepAlive time.Time
 }
 
+// Namespace is a set of websocket clients and settings of a single pipeline
+type Namespace struct {
+	name           string
+	upgrader       *websocket.Upgrader
+	clients        []*wsClient
+	cLock          sync.Mutex
+	maxWsClients   int
+	settingsLock   sync.Mutex
+	settings       namespaceSettings
+	webConnections prometheus.Gauge
+	handlers       map[string]http.HandlerFunc
+}
+
+// FrequencySettings describes how the source frequencies map to absolute RF frequencies
+type FrequencySettings struct {
+	LNBFrequency      float64 `json:"lnbFrequency"`
+	UpconverterOffset float64 `json:"upconverterOffset"`
+	BeaconFrequency   float64 `json:"beaconFrequency"`
+}
+
+// GainSettings describes the gains supported by the source tuner and the current selection
+type GainSettings struct {
+	Tuner string    `json:"tuner"`
+	Gains []float64 `json:"gains"` // dB, empty when the tuner gains are not known
+	Gain  float64   `json:"gain"`
+	Index int       `json:"index"` // Index of the gain in Gains, -1 when it is not one of them
+	Auto  bool      `json:"auto"`  // The gain is set by the automatic gain control
+}
+
+type namespaceSettings struct {
+	config.WebSettings
+	FrequencySettings
+	Pipeline string        `json:"pipeline"`
+	Gain     *GainSettings `json:"gain,omitempty"`
+}
+
 type Server struct {
 	address string
 
-	running      bool
-	stopChan     chan bool
-	listener     net.Listener
-	upgrader     websocket.Upgrader
-	clients      []*wsClient
-	cLock        sync.Mutex
-	maxWsClients int
-	settings     []byte
+	running    bool
+	stopChan   chan bool
+	listener   net.Listener
+	upgrader   websocket.Upgrader
+	namespaces []*Namespace
 }
 
-func MakeWebServer(address string, maxWsClients int, settings config.WebSettings) *Server {
-	s, _ := json.MarshalIndent(settings, "", "   ")
+func MakeWebServer(address string) *Server {
 	return &Server{
 		address:  address,
 		running:  false,
@@ -63,50 +101,104 @@ func MakeWebServer(address string, maxWsClients int, settings config.WebSettings
 				return true
 			},
 		},
-		clients:      make([]*wsClient, 0),
-		cLock:        sync.Mutex{},
-		maxWsClients: maxWsClients,
-		settings:     s,
+		namespaces: make([]*Namespace, 0),
 	}
 }
 
-func (ws *Server) putClient(c *wsClient) {
-	ws.cLock.Lock()
+// reservedNames are the paths served at the root that a namespace name would shadow
+var reservedNames = []string{"metrics", "ws", "settings.json", "pipelines.json"}
 
-	ws.clients = append(ws.clients, c)
+// ValidateNamespaceName checks that a name can be used as the URL prefix of a namespace. It can not be one of the
+// root paths served by the server, like the metrics or the web app assets, and should not need URL escaping.
+func ValidateNamespaceName(name string) error {
+	if url.PathEscape(name) != name {
+		return fmt.Errorf("only URL safe characters are allowed")
+	}
+
+	for _, v := range reservedNames {
+		if name == v {
+			return fmt.Errorf("%q is reserved", name)
+		}
+	}
+
+	for _, v := range AssetNames() {
+		if name == strings.SplitN(v, "/", 2)[0] {
+			return fmt.Errorf("%q is used by the web app", name)
+		}
+	}
 
-	ws.cLock.Unlock()
+	return nil
+}
+
+// AddNamespace registers a pipeline namespace served at /name/. The first namespace is also served at the root.
+// It should be called before Start.
+func (ws *Server) AddNamespace(name string, maxWsClients int, settings config.WebSettings, frequencies FrequencySettings) *Namespace {
+	ns := &Namespace{
+		name:           name,
+		upgrader:       &ws.upgrader,
+		clients:        make([]*wsClient, 0),
+		cLock:          sync.Mutex{},
+		maxWsClients:   maxWsClients,
+		settings:       namespaceSettings{WebSettings: settings, FrequencySettings: frequencies, Pipeline: name},
+		webConnections: metrics.WebConnections.WithLabelValues(name),
+		handlers:       map[string]http.HandlerFunc{},
+	}
+
+	metrics.MaxWebConnections.WithLabelValues(name).Add(float64(maxWsClients))
+	ws.namespaces = append(ws.namespaces, ns)
+
+	return ns
+}
+
+func (ns *Namespace) GetName() string {
+	return ns.name
 }
 
-func (ws *Server) removeClient(c *wsClient) {
-	ws.cLock.Lock()
+func (ns *Namespace) putClient(c *wsClient) {
+	ns.cLock.Lock()
+
+	ns.clients = append(ns.clients, c)
+
+	ns.cLock.Unlock()
+}
 
-	for i, v := range ws.clients {
+func (ns *Namespace) removeClient(c *wsClient) {
+	ns.cLock.Lock()
+
+	for i, v := range ns.clients {
 		if v == c {
-			ws.clients = append(ws.clients[:i], ws.clients[i+1:]...)
+			ns.clients = append(ns.clients[:i], ns.clients[i+1:]...)
 			break
 		}
 	}
 
-	ws.cLock.Unlock()
+	ns.cLock.Unlock()
 }
 
-func (ws *Server) maxClients() bool {
-	ws.cLock.Lock()
-	clientCount := len(ws.clients)
-	ws.cLock.Unlock()
+func (ns *Namespace) maxClients() bool {
+	ns.cLock.Lock()
+	clientCount := len(ns.clients)
+	ns.cLock.Unlock()
 
-	return clientCount >= ws.maxWsClients
+	return clientCount >= ns.maxWsClients
 }
 
-func (ws *Server) websocket(w http.ResponseWriter, r *http.Request) {
-	if ws.maxClients() {
+func (ns *Namespace) closeClients() {
+	ns.cLock.Lock()
+	for _, v := range ns.clients {
+		v.closeChan <- true
+	}
+	ns.cLock.Unlock()
+}
+
+func (ns *Namespace) websocket(w http.ResponseWriter, r *http.Request) {
+	if ns.maxClients() {
 		w.WriteHeader(503)
 		_, _ = w.Write([]byte("Max connections reached"))
 		return
 	}
 
-	c, err := ws.upgrader.Upgrade(w, r, nil)
+	c, err := ns.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Error("Error upgrading: %s", err)
 		return
@@ -118,12 +210,12 @@ func (ws *Server) websocket(w http.ResponseWriter, r *http.Request) {
 		lastKeepAlive: time.Now(),
 	}
 
-	ws.putClient(client)
-	log.Info("Websocket Client %s connected", c.RemoteAddr())
+	ns.putClient(client)
+	log.Info("Websocket Client %s connected to %s", c.RemoteAddr(), ns.name)
 	running := true
 	ticker := time.NewTicker(time.Second)
 
-	metrics.WebConnections.Inc()
+	ns.webConnections.Inc()
 
 	_ = c.SetReadDeadline(time.Now().Add(pongWait))
 	c.SetPongHandler(func(string) error { _ = c.SetReadDeadline(time.Now().Add(pongWait)); return nil })
@@ -164,9 +256,9 @@ func (ws *Server) websocket(w http.ResponseWriter, r *http.Request) {
 			client.Unlock()
 		}
 	}
-	log.Info("Websocket Client %s disconnected", c.RemoteAddr())
+	log.Info("Websocket Client %s disconnected from %s", c.RemoteAddr(), ns.name)
 	ticker.Stop()
-	ws.removeClient(client)
+	ns.removeClient(client)
 
 	// Send Close
 	_ = c.SetWriteDeadline(time.Now().Add(time.Second))
@@ -174,26 +266,41 @@ func (ws *Server) websocket(w http.ResponseWriter, r *http.Request) {
 
 	// Close
 	c.Close()
-	metrics.WebConnections.Dec()
+	ns.webConnections.Dec()
 }
 
-func (ws *Server) BroadcastFFT(fftType uint8, fft []float32) {
-	ws.cLock.Lock()
-
+func (ns *Namespace) BroadcastFFT(fftType uint8, fft []float32) {
 	b := bytes.NewBuffer(nil)
 	_ = binary.Write(b, binary.LittleEndian, &fft)
 
-	ob := append([]byte{fftType}, b.Bytes()...) // First Byte is FFT Type
+	ns.broadcast(fftType, b.Bytes())
+}
+
+// BroadcastJSON sends v encoded as JSON to all websocket clients
+func (ns *Namespace) BroadcastJSON(msgType uint8, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Error("Error encoding message: %s", err)
+		return
+	}
+
+	ns.broadcast(msgType, data)
+}
+
+func (ns *Namespace) broadcast(msgType uint8, data []byte) {
+	ns.cLock.Lock()
+
+	ob := append([]byte{msgType}, data...) // First Byte is Message Type
 
 	msg, err := websocket.NewPreparedMessage(websocket.BinaryMessage, ob)
 
 	if err != nil {
 		log.Error("Error creating message: %s", err)
-		ws.cLock.Unlock()
+		ns.cLock.Unlock()
 		return
 	}
 
-	for _, v := range ws.clients {
+	for _, v := range ns.clients {
 		v.Lock()
 		err := v.connection.WritePreparedMessage(msg)
 		v.Unlock()
@@ -205,7 +312,29 @@ func (ws *Server) BroadcastFFT(fftType uint8, fft []float32) {
 		}
 	}
 
-	ws.cLock.Unlock()
+	ns.cLock.Unlock()
+}
+
+// HandleFunc registers an extra handler served at /name/route. It should be called before Start.
+func (ns *Namespace) HandleFunc(route string, handler http.HandlerFunc) {
+	ns.handlers[route] = handler
+}
+
+// SetGainSettings updates the gain information served in the settings
+func (ns *Namespace) SetGainSettings(gain GainSettings) {
+	ns.settingsLock.Lock()
+	ns.settings.Gain = &gain
+	ns.settingsLock.Unlock()
+}
+
+func (ns *Namespace) settingsHandler(w http.ResponseWriter, r *http.Request) {
+	ns.settingsLock.Lock()
+	s, _ := json.MarshalIndent(ns.settings, "", "   ")
+	ns.settingsLock.Unlock()
+
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(200)
+	_, _ = w.Write(s)
 }
 
 func (ws *Server) Start() error {
@@ -230,13 +359,25 @@ func (ws *Server) Stop() {
 	if ws.running {
 		ws.running = false
 		_ = ws.listener.Close()
-		for _, v := range ws.clients {
-			v.closeChan <- true
+		for _, ns := range ws.namespaces {
+			ns.closeClients()
 		}
 		<-ws.stopChan
 	}
 }
 
+func (ws *Server) pipelinesHandler(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, len(ws.namespaces))
+	for i, ns := range ws.namespaces {
+		names[i] = ns.name
+	}
+
+	data, _ := json.Marshal(names)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(200)
+	_, _ = w.Write(data)
+}
+
 func (ws *Server) loop() {
 	srv := &http.Server{}
 	router := mux.NewRouter()
@@ -272,12 +413,23 @@ func (ws *Server) loop() {
 	}
 
 	router.Handle("/metrics", metrics.GetHandler())
-	router.HandleFunc("/ws", ws.websocket)
-	router.HandleFunc("/settings.json", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("content-type", "application/json")
-		w.WriteHeader(200)
-		_, _ = w.Write(ws.settings)
-	})
+	router.HandleFunc("/pipelines.json", ws.pipelinesHandler)
+
+	for i, ns := range ws.namespaces {
+		prefix := path.Join("/", ns.name)
+		router.HandleFunc(path.Join(prefix, "ws"), ns.websocket)
+		router.HandleFunc(path.Join(prefix, "settings.json"), ns.settingsHandler)
+		for route, handler := range ns.handlers {
+			router.HandleFunc(path.Join(prefix, route), handler)
+		}
+		if i == 0 {
+			router.HandleFunc("/ws", ns.websocket)
+			router.HandleFunc("/settings.json", ns.settingsHandler)
+			for route, handler := range ns.handlers {
+				router.HandleFunc(path.Join("/", route), handler)
+			}
+		}
+	}
 
 	indexHandler := func(w http.ResponseWriter, r *http.Request) {
 		data, err := Asset("index.html")
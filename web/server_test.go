@@ -0,0 +1,21 @@
+package web
+
+import (
+	"testing"
+)
+
+func TestValidateNamespaceName(t *testing.T) {
+	valid := []string{"main", "dish1", "dish-2", "lnb_b"}
+	for _, name := range valid {
+		if err := ValidateNamespaceName(name); err != nil {
+			t.Errorf("expected %q to be valid: %s", name, err)
+		}
+	}
+
+	invalid := []string{"metrics", "ws", "settings.json", "pipelines.json", "index.html", "static", "favicon.ico", "dish/1", "dish 1"}
+	for _, name := range invalid {
+		if err := ValidateNamespaceName(name); err == nil {
+			t.Errorf("expected %q to be rejected", name)
+		}
+	}
+}
@@ -1,7 +1,22 @@
 #!/bin/bash
+# Rebuilds the web app and embeds it in ../web/webdata.go. Run it after any change under src or public.
+set -e
 
-echo "Installing go-bindata"
-go get -u github.com/go-bindata/go-bindata/...
+cd "$(dirname "$0")"
+
+if ! command -v go-bindata > /dev/null; then
+  echo "Installing go-bindata"
+  go install github.com/go-bindata/go-bindata/...@latest
+  export PATH="$PATH:$(go env GOPATH)/bin"
+fi
+
+echo "Installing dependencies"
+yarn install --frozen-lockfile
+
+# react-scripts 2 uses the MD4 hash removed from the default OpenSSL provider of Node 17+
+if [ "$(node -p 'process.versions.node.split(".")[0]')" -ge 17 ]; then
+  export NODE_OPTIONS="--openssl-legacy-provider"
+fi
 
 echo "Building React App"
 yarn build
@@ -10,4 +25,3 @@ echo "Bundling to ../web"
 go-bindata -pkg web -o "../web/webdata.go" -prefix build/ build/...
 
 echo "Done!"
-
@@ -3,7 +3,7 @@ import 'c3/c3.css';
 
 import './App.css';
 import {connect} from "react-redux";
-import {FFTBoard, MetricsBoard} from './Components';
+import {AlertsBoard, BeaconBoard, FFTBoard, MetricsBoard, SignalsBoard, TunerBoard} from './Components';
 import AppBar from "@material-ui/core/AppBar";
 import Typography from "@material-ui/core/Typography";
 import Toolbar from "@material-ui/core/Toolbar";
@@ -26,8 +26,12 @@ class App extends Component<AppProps, AppState> {
           </Toolbar>
         </AppBar>
         <br/>
+        <AlertsBoard/>
         <FFTBoard/>
+        <SignalsBoard/>
+        <TunerBoard/>
         <MetricsBoard/>
+        <BeaconBoard/>
       </div>
     );
   }
@@ -1,12 +1,17 @@
-import {BufferToFloatArray, ParseMetrics} from "../Tools";
+import {BufferToFloatArray, BufferToJSON, ParseMetrics} from "../Tools";
 import {Metric} from "../Tools/types";
-import {SettingsState} from "../actions/types";
+import {Alert, BeaconFrame, CWStatus, SettingsState, SignalsState} from "../actions/types";
 
 type OnClose = () => void;
 type OnOpen = () => void;
 type OnFFT = (samples: number[]) => void;
 type OnMetrics = (metrics: Metric[]) => void;
 type OnSettings = (settings: SettingsState) => void;
+type OnBeaconFrame = (frame: BeaconFrame) => void;
+type OnCWStatus = (status: CWStatus) => void;
+type OnSignals = (signals: SignalsState) => void;
+type OnAlert = (alert: Alert) => void;
+type OnAlerts = (alerts: Alert[]) => void;
 
 class Client {
   conn?: WebSocket;
@@ -16,13 +21,20 @@ class Client {
   onOpen?: OnOpen;
   onMetrics?: OnMetrics;
   onSettings?: OnSettings;
+  onBeaconFrame?: OnBeaconFrame;
+  onCWStatus?: OnCWStatus;
+  onSignals?: OnSignals;
+  onAlert?: OnAlert;
+  onAlerts?: OnAlerts;
 
   host: string;
+  basePath: string;
   tmp: boolean;
   isSSL: boolean;
   websocketUrl: string;
   metricsUrl: string;
   settingsUrl: string;
+  alertsUrl: string;
   metrics: Metric[];
   running: boolean;
   settings?: SettingsState;
@@ -35,10 +47,13 @@ class Client {
 
   constructor(host?: string) {
     this.host = host || document.location.host;
+    // Pipelines are served under /<pipeline name>/
+    this.basePath = document.location.pathname.replace(/\/+$/, '');
     this.isSSL = document.location.protocol !== 'http:';
-    this.websocketUrl = `${this.isSSL ? 'wss://' : 'ws://'}${this.host}/ws`;
+    this.websocketUrl = `${this.isSSL ? 'wss://' : 'ws://'}${this.host}${this.basePath}/ws`;
     this.metricsUrl = `${this.isSSL ? 'https://' : 'http://'}${this.host}/metrics`;
-    this.settingsUrl = `${this.isSSL ? 'https://' : 'http://'}${this.host}/settings.json`;
+    this.settingsUrl = `${this.isSSL ? 'https://' : 'http://'}${this.host}${this.basePath}/settings.json`;
+    this.alertsUrl = `${this.isSSL ? 'https://' : 'http://'}${this.host}${this.basePath}/alerts.json`;
     this.tmp = false;
     this.metrics = [];
     this.serverSampleRate = 0;
@@ -58,10 +73,22 @@ class Client {
     }
   };
 
+  // updateAlerts loads the alerts fired before the page was opened. It is not served when alerts are disabled.
+  updateAlerts = async () => {
+    const d = await fetch(this.alertsUrl);
+    if (!d.ok) {
+      return;
+    }
+    const alerts = await d.json();
+    if (this.onAlerts) {
+      this.onAlerts(alerts);
+    }
+  };
+
   updateMetrics = async () => {
     const data = await fetch(this.metricsUrl);
     const metricsText = await data.text();
-    this.metrics = ParseMetrics(metricsText);
+    this.metrics = this.filterPipeline(ParseMetrics(metricsText));
     this.refreshCache();
     await this.sendKeepAlive();
     await this.updateSettings();
@@ -71,6 +98,18 @@ class Client {
     setTimeout(this.updateMetrics, 1000);
   };
 
+  filterPipeline = (metrics: Metric[]) => {
+    const pipeline = this.settings ? this.settings.pipeline : undefined;
+    if (!pipeline) {
+      return metrics;
+    }
+
+    return metrics.map((m) => ({
+      ...m,
+      metrics: m.metrics.filter((v: Metric) => !v.labels || v.labels.pipeline === pipeline),
+    })).filter((m) => m.metrics.length > 0);
+  };
+
   sendKeepAlive = () => {
     if (this.conn) {
       this.conn.send("KEEP");
@@ -116,7 +155,7 @@ class Client {
   start = () => {
     if (WebSocket) {
       this.running = true;
-      this.conn = new WebSocket("ws://" + this.host + "/ws");
+      this.conn = new WebSocket(this.websocketUrl);
       this.conn.binaryType = 'arraybuffer';
       this.conn.onerror = (err) => {
         console.log(`WS Error: `, err);
@@ -138,20 +177,40 @@ class Client {
         if (this.onOpen) {
           this.onOpen();
         }
+        this.updateAlerts();
       };
       this.conn.onmessage = (evt) => {
         const {data} = evt;
         const dv = new DataView(data);
-        const d = BufferToFloatArray(data.slice(1));
         switch (dv.getUint8(0)) {
           case 0: // FullFFT
             if (this.onFullFFT) {
-              this.onFullFFT(d);
+              this.onFullFFT(BufferToFloatArray(data.slice(1)));
             }
             break;
           case 1: // SegFFT
             if (this.onSegFFT) {
-              this.onSegFFT(d);
+              this.onSegFFT(BufferToFloatArray(data.slice(1)));
+            }
+            break;
+          case 2: // Beacon Frame
+            if (this.onBeaconFrame) {
+              this.onBeaconFrame(BufferToJSON(data.slice(1)));
+            }
+            break;
+          case 3: // CW Text
+            if (this.onCWStatus) {
+              this.onCWStatus(BufferToJSON(data.slice(1)));
+            }
+            break;
+          case 4: // Signals
+            if (this.onSignals) {
+              this.onSignals(BufferToJSON(data.slice(1)));
+            }
+            break;
+          case 5: // Alert
+            if (this.onAlert) {
+              this.onAlert(BufferToJSON(data.slice(1)));
             }
             break;
         }
@@ -178,6 +237,26 @@ class Client {
     this.onSegFFT = cb;
   }
 
+  setOnBeaconFrame(cb: OnBeaconFrame) {
+    this.onBeaconFrame = cb;
+  }
+
+  setOnCWStatus(cb: OnCWStatus) {
+    this.onCWStatus = cb;
+  }
+
+  setOnSignals(cb: OnSignals) {
+    this.onSignals = cb;
+  }
+
+  setOnAlert(cb: OnAlert) {
+    this.onAlert = cb;
+  }
+
+  setOnAlerts(cb: OnAlerts) {
+    this.onAlerts = cb;
+  }
+
   setOnClose(cb: OnClose) {
     this.onClose = cb;
   }
@@ -0,0 +1,77 @@
+import {Component, default as React} from "react";
+import {connect} from "react-redux";
+import Typography from "@material-ui/core/Typography";
+import Table from "@material-ui/core/Table";
+import TableBody from "@material-ui/core/TableBody";
+import TableCell from "@material-ui/core/TableCell";
+import TableHead from "@material-ui/core/TableHead";
+import TableRow from "@material-ui/core/TableRow";
+import {AlertsState} from "../../actions/types";
+
+type AlertsBoardProps = {
+  alerts: AlertsState,
+}
+
+const divStyle = {
+  padding: '20px',
+};
+
+const firingStyle = {
+  color: '#d32f2f',
+  fontWeight: 'bold' as 'bold',
+};
+
+const formatTime = (time?: string) => time ? new Date(time).toLocaleString() : '';
+
+class AlertsBoard extends Component<AlertsBoardProps> {
+  render() {
+    const {alerts} = this.props.alerts;
+
+    if (alerts.length === 0) {
+      return null;
+    }
+
+    const firing = alerts.filter((a) => a.state === 'firing').length;
+
+    return (
+      <div style={divStyle}>
+        <Typography variant="h2" component="h1">
+          Alerts
+        </Typography>
+        <Typography variant="subtitle1" color="textSecondary">
+          {firing} firing
+        </Typography>
+        <Table>
+          <TableHead>
+            <TableRow>
+              <TableCell>State</TableCell>
+              <TableCell>Rule</TableCell>
+              <TableCell>Message</TableCell>
+              <TableCell>Start</TableCell>
+              <TableCell>End</TableCell>
+            </TableRow>
+          </TableHead>
+          <TableBody>
+            {alerts.map((a) => (
+              <TableRow key={a.id}>
+                <TableCell style={a.state === 'firing' ? firingStyle : undefined}>{a.state.toUpperCase()}</TableCell>
+                <TableCell>{a.rule}</TableCell>
+                <TableCell>{a.message}</TableCell>
+                <TableCell>{formatTime(a.start)}</TableCell>
+                <TableCell>{formatTime(a.end)}</TableCell>
+              </TableRow>
+            ))}
+          </TableBody>
+        </Table>
+      </div>
+    )
+  }
+}
+
+const mapStateToProps = (state: any) => {
+  return ({
+    alerts: state.alerts,
+  });
+};
+
+export default connect(mapStateToProps)(AlertsBoard);
@@ -0,0 +1,68 @@
+import {Component, default as React} from "react";
+import {connect} from "react-redux";
+import Typography from "@material-ui/core/Typography";
+import Card from "@material-ui/core/Card";
+import CardContent from "@material-ui/core/CardContent";
+import {BeaconFrame, CWStatus} from "../../actions/types";
+
+type BeaconBoardProps = {
+  frames: BeaconFrame[],
+  cw?: CWStatus,
+}
+
+const divStyle = {
+  padding: '20px',
+};
+
+const textStyle = {
+  textAlign: 'left' as 'left',
+  whiteSpace: 'pre-wrap' as 'pre-wrap',
+  margin: 0,
+};
+
+class BeaconBoard extends Component<BeaconBoardProps> {
+  render() {
+    const {frames, cw} = this.props;
+
+    if (frames.length === 0 && !cw) {
+      return null;
+    }
+
+    return (
+      <div style={divStyle}>
+        <Typography variant="h2" component="h1">
+          Beacon
+        </Typography>
+        {cw ? (
+          <Card>
+            <CardContent>
+              <Typography variant="subtitle1" color={cw.verified ? "primary" : "error"}>
+                CW {cw.verified ? "verified" : "not verified"} - {cw.wpm.toFixed(0)} WPM - Confidence {(cw.confidence * 100).toFixed(0)}%
+              </Typography>
+              <pre style={textStyle}>{cw.text}</pre>
+            </CardContent>
+          </Card>
+        ) : null}
+        {frames.map((frame) => (
+          <Card key={`${frame.number}_${frame.time}`}>
+            <CardContent>
+              <Typography variant="subtitle1" color="textSecondary">
+                #{frame.number} - {new Date(frame.time).toLocaleString()} - MER {frame.mer.toFixed(1)} dB
+              </Typography>
+              {frame.text ? <pre style={textStyle}>{frame.text}</pre> : null}
+            </CardContent>
+          </Card>
+        ))}
+      </div>
+    )
+  }
+}
+
+const mapStateToProps = (state: any) => {
+  return ({
+    frames: state.beacon.frames,
+    cw: state.beacon.cw,
+  });
+};
+
+export default connect(mapStateToProps)(BeaconBoard);
@@ -23,8 +23,12 @@ const itemGenerator: { [id: string]: (metric: Metric) => any } = {
   'GAUGE': MakeGauge,
 };
 
+// Metrics that are shown somewhere else
+const Hidden = () => null;
+
 const generatorOverride: { [id: string]: ((metric: Metric) => any) | null } = {
   '_': null,
+  'cw_text': Hidden,
   'server_samplerate': MakeCounter,
   'segment_samplerate': MakeCounter,
 };
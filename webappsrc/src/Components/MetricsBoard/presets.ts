@@ -1,5 +1,57 @@
 import {toNotationUnit} from "../../Tools";
 
+const dbPreset = (units: string, min: number, max: number, pattern: string[]) => {
+  return {
+    preCompute: (value: number) => {
+      return {
+        value: Math.round(value * 10) / 10,
+        units: ` ${units}`,
+      };
+    },
+    gauge: {
+      units,
+      min,
+      max,
+      label: {
+        format: (value: number) => `${value}`,
+      },
+    },
+    color: {
+      pattern,
+      threshold: {
+        unit: 'percentage',
+        values: [25, 50, 75, 100],
+      },
+    },
+  };
+};
+
+const hzPreset = (min: number, max: number, pattern: string[]) => {
+  return {
+    preCompute: (value: number) => {
+      return {
+        value: Math.round(value * 1000) / 1000,
+        units: ' Hz',
+      };
+    },
+    gauge: {
+      units: 'Hz',
+      min,
+      max,
+      label: {
+        format: (value: number) => `${value}`,
+      },
+    },
+    color: {
+      pattern,
+      threshold: {
+        unit: 'percentage',
+        values: [25, 50, 75, 100],
+      },
+    },
+  };
+};
+
 const gaugePresets: { [id: string]: any } = {
   '_': {
     gauge: {},
@@ -118,6 +170,14 @@ const gaugePresets: { [id: string]: any } = {
       }
     },
   },
+  'beacon_power': dbPreset('dBFS', -120, 0, ['#FF0000', '#F97600', '#F6C600', '#60B044']),
+  'noise_floor': dbPreset('dBFS/Hz', -180, -80, ['#60B044', '#F6C600', '#F97600', '#FF0000']),
+  'beacon_snr': dbPreset('dB', 0, 40, ['#FF0000', '#F97600', '#F6C600', '#60B044']),
+  'beacon_cn0': dbPreset('dB-Hz', 20, 80, ['#FF0000', '#F97600', '#F6C600', '#60B044']),
+  'agc_gain': dbPreset('dB', -60, 60, ['#FF0000', '#F97600', '#F6C600', '#60B044']),
+  'residual_error': hzPreset(-10, 10, ['#60B044', '#F6C600', '#F97600', '#FF0000']),
+  'residual_width': hzPreset(0, 5, ['#60B044', '#F6C600', '#F97600', '#FF0000']),
+  'residual_jitter': hzPreset(0, 2, ['#60B044', '#F6C600', '#F97600', '#FF0000']),
 };
 
 export {
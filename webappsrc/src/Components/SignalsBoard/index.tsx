@@ -0,0 +1,76 @@
+import {Component, default as React} from "react";
+import {connect} from "react-redux";
+import Typography from "@material-ui/core/Typography";
+import Table from "@material-ui/core/Table";
+import TableBody from "@material-ui/core/TableBody";
+import TableCell from "@material-ui/core/TableCell";
+import TableHead from "@material-ui/core/TableHead";
+import TableRow from "@material-ui/core/TableRow";
+import {SignalsState} from "../../actions/types";
+
+type SignalsBoardProps = {
+  signals: SignalsState,
+}
+
+const divStyle = {
+  padding: '20px',
+};
+
+const formatDuration = (firstSeen: string, lastSeen: string) => {
+  const seconds = Math.round((new Date(lastSeen).getTime() - new Date(firstSeen).getTime()) / 1000);
+  const minutes = Math.floor(seconds / 60);
+  return minutes > 0 ? `${minutes}m ${seconds % 60}s` : `${seconds}s`;
+};
+
+class SignalsBoard extends Component<SignalsBoardProps> {
+  render() {
+    const {signals} = this.props;
+
+    if (!signals.time) {
+      return null;
+    }
+
+    return (
+      <div style={divStyle}>
+        <Typography variant="h2" component="h1">
+          Activity
+        </Typography>
+        <Typography variant="subtitle1" color="textSecondary">
+          {signals.signals.length} signals - Noise floor {signals.noiseFloor.toFixed(1)} dBFS
+        </Typography>
+        <Table>
+          <TableHead>
+            <TableRow>
+              <TableCell>Frequency</TableCell>
+              <TableCell>Mode</TableCell>
+              <TableCell align="right">Bandwidth</TableCell>
+              <TableCell align="right">Power</TableCell>
+              <TableCell align="right">SNR</TableCell>
+              <TableCell align="right">Duration</TableCell>
+            </TableRow>
+          </TableHead>
+          <TableBody>
+            {signals.signals.map((s) => (
+              <TableRow key={s.id}>
+                <TableCell>{(s.frequency / 1e6).toFixed(4)} MHz</TableCell>
+                <TableCell>{s.mode.toUpperCase()}</TableCell>
+                <TableCell align="right">{Math.round(s.bandwidth)} Hz</TableCell>
+                <TableCell align="right">{s.power.toFixed(1)} dBFS</TableCell>
+                <TableCell align="right">{s.snr.toFixed(1)} dB</TableCell>
+                <TableCell align="right">{formatDuration(s.firstSeen, s.lastSeen)}</TableCell>
+              </TableRow>
+            ))}
+          </TableBody>
+        </Table>
+      </div>
+    )
+  }
+}
+
+const mapStateToProps = (state: any) => {
+  return ({
+    signals: state.signals,
+  });
+};
+
+export default connect(mapStateToProps)(SignalsBoard);
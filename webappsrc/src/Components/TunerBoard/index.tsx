@@ -0,0 +1,54 @@
+import {Component, default as React} from "react";
+import {connect} from "react-redux";
+import Typography from "@material-ui/core/Typography";
+import Chip from "@material-ui/core/Chip";
+import {GainSettings} from "../../actions/types";
+
+type TunerBoardProps = {
+  gain?: GainSettings,
+}
+
+const divStyle = {
+  padding: '20px',
+};
+
+const chipStyle = {
+  margin: '4px',
+};
+
+class TunerBoard extends Component<TunerBoardProps> {
+  render() {
+    const {gain} = this.props;
+
+    if (!gain) {
+      return null;
+    }
+
+    return (
+      <div style={divStyle}>
+        <Typography variant="h2" component="h1">
+          Tuner
+        </Typography>
+        <Typography variant="subtitle1" color="textSecondary">
+          {gain.tuner} - Gain {gain.gain.toFixed(1)} dB {gain.auto ? "(automatic)" : ""}
+        </Typography>
+        {gain.gains.map((g, i) => (
+          <Chip
+            key={i}
+            style={chipStyle}
+            label={`${g.toFixed(1)} dB`}
+            color={i === gain.index ? "primary" : "default"}
+          />
+        ))}
+      </div>
+    )
+  }
+}
+
+const mapStateToProps = (state: any) => {
+  return ({
+    gain: state.settings.gain,
+  });
+};
+
+export default connect(mapStateToProps)(TunerBoard);
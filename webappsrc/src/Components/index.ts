@@ -1,9 +1,17 @@
+import AlertsBoard from './AlertsBoard';
+import BeaconBoard from './BeaconBoard';
 import FFT from './FFT';
 import FFTBoard from './FFTBoard';
 import MetricsBoard from './MetricsBoard';
+import SignalsBoard from './SignalsBoard';
+import TunerBoard from './TunerBoard';
 
 export {
+  AlertsBoard,
+  BeaconBoard,
   FFT,
   FFTBoard,
   MetricsBoard,
+  SignalsBoard,
+  TunerBoard,
 }
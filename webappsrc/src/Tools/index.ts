@@ -1,5 +1,6 @@
 import {
   BufferToFloatArray,
+  BufferToJSON,
   UnescapeHelp,
 } from './parsers';
 
@@ -16,6 +17,7 @@ import {
 export {
   ParseMetrics,
   BufferToFloatArray,
+  BufferToJSON,
   UnescapeHelp,
   toNotationUnit,
   toHzNotation,
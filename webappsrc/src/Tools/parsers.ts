@@ -41,6 +41,10 @@ function BufferToFloatArray(data: ArrayBuffer): number[] {
   return out;
 }
 
+function BufferToJSON(data: ArrayBuffer): any {
+  return JSON.parse(new TextDecoder('utf-8').decode(data));
+}
+
 function ShallowObjectEquals(obj1: any | void | null, obj2: any | void | null): boolean {
   if (!obj1 || !obj2) {
     return obj1 === obj2;
@@ -63,6 +67,7 @@ function ShallowObjectEquals(obj1: any | void | null, obj2: any | void | null):
 
 export {
   BufferToFloatArray,
+  BufferToJSON,
   UnescapeHelp,
   ShallowObjectEquals,
 }
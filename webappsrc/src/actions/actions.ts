@@ -1,4 +1,20 @@
-import {FFTAction, MetricsAction, SettingsAction, SettingsState, StatusAction, StatusState} from "./types";
+import {
+  Alert,
+  AlertAction,
+  AlertsAction,
+  BeaconAction,
+  BeaconFrame,
+  CWStatus,
+  CWStatusAction,
+  FFTAction,
+  MetricsAction,
+  SettingsAction,
+  SettingsState,
+  SignalsAction,
+  SignalsState,
+  StatusAction,
+  StatusState
+} from "./types";
 import {Metric} from "../Tools/types";
 
 const DefinedActions = {
@@ -7,6 +23,11 @@ const DefinedActions = {
   AddMetrics: 'ADD_METRICS',
   SetSettings: 'SET_SETTINGS',
   SetStatus: 'SET_STATUS',
+  AddBeaconFrame: 'ADD_BEACON_FRAME',
+  SetCWStatus: 'SET_CW_STATUS',
+  SetSignals: 'SET_SIGNALS',
+  AddAlert: 'ADD_ALERT',
+  SetAlerts: 'SET_ALERTS',
 };
 
 
@@ -50,6 +71,41 @@ function SetStatus(status: StatusState): StatusAction {
   }
 }
 
+function AddBeaconFrame(frame: BeaconFrame): BeaconAction {
+  return {
+    type: DefinedActions.AddBeaconFrame,
+    frame,
+  }
+}
+
+function SetCWStatus(cw: CWStatus): CWStatusAction {
+  return {
+    type: DefinedActions.SetCWStatus,
+    cw,
+  }
+}
+
+function SetSignals(signals: SignalsState): SignalsAction {
+  return {
+    type: DefinedActions.SetSignals,
+    ...signals,
+  }
+}
+
+function AddAlert(alert: Alert): AlertAction {
+  return {
+    type: DefinedActions.AddAlert,
+    alert,
+  }
+}
+
+function SetAlerts(alerts: Alert[]): AlertsAction {
+  return {
+    type: DefinedActions.SetAlerts,
+    alerts,
+  }
+}
+
 export {
   DefinedActions,
   AddMetrics,
@@ -57,4 +113,9 @@ export {
   AddSegmentFFT,
   SetSettings,
   SetStatus,
+  AddBeaconFrame,
+  SetCWStatus,
+  SetSignals,
+  AddAlert,
+  SetAlerts,
 }
@@ -1,4 +1,4 @@
-import {FFTState, MetricsState, SettingsState, StatusState} from './types';
+import {AlertsState, BeaconState, FFTState, MetricsState, SettingsState, SignalsState, StatusState} from './types';
 
 const FFTInitialState: FFTState = {
   samples: [],
@@ -30,9 +30,26 @@ const StatusInitialState: StatusState = {
   wsConnected: false,
 };
 
+const BeaconInitialState: BeaconState = {
+  frames: [],
+};
+
+const SignalsInitialState: SignalsState = {
+  noiseFloor: 0,
+  binWidth: 0,
+  signals: [],
+};
+
+const AlertsInitialState: AlertsState = {
+  alerts: [],
+};
+
 export {
   FFTInitialState,
   MetricsInitialState,
   SettingsInitialState,
   StatusInitialState,
+  BeaconInitialState,
+  SignalsInitialState,
+  AlertsInitialState,
 }
@@ -1,7 +1,18 @@
 import {DefinedActions} from "./actions";
-import {FFTInitialState, MetricsInitialState, SettingsInitialState, StatusInitialState} from "./initialStates";
+import {
+  AlertsInitialState,
+  BeaconInitialState,
+  FFTInitialState,
+  MetricsInitialState,
+  SettingsInitialState,
+  SignalsInitialState,
+  StatusInitialState
+} from "./initialStates";
 import {combineReducers} from "redux";
-import {SettingsState, StatusState} from "./types";
+import {AlertAction, AlertsAction, BeaconAction, CWStatusAction, SettingsState, SignalsState, StatusState} from "./types";
+
+const maxBeaconFrames = 20;
+const maxAlerts = 50;
 
 
 function segmentFFT(state: any | void | null, action: any) {
@@ -68,10 +79,68 @@ function status(state: any | void | null, action: any) {
   return state || StatusInitialState;
 }
 
+function beacon(state: any | void | null, action: any) {
+  if (action.type === DefinedActions.AddBeaconFrame) {
+    const s = !state ? BeaconInitialState : state;
+    return {
+      ...s,
+      frames: [(<BeaconAction>action).frame, ...s.frames].slice(0, maxBeaconFrames),
+    }
+  }
+
+  if (action.type === DefinedActions.SetCWStatus) {
+    const s = !state ? BeaconInitialState : state;
+    return {
+      ...s,
+      cw: (<CWStatusAction>action).cw,
+    }
+  }
+
+  return state || BeaconInitialState;
+}
+
+function signals(state: any | void | null, action: any) {
+  if (action.type === DefinedActions.SetSignals) {
+    const s = !state ? SignalsInitialState : state;
+    const {type, ...activity} = action;
+    return {
+      ...s,
+      ...<SignalsState>activity,
+    }
+  }
+
+  return state || SignalsInitialState;
+}
+
+function alerts(state: any | void | null, action: any) {
+  if (action.type === DefinedActions.AddAlert) {
+    const s = !state ? AlertsInitialState : state;
+    const {alert} = <AlertAction>action;
+    // Alerts are sent again when they resolve
+    return {
+      ...s,
+      alerts: [alert, ...s.alerts.filter((a: any) => a.id !== alert.id)].slice(0, maxAlerts),
+    }
+  }
+
+  if (action.type === DefinedActions.SetAlerts) {
+    const s = !state ? AlertsInitialState : state;
+    return {
+      ...s,
+      alerts: (<AlertsAction>action).alerts.slice(0, maxAlerts),
+    }
+  }
+
+  return state || AlertsInitialState;
+}
+
 export default combineReducers({
   segmentFFT,
   fullFFT,
   metrics,
   settings,
   status,
+  beacon,
+  signals,
+  alerts,
 })
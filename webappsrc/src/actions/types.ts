@@ -21,16 +21,103 @@ export type FFTConfig = {
   height: number;
 }
 
+export type GainSettings = {
+  tuner: string;
+  gains: number[];
+  gain: number;
+  index: number;
+  auto: boolean;
+}
+
 export type SettingsState = {
   name: string;
+  pipeline?: string;
+  lnbFrequency?: number;
+  upconverterOffset?: number;
+  beaconFrequency?: number;
   segFFT: FFTConfig;
   fullFFT: FFTConfig;
+  gain?: GainSettings;
 }
 
 export type StatusState = {
   wsConnected: boolean;
 }
 
+export type BeaconFrame = {
+  time: string;
+  number: number;
+  text?: string;
+  mer: number;
+  inverted: boolean;
+}
+
+export type CWStatus = {
+  text: string;
+  confidence: number;
+  wpm: number;
+  snr: number;
+  characters: number;
+  verified: boolean;
+  lastVerified: string;
+}
+
+export type BeaconState = {
+  frames: BeaconFrame[];
+  cw?: CWStatus;
+}
+
+export type BeaconAction = ActionType & {
+  frame: BeaconFrame;
+}
+
+export type CWStatusAction = ActionType & {
+  cw: CWStatus;
+}
+
+export type Signal = {
+  id: number;
+  frequency: number;
+  bandwidth: number;
+  power: number;
+  snr: number;
+  flatness: number;
+  mode: string;
+  firstSeen: string;
+  lastSeen: string;
+}
+
+export type SignalsState = {
+  time?: string;
+  noiseFloor: number;
+  binWidth: number;
+  signals: Signal[];
+}
+
+export type SignalsAction = ActionType & SignalsState;
+
+export type Alert = {
+  id: number;
+  pipeline: string;
+  rule: string;
+  state: string;
+  message: string;
+  value: number;
+  threshold: number;
+  start: string;
+  end?: string;
+}
+
+export type AlertsState = {
+  alerts: Alert[];
+}
+
+export type AlertAction = ActionType & {
+  alert: Alert;
+}
+
+export type AlertsAction = ActionType & AlertsState;
+
 export type FFTAction = ActionType & FFTState
 export type MetricsAction = ActionType & MetricsState;
 export type SettingsAction = ActionType & SettingsState;
@@ -8,9 +8,20 @@ import App from './App';
 import {Client} from "./Client";
 import * as serviceWorker from './serviceWorker';
 import appReducers from "./actions/reducers";
-import {AddFullFFT, AddMetrics, AddSegmentFFT, SetSettings, SetStatus} from "./actions/actions";
+import {
+  AddAlert,
+  AddBeaconFrame,
+  AddFullFFT,
+  AddMetrics,
+  AddSegmentFFT,
+  SetCWStatus,
+  SetAlerts,
+  SetSettings,
+  SetSignals,
+  SetStatus
+} from "./actions/actions";
 import {Metric} from "./Tools/types";
-import {SettingsState} from "./actions/types";
+import {Alert, BeaconFrame, CWStatus, SettingsState, SignalsState} from "./actions/types";
 
 const store = createStore(appReducers);
 
@@ -33,6 +44,26 @@ client.setOnSettings((settings: SettingsState) => {
   store.dispatch(SetSettings(settings));
 });
 
+client.setOnBeaconFrame((frame: BeaconFrame) => {
+  store.dispatch(AddBeaconFrame(frame));
+});
+
+client.setOnCWStatus((status: CWStatus) => {
+  store.dispatch(SetCWStatus(status));
+});
+
+client.setOnSignals((signals: SignalsState) => {
+  store.dispatch(SetSignals(signals));
+});
+
+client.setOnAlert((alert: Alert) => {
+  store.dispatch(AddAlert(alert));
+});
+
+client.setOnAlerts((alerts: Alert[]) => {
+  store.dispatch(SetAlerts(alerts));
+});
+
 client.setOnClose(() => {
   store.dispatch(SetStatus({
     wsConnected: false,
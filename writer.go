@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/quan-to/slog"
+)
+
+// fileWriterQueueSize is the number of writes that can wait for the writer before new ones are dropped
+const fileWriterQueueSize = 64
+
+type fileWrite struct {
+	description string
+	write       func() error
+}
+
+// FileWriter runs the state, drift log and temperature model writes in a separate goroutine, so a slow disk never
+// delays the DSP loop. The writes run in the order they were queued.
+type FileWriter struct {
+	log    *slog.Instance
+	writes chan fileWrite
+	done   chan bool
+}
+
+func MakeFileWriter(name string) *FileWriter {
+	return &FileWriter{
+		log:    slog.Scope("File Writer " + name),
+		writes: make(chan fileWrite, fileWriterQueueSize),
+		done:   make(chan bool),
+	}
+}
+
+func (w *FileWriter) Start() {
+	go w.loop()
+}
+
+// Stop runs the writes already queued and waits for them to finish. Nothing should be queued after Stop.
+func (w *FileWriter) Stop() {
+	close(w.writes)
+	<-w.done
+}
+
+// Write queues a write. It never blocks, the write is dropped if the queue is full.
+func (w *FileWriter) Write(description string, write func() error) {
+	select {
+	case w.writes <- fileWrite{description: description, write: write}:
+	default:
+		w.log.Error("Write queue is full. Dropping %s.", description)
+	}
+}
+
+func (w *FileWriter) loop() {
+	for fw := range w.writes {
+		err := fw.write()
+		if err != nil {
+			w.log.Error("Error writing %s: %s", fw.description, err)
+		}
+	}
+
+	w.done <- true
+}
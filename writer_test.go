@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFileWriterOrderAndFlush(t *testing.T) {
+	w := MakeFileWriter("test")
+	w.Start()
+
+	written := make([]int, 0)
+	for i := 0; i < fileWriterQueueSize; i++ {
+		n := i
+		w.Write(fmt.Sprintf("write %d", n), func() error {
+			written = append(written, n)
+			return nil
+		})
+	}
+
+	w.Stop()
+
+	if len(written) != fileWriterQueueSize {
+		t.Fatalf("expected %d writes after Stop, got %d", fileWriterQueueSize, len(written))
+	}
+
+	for i, n := range written {
+		if n != i {
+			t.Fatalf("expected write %d at position %d, got %d", i, i, n)
+		}
+	}
+}